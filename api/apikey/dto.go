@@ -0,0 +1,60 @@
+package apikey
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ApiKeyDTO struct {
+	ID         primitive.ObjectID `json:"id"`
+	Name       string             `json:"name"`
+	Scopes     []string           `json:"scopes"`
+	CreatedBy  string             `json:"createdBy"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	LastUsedAt *time.Time         `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time         `json:"revokedAt,omitempty"`
+}
+
+// CreateApiKeyDTO lists the scopes an integration may need; administrative
+// permissions are left out so keys never manage users or other keys.
+type CreateApiKeyDTO struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read:budgets write:budgets read:materials write:materials write:prices read:dimensions write:dimensions delete:dimensions read:audit"`
+}
+
+// IssuedApiKeyDTO is the only response that includes the key.
+type IssuedApiKeyDTO struct {
+	ApiKey ApiKeyDTO `json:"apiKey"`
+	Key    string    `json:"key"`
+}
+
+var ApiKeySortFields = []string{"name"}
+
+func NewApiKeyDTO(key *ApiKey) *ApiKeyDTO {
+	scopes := key.Scopes
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return &ApiKeyDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     scopes,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+func NewApiKeyDTOs(keys []ApiKey) []ApiKeyDTO {
+	dtos := make([]ApiKeyDTO, len(keys))
+
+	for i := range keys {
+		dtos[i] = *NewApiKeyDTO(&keys[i])
+	}
+
+	return dtos
+}
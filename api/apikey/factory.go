@@ -0,0 +1,25 @@
+package apikey
+
+import (
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewApiKeyHandler(service ApiKeyService) ApiKeyHandler {
+	return &handler{
+		service: service,
+	}
+}
+
+func NewApiKeyService(apiKeyRepository ApiKeyRepository) ApiKeyService {
+	return &service{
+		apiKeyRepository: apiKeyRepository,
+	}
+}
+
+func NewApiKeyRepository(db *mongo.Database, timeouts core.QueryTimeouts) ApiKeyRepository {
+	return &repository{
+		db:       db.Collection("apiKeys"),
+		timeouts: timeouts,
+	}
+}
@@ -0,0 +1,89 @@
+package apikey
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	service ApiKeyService
+}
+
+type ApiKeyHandler interface {
+	GetAllApiKeys(w http.ResponseWriter, r *http.Request)
+	CreateApiKey(w http.ResponseWriter, r *http.Request)
+	RotateApiKey(w http.ResponseWriter, r *http.Request)
+	RevokeApiKey(w http.ResponseWriter, r *http.Request)
+	GetApiKeyRoutes() core.Routes
+}
+
+func (h *handler) GetAllApiKeys(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetAllApiKeys(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) CreateApiKey(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.CreateApiKey(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
+}
+
+func (h *handler) RotateApiKey(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.RotateApiKey(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) RevokeApiKey(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.RevokeApiKey(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetApiKeyRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/api-keys",
+			HandlerFunc: h.GetAllApiKeys,
+			Method:      "GET",
+			Permission:  core.PermissionManageApiKeys,
+			Summary:     "Lista las claves de API de la organizacion, sin sus secretos",
+			Response:    core.Page[ApiKeyDTO]{},
+			QueryParams: map[string]string{
+				"limit":     "Cantidad de claves por pagina, hasta 100",
+				"pageToken": "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":      "Orden: name o createdAt, con el prefijo - para descendente",
+			},
+		},
+		core.Route{
+			Path:        "/api-keys",
+			HandlerFunc: h.CreateApiKey,
+			Method:      "POST",
+			Permission:  core.PermissionManageApiKeys,
+			Summary:     "Emite una clave de API; la clave solo se muestra en esta respuesta",
+			Request:     CreateApiKeyDTO{},
+			Response:    IssuedApiKeyDTO{},
+			Status:      http.StatusCreated,
+		},
+		core.Route{
+			Path:        "/api-keys/{id}/rotate",
+			HandlerFunc: h.RotateApiKey,
+			Method:      "POST",
+			Permission:  core.PermissionManageApiKeys,
+			Summary:     "Reemplaza el secreto de una clave de API; el anterior deja de funcionar",
+			Response:    IssuedApiKeyDTO{},
+			PathParams: map[string]string{
+				"id": "ID de la clave de API",
+			},
+		},
+		core.Route{
+			Path:        "/api-keys/{id}",
+			HandlerFunc: h.RevokeApiKey,
+			Method:      "DELETE",
+			Permission:  core.PermissionManageApiKeys,
+			Summary:     "Revoca una clave de API",
+			Response:    ApiKeyDTO{},
+			PathParams: map[string]string{
+				"id": "ID de la clave de API",
+			},
+		},
+	}
+}
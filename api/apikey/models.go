@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApiKey keeps only the SHA-256 hash of the key secret; the key itself is
+// shown once, when it is issued or rotated.
+type ApiKey struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organizationId"`
+	Name           string             `bson:"name"`
+	Scopes         []string           `bson:"scopes"`
+	SecretHash     string             `bson:"secretHash"`
+	CreatedBy      string             `bson:"createdBy"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+	LastUsedAt     *time.Time         `bson:"lastUsedAt,omitempty"`
+	RevokedAt      *time.Time         `bson:"revokedAt,omitempty"`
+}
+
+func (k *ApiKey) Principal() core.Principal {
+	return core.Principal{
+		Username:       "apikey:" + k.Name,
+		OrganizationID: k.OrganizationID,
+		ApiKeyID:       k.ID,
+		Scopes:         k.Scopes,
+	}
+}
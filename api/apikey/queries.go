@@ -0,0 +1,41 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var apiKeySortFields = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "_id",
+}
+
+func GetApiKeysByOrganization(ctx context.Context) bson.M {
+	return core.TenantFilter(ctx, bson.M{})
+}
+
+func GetApiKeyById(ctx context.Context, oid primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"_id": oid})
+}
+
+// GetApiKeyToVerify is not scoped by organization: the request has no
+// principal yet, and the key tells its own organization.
+func GetApiKeyToVerify(oid primitive.ObjectID) bson.M {
+	return bson.M{"_id": oid}
+}
+
+func UpdateApiKeySecret(secretHash string) bson.M {
+	return bson.M{"$set": bson.M{"secretHash": secretHash}}
+}
+
+func RevokeApiKey(revokedAt time.Time) bson.M {
+	return bson.M{"$set": bson.M{"revokedAt": revokedAt}}
+}
+
+func UpdateApiKeyLastUsed(usedAt time.Time) bson.M {
+	return bson.M{"$set": bson.M{"lastUsedAt": usedAt}}
+}
@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type repository struct {
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
+}
+
+type ApiKeyRepository interface {
+	FindApiKeys(ctx context.Context, page core.PageRequest) ([]ApiKey, int64, error)
+	FindApiKeyByOID(ctx context.Context, oid *primitive.ObjectID) *ApiKey
+	// FindApiKeyToVerify searches every organization, see GetApiKeyToVerify.
+	FindApiKeyToVerify(ctx context.Context, oid *primitive.ObjectID) *ApiKey
+	CreateApiKey(ctx context.Context, key *ApiKey) error
+	UpdateApiKeySecret(ctx context.Context, oid *primitive.ObjectID, secretHash string) error
+	RevokeApiKey(ctx context.Context, oid *primitive.ObjectID, revokedAt time.Time) error
+	UpdateApiKeyLastUsed(ctx context.Context, oid *primitive.ObjectID, usedAt time.Time) error
+}
+
+func (r *repository) FindApiKeys(ctx context.Context, page core.PageRequest) ([]ApiKey, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeys")
+	defer cancel()
+
+	filter := GetApiKeysByOrganization(ctx)
+
+	total, err := r.db.CountDocuments(ctx, filter)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	cursor, err := r.db.Find(ctx, filter, page.FindOptions(apiKeySortFields))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	var keys []ApiKey = []ApiKey{}
+
+	err = cursor.All(ctx, &keys)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return keys, total, nil
+}
+
+func (r *repository) FindApiKeyByOID(ctx context.Context, oid *primitive.ObjectID) *ApiKey {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeyByOID")
+	defer cancel()
+
+	return r.findApiKey(ctx, GetApiKeyById(ctx, *oid))
+}
+
+func (r *repository) FindApiKeyToVerify(ctx context.Context, oid *primitive.ObjectID) *ApiKey {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeyToVerify")
+	defer cancel()
+
+	return r.findApiKey(ctx, GetApiKeyToVerify(*oid))
+}
+
+func (r *repository) CreateApiKey(ctx context.Context, key *ApiKey) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.CreateApiKey")
+	defer cancel()
+
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.InsertOne(ctx, key)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func (r *repository) UpdateApiKeySecret(ctx context.Context, oid *primitive.ObjectID, secretHash string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.UpdateApiKeySecret")
+	defer cancel()
+
+	return r.updateApiKey(ctx, GetApiKeyById(ctx, *oid), UpdateApiKeySecret(secretHash))
+}
+
+func (r *repository) RevokeApiKey(ctx context.Context, oid *primitive.ObjectID, revokedAt time.Time) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.RevokeApiKey")
+	defer cancel()
+
+	return r.updateApiKey(ctx, GetApiKeyById(ctx, *oid), RevokeApiKey(revokedAt))
+}
+
+func (r *repository) UpdateApiKeyLastUsed(ctx context.Context, oid *primitive.ObjectID, usedAt time.Time) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.UpdateApiKeyLastUsed")
+	defer cancel()
+
+	return r.updateApiKey(ctx, GetApiKeyToVerify(*oid), UpdateApiKeyLastUsed(usedAt))
+}
+
+func (r *repository) findApiKey(ctx context.Context, filter any) *ApiKey {
+	var key *ApiKey = &ApiKey{}
+
+	err := r.db.FindOne(ctx, filter).Decode(key)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			core.LogError(ctx, err)
+		}
+
+		return nil
+	}
+
+	return key
+}
+
+func (r *repository) updateApiKey(ctx context.Context, filter any, update any) error {
+	_, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
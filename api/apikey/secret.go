@@ -0,0 +1,58 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// keyPrefix makes keys recognizable, e.g. by secret scanners.
+const keyPrefix = "ak_"
+
+// NewSecret returns a key for the given ID, in the form ak_<id>_<secret>, and
+// the hash to store. The ID lets verification find the key without scanning,
+// and the secret has 256 random bits, so a fast hash is enough.
+func NewSecret(oid primitive.ObjectID) (string, string, error) {
+	secret := make([]byte, 32)
+
+	_, err := rand.Read(secret)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	return keyPrefix + oid.Hex() + "_" + encoded, hashSecret(encoded), nil
+}
+
+// ParseKey splits a key into its ID and secret.
+func ParseKey(key string) (primitive.ObjectID, string, bool) {
+	id, secret, found := strings.Cut(strings.TrimPrefix(key, keyPrefix), "_")
+
+	if !found || !strings.HasPrefix(key, keyPrefix) || secret == "" {
+		return primitive.NilObjectID, "", false
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		return primitive.NilObjectID, "", false
+	}
+
+	return oid, secret, true
+}
+
+func MatchesSecret(secret string, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(secretHash)) == 1
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,207 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lastUsedResolution limits how often verification writes the last use of a
+// key, so busy integrations do not write on every request.
+const lastUsedResolution = time.Minute
+
+var (
+	errInvalidApiKey = errors.New("la clave de API no es valida")
+	errRevokedApiKey = errors.New("la clave de API fue revocada")
+)
+
+type service struct {
+	apiKeyRepository ApiKeyRepository
+}
+
+type ApiKeyService interface {
+	GetAllApiKeys(ctx context.Context, r *http.Request) (*core.Page[ApiKeyDTO], *core.ApiError)
+	CreateApiKey(ctx context.Context, r *http.Request) (*IssuedApiKeyDTO, *core.ApiError)
+	RotateApiKey(ctx context.Context, r *http.Request) (*IssuedApiKeyDTO, *core.ApiError)
+	RevokeApiKey(ctx context.Context, r *http.Request) (*ApiKeyDTO, *core.ApiError)
+	VerifyApiKey(ctx context.Context, key string) (*core.Principal, error)
+}
+
+func (s *service) GetAllApiKeys(ctx context.Context, r *http.Request) (*core.Page[ApiKeyDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "ApiKeyService.GetAllApiKeys")
+	defer span.End()
+
+	page, apiErr := core.ParsePageRequest(r, ApiKeySortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	keys, total, err := s.apiKeyRepository.FindApiKeys(ctx, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(NewApiKeyDTOs(keys), total, *page), nil
+}
+
+func (s *service) CreateApiKey(ctx context.Context, r *http.Request) (*IssuedApiKeyDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "ApiKeyService.CreateApiKey")
+	defer span.End()
+
+	var created *CreateApiKeyDTO = &CreateApiKeyDTO{}
+
+	apiErr := core.DecodeBody(r, created)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	principal := core.PrincipalFromContext(ctx)
+
+	// a key never grants more than the admin issuing it holds
+	for _, scope := range created.Scopes {
+		if principal != nil && !principal.HasPermission(scope) {
+			return nil, core.NewForbiddenError(scope)
+		}
+	}
+
+	key := &ApiKey{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: core.TenantFromContext(ctx),
+		Name:           created.Name,
+		Scopes:         created.Scopes,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	if principal != nil {
+		key.CreatedBy = principal.Username
+	}
+
+	secret, secretHash, err := NewSecret(key.ID)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	key.SecretHash = secretHash
+
+	err = s.apiKeyRepository.CreateApiKey(ctx, key)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return &IssuedApiKeyDTO{ApiKey: *NewApiKeyDTO(key), Key: secret}, nil
+}
+
+func (s *service) RotateApiKey(ctx context.Context, r *http.Request) (*IssuedApiKeyDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "ApiKeyService.RotateApiKey")
+	defer span.End()
+
+	key, apiErr := s.findApiKey(ctx, r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if key.RevokedAt != nil {
+		return nil, core.NewConflictError("La clave de API fue revocada y no puede rotarse")
+	}
+
+	// the previous secret stops working as soon as the new hash is stored
+	secret, secretHash, err := NewSecret(key.ID)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	err = s.apiKeyRepository.UpdateApiKeySecret(ctx, &key.ID, secretHash)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return &IssuedApiKeyDTO{ApiKey: *NewApiKeyDTO(key), Key: secret}, nil
+}
+
+func (s *service) RevokeApiKey(ctx context.Context, r *http.Request) (*ApiKeyDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "ApiKeyService.RevokeApiKey")
+	defer span.End()
+
+	key, apiErr := s.findApiKey(ctx, r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if key.RevokedAt != nil {
+		return NewApiKeyDTO(key), nil
+	}
+
+	revokedAt := time.Now().UTC()
+
+	err := s.apiKeyRepository.RevokeApiKey(ctx, &key.ID, revokedAt)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	key.RevokedAt = &revokedAt
+
+	return NewApiKeyDTO(key), nil
+}
+
+func (s *service) VerifyApiKey(ctx context.Context, key string) (*core.Principal, error) {
+	ctx, span := core.StartSpan(ctx, "ApiKeyService.VerifyApiKey")
+	defer span.End()
+
+	oid, secret, ok := ParseKey(key)
+
+	if !ok {
+		return nil, errInvalidApiKey
+	}
+
+	found := s.apiKeyRepository.FindApiKeyToVerify(ctx, &oid)
+
+	if found == nil || !MatchesSecret(secret, found.SecretHash) {
+		return nil, errInvalidApiKey
+	}
+
+	if found.RevokedAt != nil {
+		return nil, errRevokedApiKey
+	}
+
+	now := time.Now().UTC()
+
+	if found.LastUsedAt == nil || now.Sub(*found.LastUsedAt) >= lastUsedResolution {
+		// a failed write only loses the timestamp, the request goes on
+		s.apiKeyRepository.UpdateApiKeyLastUsed(ctx, &oid, now)
+	}
+
+	principal := found.Principal()
+
+	return &principal, nil
+}
+
+func (s *service) findApiKey(ctx context.Context, r *http.Request) (*ApiKey, *core.ApiError) {
+	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if oid == nil {
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	key := s.apiKeyRepository.FindApiKeyByOID(ctx, oid)
+
+	if key == nil {
+		return nil, core.NewNotFoundError("clave de API")
+	}
+
+	return key, nil
+}
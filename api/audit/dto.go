@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EntryDTO struct {
+	ID         primitive.ObjectID  `json:"id"`
+	Actor      string              `json:"actor"`
+	ActorID    *primitive.ObjectID `json:"actorId,omitempty"`
+	Timestamp  time.Time           `json:"timestamp"`
+	EntityType string              `json:"entityType"`
+	EntityID   primitive.ObjectID  `json:"entityId"`
+	Operation  string              `json:"operation"`
+	Cause      string              `json:"cause,omitempty"`
+	RequestID  string              `json:"requestId,omitempty"`
+	Before     Snapshot            `json:"before,omitempty"`
+	After      Snapshot            `json:"after,omitempty"`
+}
+
+// EntryFilter narrows the entries; the zero value matches every entry of the
+// organization. From is inclusive and To exclusive.
+type EntryFilter struct {
+	EntityType string
+	EntityID   *primitive.ObjectID
+	Actor      string
+	Operation  string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// Entries are only listed by creation date, newest first unless the sort
+// says otherwise.
+var EntrySortFields = []string{}
+
+func NewEntryDTO(entry *Entry) *EntryDTO {
+	var dto *EntryDTO = &EntryDTO{
+		ID:         entry.ID,
+		Actor:      entry.Actor,
+		Timestamp:  entry.Timestamp,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Operation:  entry.Operation,
+		Cause:      entry.Cause,
+		RequestID:  entry.RequestID,
+		Before:     entry.Before,
+		After:      entry.After,
+	}
+
+	if !entry.ActorID.IsZero() {
+		actorId := entry.ActorID
+		dto.ActorID = &actorId
+	}
+
+	return dto
+}
+
+func NewEntryDTOs(entries []Entry) []EntryDTO {
+	dtos := make([]EntryDTO, len(entries))
+
+	for i := range entries {
+		dtos[i] = *NewEntryDTO(&entries[i])
+	}
+
+	return dtos
+}
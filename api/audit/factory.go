@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewAuditHandler(service AuditService) AuditHandler {
+	return &handler{
+		service: service,
+	}
+}
+
+func NewAuditService(auditRepository AuditRepository) AuditService {
+	return &service{
+		auditRepository: auditRepository,
+	}
+}
+
+func NewAuditRepository(db *mongo.Database, timeouts core.QueryTimeouts) AuditRepository {
+	return &repository{
+		db:       db.Collection("auditLog"),
+		timeouts: timeouts,
+	}
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	service AuditService
+}
+
+type AuditHandler interface {
+	GetAuditEntries(w http.ResponseWriter, r *http.Request)
+	GetAuditRoutes() core.Routes
+}
+
+func (h *handler) GetAuditEntries(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetAuditEntries(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetAuditRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/audit",
+			HandlerFunc: h.GetAuditEntries,
+			Method:      "GET",
+			Permission:  core.PermissionReadAudit,
+			Summary:     "Lista los cambios de presupuestos, materiales y dimensiones de la organizacion, del mas reciente al mas antiguo",
+			Response:    core.Page[EntryDTO]{},
+			QueryParams: map[string]string{
+				"entityType": "Tipo de entidad: budget, material o dimension",
+				"entityId":   "ID de la entidad modificada",
+				"actor":      "Usuario o clave de API (apikey:nombre) que hizo el cambio",
+				"operation":  "Operacion, por ejemplo create, update, delete, changePrice o reprice",
+				"requestId":  "ID de la solicitud, que comparten los cambios en cascada",
+				"from":       "Fecha RFC 3339 desde la que incluir cambios",
+				"to":         "Fecha RFC 3339 hasta la que incluir cambios, sin incluirla",
+				"limit":      "Cantidad de cambios por pagina, hasta 100",
+				"pageToken":  "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":       "Orden: createdAt, con el prefijo - para descendente; por defecto -createdAt",
+			},
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EntityBudget    = "budget"
+	EntityMaterial  = "material"
+	EntityDimension = "dimension"
+)
+
+const (
+	OperationCreate          = "create"
+	OperationUpdate          = "update"
+	OperationDelete          = "delete"
+	OperationAddMaterial     = "addMaterial"
+	OperationRemoveMaterial  = "removeMaterial"
+	OperationAddDimension    = "addDimension"
+	OperationRemoveDimension = "removeDimension"
+	OperationChangePrice     = "changePrice"
+	OperationReprice         = "reprice"
+	OperationMerge           = "merge"
+	OperationReassign        = "reassignMaterial"
+)
+
+// Snapshot is the JSON form of an entity, so every storage keeps the same
+// fields the API returns for it.
+type Snapshot map[string]any
+
+// Entry records one change of one entity. Changes cascaded from another
+// mutation, like the budgets repriced when a material price changes, name it
+// in Cause and share the RequestID of the request that caused them.
+type Entry struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organizationId"`
+	Actor          string             `bson:"actor"`
+	ActorID        primitive.ObjectID `bson:"actorId,omitempty"`
+	Timestamp      time.Time          `bson:"timestamp"`
+	EntityType     string             `bson:"entityType"`
+	EntityID       primitive.ObjectID `bson:"entityId"`
+	Operation      string             `bson:"operation"`
+	Cause          string             `bson:"cause,omitempty"`
+	RequestID      string             `bson:"requestId,omitempty"`
+	Before         Snapshot           `bson:"before,omitempty"`
+	After          Snapshot           `bson:"after,omitempty"`
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var entrySortFields = map[string]string{
+	core.SortCreatedAt: "_id",
+}
+
+// EntityIndex serves the history of one entity, the most common query.
+func EntityIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: core.TenantField, Value: 1}, {Key: "entityId", Value: 1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("auditLog_entity"),
+	}
+}
+
+func FilterEntries(ctx context.Context, filter EntryFilter) bson.M {
+	query := core.TenantFilter(ctx, bson.M{})
+
+	if filter.EntityType != "" {
+		query["entityType"] = filter.EntityType
+	}
+
+	if filter.EntityID != nil {
+		query["entityId"] = *filter.EntityID
+	}
+
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+
+	if filter.Operation != "" {
+		query["operation"] = filter.Operation
+	}
+
+	if filter.RequestID != "" {
+		query["requestId"] = filter.RequestID
+	}
+
+	timestamp := bson.M{}
+
+	if filter.From != nil {
+		timestamp["$gte"] = *filter.From
+	}
+
+	if filter.To != nil {
+		timestamp["$lt"] = *filter.To
+	}
+
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	return query
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type repository struct {
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
+}
+
+type AuditRepository interface {
+	FindEntries(ctx context.Context, filter EntryFilter, page core.PageRequest) ([]Entry, int64, error)
+	CreateEntries(ctx context.Context, entries []Entry) error
+}
+
+func EnsureAuditIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("auditLog").Indexes().CreateOne(ctx, EntityIndex())
+	return err
+}
+
+func (r *repository) FindEntries(ctx context.Context, filter EntryFilter, page core.PageRequest) ([]Entry, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "auditLog.FindEntries")
+	defer cancel()
+
+	query := FilterEntries(ctx, filter)
+
+	total, err := r.db.CountDocuments(ctx, query)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	cursor, err := r.db.Find(ctx, query, page.FindOptions(entrySortFields))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	var entries []Entry = []Entry{}
+
+	err = cursor.All(ctx, &entries)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+func (r *repository) CreateEntries(ctx context.Context, entries []Entry) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "auditLog.CreateEntries")
+	defer cancel()
+
+	documents := make([]any, len(entries))
+
+	for i := range entries {
+		if entries[i].ID.IsZero() {
+			entries[i].ID = primitive.NewObjectID()
+		}
+
+		documents[i] = entries[i]
+	}
+
+	_, err := r.db.InsertMany(ctx, documents)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnonymousActor records the changes made while authentication is disabled.
+const AnonymousActor = "anonimo"
+
+var entityTypes = []string{EntityBudget, EntityMaterial, EntityDimension}
+
+type service struct {
+	auditRepository AuditRepository
+}
+
+// Recorder is what the budget, material and dimension services need to
+// record their changes.
+type Recorder interface {
+	// Record completes the entries with the actor, organization, time and
+	// request, and stores them. The change already happened, so a failure
+	// is logged instead of failing the request. Entries whose snapshots did
+	// not change are skipped. The changes of a unit of work are recorded once
+	// it commits: in a Mongo transaction a failed insert would abort the
+	// whole unit, and a unit rolled back changed nothing.
+	Record(ctx context.Context, entries ...Entry)
+}
+
+type AuditService interface {
+	Recorder
+	GetAuditEntries(ctx context.Context, r *http.Request) (*core.Page[EntryDTO], *core.ApiError)
+}
+
+// Change builds the entry of a mutation. Before is nil for creations and
+// after is nil for deletions.
+func Change(entityType string, entityId primitive.ObjectID, operation string, before Snapshot, after Snapshot) Entry {
+	return Entry{
+		EntityType: entityType,
+		EntityID:   entityId,
+		Operation:  operation,
+		Before:     before,
+		After:      after,
+	}
+}
+
+// CausedBy marks the entry as cascaded from the operation on another entity
+// type, e.g. CausedBy(EntityMaterial, OperationChangePrice).
+func (e Entry) CausedBy(entityType string, operation string) Entry {
+	e.Cause = entityType + "." + operation
+	return e
+}
+
+// NewSnapshot copies the entity through its JSON form, so later changes to
+// the value do not alter the snapshot. Nil entities give a nil snapshot.
+func NewSnapshot(entity any) Snapshot {
+	encoded, err := json.Marshal(entity)
+
+	if err != nil {
+		return nil
+	}
+
+	var snapshot Snapshot
+
+	if json.Unmarshal(encoded, &snapshot) != nil {
+		return nil
+	}
+
+	return snapshot
+}
+
+func (s *service) Record(ctx context.Context, entries ...Entry) {
+	ctx, span := core.StartSpan(ctx, "AuditService.Record")
+	defer span.End()
+
+	var actor string = AnonymousActor
+	var actorId primitive.ObjectID
+
+	if principal := core.PrincipalFromContext(ctx); principal != nil {
+		actor = principal.Username
+		actorId = principal.UserID
+
+		if !principal.ApiKeyID.IsZero() {
+			actorId = principal.ApiKeyID
+		}
+	}
+
+	// Mongo keeps milliseconds, so every storage does
+	timestamp := time.Now().UTC().Truncate(time.Millisecond)
+	recorded := []Entry{}
+
+	for _, entry := range entries {
+		if entry.Before != nil && entry.After != nil && reflect.DeepEqual(entry.Before, entry.After) {
+			continue
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.OrganizationID = core.TenantFromContext(ctx)
+		entry.Actor = actor
+		entry.ActorID = actorId
+		entry.Timestamp = timestamp
+		entry.RequestID = core.RequestIdFromContext(ctx)
+
+		recorded = append(recorded, entry)
+	}
+
+	if len(recorded) == 0 {
+		return
+	}
+
+	err := s.auditRepository.CreateEntries(ctx, recorded)
+
+	if err != nil {
+		core.Log(ctx, core.LogLevelError, "No se pudo registrar la auditoria de los cambios", core.LogFields{
+			"entries": len(recorded),
+			"error":   err.Error(),
+		})
+	}
+}
+
+func (s *service) GetAuditEntries(ctx context.Context, r *http.Request) (*core.Page[EntryDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "AuditService.GetAuditEntries")
+	defer span.End()
+
+	page, apiErr := core.ParsePageRequest(r, EntrySortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if r.URL.Query().Get("sort") == "" {
+		page.SortDescending = true
+	}
+
+	filter, apiErr := parseEntryFilter(r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	entries, total, err := s.auditRepository.FindEntries(ctx, *filter, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(NewEntryDTOs(entries), total, *page), nil
+}
+
+func parseEntryFilter(r *http.Request) (*EntryFilter, *core.ApiError) {
+	query := r.URL.Query()
+
+	entityType := query.Get("entityType")
+
+	if entityType != "" && !contains(entityTypes, entityType) {
+		return nil, core.NewInvalidQueryError("entityType", "El valor debe ser uno de: "+strings.Join(entityTypes, ", "))
+	}
+
+	entityId, apiErr := core.QueryObjectId(r, "entityId")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	from, apiErr := core.QueryTime(r, "from")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	to, apiErr := core.QueryTime(r, "to")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return &EntryFilter{
+		EntityType: entityType,
+		EntityID:   entityId,
+		Actor:      query.Get("actor"),
+		Operation:  query.Get("operation"),
+		RequestID:  query.Get("requestId"),
+		From:       from,
+		To:         to,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
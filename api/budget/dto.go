@@ -7,14 +7,17 @@ type BudgetDTO struct {
 	Name      string             `json:"name"`
 	Materials []MaterialsDTO     `json:"materials"`
 	Price     float64            `json:"price"`
+	// Version is sent back as the ETag and in If-Match, see core.VersionField.
+	Version int64 `json:"version"`
 }
 
 type MaterialsDTO struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Name      string             `json:"name"`
-	Price     float64            `json:"price"`
-	Dimension DimensionDTO       `json:"dimension"`
-	Quantity  float64            `json:"quantity"`
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	MaterialID primitive.ObjectID `bson:"materialId,omitempty" json:"materialId,omitempty"`
+	Name       string             `json:"name"`
+	Price      float64            `json:"price"`
+	Dimension  DimensionDTO       `json:"dimension"`
+	Quantity   float64            `json:"quantity"`
 }
 
 type DimensionDTO struct {
@@ -27,3 +30,12 @@ type DimensionDTO struct {
 type BudgetNameDTO struct {
 	Name string `json:"name" validate:"required"`
 }
+
+var BudgetSortFields = []string{"name", "price"}
+
+type BudgetFilter struct {
+	NamePrefix string
+	MinPrice   *float64
+	MaxPrice   *float64
+	MaterialId *primitive.ObjectID
+}
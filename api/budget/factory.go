@@ -1,32 +1,28 @@
 package budget
 
 import (
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetBudgetHandlerInstance() *handler {
-	if budgetHandlerInstance == nil {
-		budgetHandlerInstance = &handler{
-			service: GetBudgetServiceInstance(),
-		}
+func NewBudgetHandler(service BudgetService) BudgetHandler {
+	return &handler{
+		service: service,
 	}
-	return budgetHandlerInstance
 }
 
-func GetBudgetServiceInstance() *service {
-	if budgetServiceInstance == nil {
-		budgetServiceInstance = &service{
-			budgetRepository: GetBudgetRepositoryInstance(),
-		}
+func NewBudgetService(budgetRepository BudgetRepository, recorder audit.Recorder, metrics *core.Metrics) BudgetService {
+	return &service{
+		budgetRepository: budgetRepository,
+		audit:            recorder,
+		metrics:          metrics,
 	}
-	return budgetServiceInstance
 }
 
-func GetBudgetRepositoryInstance() *repository {
-	if budgetRepositoryInstance == nil {
-		budgetRepositoryInstance = &repository{
-			db: core.GetDatabaseConnection().Collection("budgets"),
-		}
+func NewBudgetRepository(db *mongo.Database, timeouts core.QueryTimeouts) BudgetRepository {
+	return &repository{
+		db:       db.Collection("budgets"),
+		timeouts: timeouts,
 	}
-	return budgetRepositoryInstance
 }
@@ -4,15 +4,14 @@ import (
 	"net/http"
 
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type handler struct {
 	service BudgetService
 }
 
-var budgetHandlerInstance *handler
-
-type RecipeHandler interface {
+type BudgetHandler interface {
 	GetAllBudgets(w http.ResponseWriter, r *http.Request)
 	GetBudget(w http.ResponseWriter, r *http.Request)
 	CreateBudget(w http.ResponseWriter, r *http.Request)
@@ -23,28 +22,43 @@ type RecipeHandler interface {
 }
 
 func (h *handler) GetAllBudgets(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.GetAllBudgets()
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.GetAllBudgets(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) GetBudget(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.GetBudget(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.GetBudget(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) CreateBudget(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.CreateBudget(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.CreateBudget(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
 }
 
 func (h *handler) UpdateBudgetName(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.UpdateBudgetName(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.UpdateBudgetName(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.DeleteBudget(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.DeleteBudget(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) GetBudgetRoutes() core.Routes {
@@ -53,26 +67,65 @@ func (h *handler) GetBudgetRoutes() core.Routes {
 			Path:        "/budgets",
 			HandlerFunc: h.GetAllBudgets,
 			Method:      "GET",
+			Permission:  core.PermissionReadBudgets,
+			Summary:     "Lista los presupuestos",
+			Response:    core.Page[BudgetDTO]{},
+			QueryParams: map[string]string{
+				"limit":      "Cantidad de presupuestos por pagina, hasta 100",
+				"pageToken":  "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":       "Orden: name, price o createdAt, con el prefijo - para descendente",
+				"name":       "Prefijo del nombre, sin distinguir mayusculas",
+				"minPrice":   "Precio minimo",
+				"maxPrice":   "Precio maximo",
+				"materialId": "ID de un material incluido en el presupuesto",
+			},
 		},
 		core.Route{
 			Path:        "/budgets",
 			HandlerFunc: h.CreateBudget,
 			Method:      "POST",
+			Permission:  core.PermissionWriteBudgets,
+			Idempotent:  true,
+			Summary:     "Crea un presupuesto vacio",
+			Request:     BudgetNameDTO{},
+			Response:    BudgetDTO{},
+			Status:      http.StatusCreated,
 		},
 		core.Route{
 			Path:        "/budgets/{id}",
 			HandlerFunc: h.UpdateBudgetName,
 			Method:      "PUT",
+			Permission:  core.PermissionWriteBudgets,
+			Conditional: true,
+			Summary:     "Renombra un presupuesto",
+			Request:     BudgetNameDTO{},
+			Response:    BudgetDTO{},
+			PathParams: map[string]string{
+				"id": "ID del presupuesto",
+			},
 		},
 		core.Route{
 			Path:        "/budgets/{id}",
 			HandlerFunc: h.GetBudget,
 			Method:      "GET",
+			Permission:  core.PermissionReadBudgets,
+			Summary:     "Obtiene un presupuesto con sus materiales",
+			Response:    BudgetDTO{},
+			PathParams: map[string]string{
+				"id": "ID del presupuesto",
+			},
 		},
 		core.Route{
 			Path:        "/budgets/{id}",
 			HandlerFunc: h.DeleteBudget,
 			Method:      "DELETE",
+			Permission:  core.PermissionWriteBudgets,
+			Conditional: true,
+			Summary:     "Elimina un presupuesto",
+			Response:    primitive.ObjectID{},
+			PathParams: map[string]string{
+				"id": "ID del presupuesto",
+			},
 		},
 	}
 }
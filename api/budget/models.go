@@ -9,14 +9,18 @@ type Budget struct {
 	Name      string             `bson:"name" json:"name,omitempty" validate:"required"`
 	Materials []BudgetMaterial   `bson:"materials" json:"materials,omitempty" validate:"required"`
 	Price     float64            `bson:"price" json:"price,omitempty" validate:"required"`
+	Version   int64              `bson:"version" json:"version"`
+	// OrganizationID is set from the request principal, never from the body.
+	OrganizationID primitive.ObjectID `bson:"organizationId" json:"-"`
 }
 
 type BudgetMaterial struct {
-	ID        primitive.ObjectID      `bson:"_id" json:"id,omitempty"`
-	Name      string                  `bson:"name" json:"name,omitempty"`
-	Dimension BudgetMaterialDimension `bson:"dimension" json:"dimension" validate:"required"`
-	Quantity  float32                 `bson:"quantity" json:"quantity" validate:"required"`
-	Price     float64                 `bson:"price" json:"price" validate:"required"`
+	ID         primitive.ObjectID      `bson:"_id" json:"id,omitempty"`
+	MaterialID primitive.ObjectID      `bson:"materialId,omitempty" json:"materialId,omitempty"`
+	Name       string                  `bson:"name" json:"name,omitempty"`
+	Dimension  BudgetMaterialDimension `bson:"dimension" json:"dimension" validate:"required"`
+	Quantity   float32                 `bson:"quantity" json:"quantity" validate:"required"`
+	Price      float64                 `bson:"price" json:"price" validate:"required"`
 }
 
 type BudgetMaterialDimension struct {
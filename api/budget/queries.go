@@ -1,59 +1,175 @@
 package budget
 
 import (
+	"context"
+	"regexp"
+
+	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func All() bson.M {
-	return bson.M{}
+var budgetSortFields = map[string]string{
+	"name":             "name",
+	"price":            "price",
+	core.SortCreatedAt: "_id",
+}
+
+// DimensionIndex serves GetBudgetByDimensionId, which every dimension price
+// change and deletion runs.
+func DimensionIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: core.TenantField, Value: 1}, {Key: "materials.dimension._id", Value: 1}},
+		Options: options.Index().SetName("budgets_dimension"),
+	}
 }
 
-func GetRecipeById(oid primitive.ObjectID) bson.M {
-	return bson.M{"_id": oid}
+// MaterialIndex serves the materialId filter of the budget list.
+func MaterialIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: core.TenantField, Value: 1}, {Key: "materials.materialId", Value: 1}},
+		Options: options.Index().SetName("budgets_material"),
+	}
+}
+
+func FilterBudgets(ctx context.Context, filter BudgetFilter) bson.M {
+	query := core.TenantFilter(ctx, bson.M{})
+
+	if filter.NamePrefix != "" {
+		query["name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.NamePrefix), "$options": "i"}
+	}
+
+	price := bson.M{}
+
+	if filter.MinPrice != nil {
+		price["$gte"] = *filter.MinPrice
+	}
+
+	if filter.MaxPrice != nil {
+		price["$lte"] = *filter.MaxPrice
+	}
+
+	if len(price) > 0 {
+		query["price"] = price
+	}
+
+	if filter.MaterialId != nil {
+		query["materials.materialId"] = *filter.MaterialId
+	}
+
+	return query
+}
+
+func GetRecipeById(ctx context.Context, oid primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"_id": oid})
+}
+
+// GetBudgetByIdAndVersion matches the budget only while it has the version.
+func GetBudgetByIdAndVersion(ctx context.Context, oid primitive.ObjectID, version int64) bson.M {
+	return core.WithVersion(GetRecipeById(ctx, oid), version)
+}
+
+func NewBudget(ctx context.Context, dto BudgetNameDTO) Budget {
+	return Budget{
+		Name:           dto.Name,
+		Materials:      []BudgetMaterial{},
+		Version:        1,
+		OrganizationID: core.TenantFromContext(ctx),
+	}
 }
 
 func UpdateRecipeName(dto BudgetNameDTO) bson.M {
-	return bson.M{"$set": bson.M{"name": dto.Name}}
+	return bson.M{"$set": bson.M{"name": dto.Name}, "$inc": core.IncrementVersion()}
 }
 
 func AddIngredientToRecipe(budget BudgetMaterial) bson.M {
-	return bson.M{"$addToSet": bson.M{"materials": budget}}
+	return bson.M{"$addToSet": bson.M{"materials": budget}, "$inc": core.IncrementVersion()}
 }
 
 func RemoveMaterialFromBudget(budget BudgetMaterial) bson.M {
-	return bson.M{"$pull": bson.M{"materials._id": budget.ID}}
+	return bson.M{"$pull": bson.M{"materials": bson.M{"_id": budget.ID}}, "$inc": core.IncrementVersion()}
 }
 
+// SetBudgetPrice is an update pipeline, which has no $inc, so it adds to the
+// version like $inc does on documents without one.
 func SetBudgetPrice() bson.A {
-	return bson.A{bson.D{{"$set", bson.D{{"price", bson.D{{"$sum", "$materials.price"}}}}}}}
+	return bson.A{bson.D{{Key: "$set", Value: bson.D{
+		{Key: "price", Value: bson.D{{Key: "$sum", Value: "$materials.price"}}},
+		{Key: core.VersionField, Value: bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$" + core.VersionField, 0}}}, 1}}}},
+	}}}}
 }
 
 func SetMaterialDimensionPrice(price float64) bson.D {
-	return bson.D{{"$set", bson.D{{"materials.$[material].dimension.price", price}}}}
+	return bson.D{
+		{Key: "$set", Value: bson.D{{Key: "materials.$[material].dimension.price", Value: price}}},
+		{Key: "$inc", Value: core.IncrementVersion()},
+	}
 }
 
-func SetMaterialPrice(budget BudgetDTO) bson.D {
-	return bson.D{{"$set", budget}}
+// SetMaterialPrice writes the repriced lines and total of the budget read
+// before, see GetBudgetByIdAndDimensionId.
+func SetMaterialPrice(budget BudgetDTO) bson.M {
+	return bson.M{"$set": bson.M{"materials": budget.Materials, "price": budget.Price}, "$inc": core.IncrementVersion()}
 }
 
 func GetArrayFiltersForMaterialsByDimensionId(dimensionId primitive.ObjectID) *options.UpdateOptions {
 	return options.Update().SetArrayFilters(options.ArrayFilters{
 		Filters: []interface{}{
-			bson.M{"materials.dimension._id": dimensionId},
+			bson.M{"material.dimension._id": dimensionId},
 		},
 	})
 }
 
-func GetBudgetByDimensionId(DimensionId primitive.ObjectID) bson.M {
-	return bson.M{"materials.dimension._id": DimensionId}
+func GetBudgetByDimensionId(ctx context.Context, DimensionId primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"materials.dimension._id": DimensionId})
+}
+
+func GetBudgetByIdAndDimensionId(ctx context.Context, budgetId primitive.ObjectID, dimensionId primitive.ObjectID, version int64) bson.M {
+	return core.WithVersion(core.TenantFilter(ctx, bson.M{"_id": budgetId, "materials.dimension._id": dimensionId}), version)
+}
+
+func GetBudgetByMaterialId(ctx context.Context, materialId primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"materials._id": materialId})
+}
+
+func GetBudgetByLineMaterialId(ctx context.Context, materialId primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"materials.materialId": materialId})
 }
 
-func GetBudgetByMaterialId(materialId primitive.ObjectID) bson.M {
-	return bson.M{"materials._id": materialId}
+func SetLineMaterial(materialId primitive.ObjectID, name string) bson.M {
+	return bson.M{"$set": bson.M{"materials.$[line].materialId": materialId, "materials.$[line].name": name}, "$inc": core.IncrementVersion()}
+}
+
+func GetArrayFiltersForLinesByMaterialId(materialId primitive.ObjectID) *options.UpdateOptions {
+	return options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"line.materialId": materialId},
+		},
+	})
 }
 
 func RemoveDimensionFromBudget(dimensionId primitive.ObjectID) bson.M {
-	return bson.M{"$pull": bson.M{"materials": bson.M{"dimension._id": dimensionId}}}
+	return bson.M{"$pull": bson.M{"materials": bson.M{"dimension._id": dimensionId}}, "$inc": core.IncrementVersion()}
+}
+
+func GetByOrganization(organizationId primitive.ObjectID) bson.M {
+	return bson.M{core.TenantField: organizationId}
+}
+
+func WithLinesWithoutMaterialId() bson.M {
+	return bson.M{"materials": bson.M{"$elemMatch": bson.M{"materialId": bson.M{"$exists": false}}}}
+}
+
+func SetLineMaterialId(materialId primitive.ObjectID) bson.M {
+	return bson.M{"$set": bson.M{"materials.$[line].materialId": materialId}}
+}
+
+func GetArrayFiltersForLineWithoutMaterialId(lineId primitive.ObjectID) *options.UpdateOptions {
+	return options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"line._id": lineId, "line.materialId": bson.M{"$exists": false}},
+		},
+	})
 }
@@ -2,103 +2,226 @@ package budget
 
 import (
 	"context"
-	"log"
-	"time"
 
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type repository struct {
-	db *mongo.Collection
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
 }
 
 type BudgetRepository interface {
-	FindAllBudgets() *[]BudgetDTO
-	FindBudgetByOID(oid *primitive.ObjectID) *BudgetDTO
-	FindBudgetsByDimensionId(oid *primitive.ObjectID) []BudgetDTO
-	CreateBudget(budget *BudgetNameDTO) *primitive.ObjectID
-	UpdateBudgetName(oid *primitive.ObjectID, budgetName *BudgetNameDTO) error
-	AddMaterialToBudget(oid *primitive.ObjectID, recipe *BudgetMaterial) error
-	RemoveMaterialFromBudget(oid *primitive.ObjectID, budget *BudgetMaterial) error
-	DeleteBudget(oid *primitive.ObjectID) error
-	RemoveMaterialByDimensionId(packageId *primitive.ObjectID) error
-	UpdateBudgetByIdPrice(recipeId *primitive.ObjectID) error
-	UpdateMaterialDimensionPrice(packageId *primitive.ObjectID, price float64) error
-	UpdateMaterialsPrice(packageId *primitive.ObjectID, recipe BudgetDTO) error
-	UpdateBudgetsPrice() error
-}
-
-var budgetRepositoryInstance *repository
-
-func (r *repository) FindAllBudgets() *[]BudgetDTO {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	FindBudgets(ctx context.Context, filter BudgetFilter, page core.PageRequest) ([]BudgetDTO, int64, error)
+	FindBudgetByOID(ctx context.Context, oid *primitive.ObjectID) *BudgetDTO
+	FindBudgetsByDimensionId(ctx context.Context, oid *primitive.ObjectID) []BudgetDTO
+	FindBudgetsByMaterialId(ctx context.Context, materialId *primitive.ObjectID) []BudgetDTO
+	CreateBudget(ctx context.Context, budget *BudgetNameDTO) *primitive.ObjectID
+	// UpdateBudgetName, AddMaterialToBudget and DeleteBudget write only while
+	// the budget has the version, returning core.ErrVersionConflict otherwise.
+	// Every write increments the version.
+	UpdateBudgetName(ctx context.Context, oid *primitive.ObjectID, version int64, budgetName *BudgetNameDTO) error
+	AddMaterialToBudget(ctx context.Context, oid *primitive.ObjectID, version int64, recipe *BudgetMaterial) error
+	RemoveMaterialFromBudget(ctx context.Context, oid *primitive.ObjectID, budget *BudgetMaterial) error
+	DeleteBudget(ctx context.Context, oid *primitive.ObjectID, version int64) error
+	RemoveMaterialByDimensionId(ctx context.Context, packageId *primitive.ObjectID) error
+	UpdateBudgetByIdPrice(ctx context.Context, recipeId *primitive.ObjectID) error
+	UpdateMaterialDimensionPrice(ctx context.Context, packageId *primitive.ObjectID, price float64) error
+	// UpdateMaterialsPrice writes the lines and price of the budget while it
+	// still has budget.Version, returning core.ErrVersionConflict otherwise.
+	UpdateMaterialsPrice(ctx context.Context, packageId *primitive.ObjectID, recipe BudgetDTO) error
+	// ReassignMaterial points the lines of the from material to the to
+	// material, renaming them after it.
+	ReassignMaterial(ctx context.Context, from *primitive.ObjectID, to *primitive.ObjectID, name string) error
+}
+
+// EnsureBudgetIndexes creates the indexes of the budgets by dimension and by
+// material, if missing.
+func EnsureBudgetIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("budgets").Indexes().CreateMany(ctx, []mongo.IndexModel{DimensionIndex(), MaterialIndex()})
+	return err
+}
+
+// BackfillMaterialIds links the budget lines added before lines kept their
+// material id to the material of the organization with the same name. Lines
+// whose name matches no material, or more than one, stay unlinked.
+func BackfillMaterialIds(ctx context.Context, db *mongo.Database) error {
+	budgets := db.Collection("budgets")
+
+	cursor, err := budgets.Find(ctx, WithLinesWithoutMaterialId())
+
+	if err != nil {
+		return err
+	}
+
+	var pending []Budget = []Budget{}
+
+	err = cursor.All(ctx, &pending)
+
+	if err != nil {
+		return err
+	}
+
+	materials := map[primitive.ObjectID]map[string][]primitive.ObjectID{}
+
+	for _, budget := range pending {
+		if materials[budget.OrganizationID] == nil {
+			materials[budget.OrganizationID], err = materialIdsByName(ctx, db, budget.OrganizationID)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, line := range budget.Materials {
+			matches := materials[budget.OrganizationID][core.NormalizeText(line.Name)]
+
+			if !line.MaterialID.IsZero() || len(matches) != 1 {
+				continue
+			}
+
+			_, err = budgets.UpdateOne(ctx, bson.M{"_id": budget.ID}, SetLineMaterialId(matches[0]), GetArrayFiltersForLineWithoutMaterialId(line.ID))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func materialIdsByName(ctx context.Context, db *mongo.Database, organizationId primitive.ObjectID) (map[string][]primitive.ObjectID, error) {
+	cursor, err := db.Collection("materials").Find(ctx, GetByOrganization(organizationId))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var materials []struct {
+		ID   primitive.ObjectID `bson:"_id"`
+		Name string             `bson:"name"`
+	}
+
+	err = cursor.All(ctx, &materials)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string][]primitive.ObjectID{}
+
+	for _, material := range materials {
+		name := core.NormalizeText(material.Name)
+		ids[name] = append(ids[name], material.ID)
+	}
+
+	return ids, nil
+}
+
+func (r *repository) FindBudgets(ctx context.Context, filter BudgetFilter, page core.PageRequest) ([]BudgetDTO, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgets")
 	defer cancel()
 
-	cursor, err := r.db.Find(ctx, All())
+	query := FilterBudgets(ctx, filter)
+
+	total, err := r.db.CountDocuments(ctx, query)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
 
-	var recipes *[]BudgetDTO = &[]BudgetDTO{}
+	cursor, err := r.db.Find(ctx, query, page.FindOptions(budgetSortFields))
 
 	if err != nil {
-		log.Println(err.Error())
-		return recipes
+		core.LogError(ctx, err)
+		return nil, 0, err
 	}
 
-	err = cursor.All(ctx, recipes)
+	var budgets []BudgetDTO = []BudgetDTO{}
+
+	err = cursor.All(ctx, &budgets)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return nil, 0, err
 	}
 
-	return recipes
+	return budgets, total, nil
 }
 
-func (r *repository) FindBudgetByOID(oid *primitive.ObjectID) *BudgetDTO {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) FindBudgetByOID(ctx context.Context, oid *primitive.ObjectID) *BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetByOID")
 	defer cancel()
 
 	var recipe *BudgetDTO = &BudgetDTO{}
 
-	err := r.db.FindOne(ctx, GetRecipeById(*oid)).Decode(recipe)
+	err := r.db.FindOne(ctx, GetRecipeById(ctx, *oid)).Decode(recipe)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return nil
 	}
 
 	return recipe
 }
 
-func (r *repository) FindBudgetsByDimensionId(dimensionId *primitive.ObjectID) []BudgetDTO {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) FindBudgetsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetsByDimensionId")
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, GetBudgetByDimensionId(ctx, *dimensionId))
+
+	var budgets []BudgetDTO = []BudgetDTO{}
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return budgets
+	}
+
+	err = cursor.All(ctx, &budgets)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return budgets
+}
+
+func (r *repository) FindBudgetsByMaterialId(ctx context.Context, materialId *primitive.ObjectID) []BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetsByMaterialId")
 	defer cancel()
 
-	cursor, err := r.db.Find(ctx, GetBudgetByDimensionId(*dimensionId))
+	cursor, err := r.db.Find(ctx, GetBudgetByLineMaterialId(ctx, *materialId))
 
 	var budgets []BudgetDTO = []BudgetDTO{}
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return budgets
 	}
 
 	err = cursor.All(ctx, &budgets)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return budgets
 }
 
-func (r *repository) CreateBudget(recipe *BudgetNameDTO) *primitive.ObjectID {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) CreateBudget(ctx context.Context, recipe *BudgetNameDTO) *primitive.ObjectID {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.CreateBudget")
 	defer cancel()
 
-	result, err := r.db.InsertOne(ctx, recipe)
+	result, err := r.db.InsertOne(ctx, NewBudget(ctx, *recipe))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return nil
 	}
 
@@ -111,118 +234,138 @@ func (r *repository) CreateBudget(recipe *BudgetNameDTO) *primitive.ObjectID {
 	return &id
 }
 
-func (r *repository) UpdateBudgetName(oid *primitive.ObjectID, budgetName *BudgetNameDTO) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) UpdateBudgetName(ctx context.Context, oid *primitive.ObjectID, version int64, budgetName *BudgetNameDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateBudgetName")
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetRecipeById(*oid), UpdateRecipeName(*budgetName))
+	result, err := r.db.UpdateOne(ctx, GetBudgetByIdAndVersion(ctx, *oid, version), UpdateRecipeName(*budgetName))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) AddMaterialToBudget(oid *primitive.ObjectID, budget *BudgetMaterial) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) AddMaterialToBudget(ctx context.Context, oid *primitive.ObjectID, version int64, budget *BudgetMaterial) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.AddMaterialToBudget")
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetRecipeById(*oid), AddIngredientToRecipe(*budget))
+	result, err := r.db.UpdateOne(ctx, GetBudgetByIdAndVersion(ctx, *oid, version), AddIngredientToRecipe(*budget))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) RemoveMaterialFromBudget(oid *primitive.ObjectID, budget *BudgetMaterial) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) RemoveMaterialFromBudget(ctx context.Context, oid *primitive.ObjectID, budget *BudgetMaterial) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.RemoveMaterialFromBudget")
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetRecipeById(*oid), RemoveMaterialFromBudget(*budget))
+	_, err := r.db.UpdateOne(ctx, GetRecipeById(ctx, *oid), RemoveMaterialFromBudget(*budget))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return err
 }
 
-func (r *repository) DeleteBudget(oid *primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) DeleteBudget(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.DeleteBudget")
 	defer cancel()
 
-	_, err := r.db.DeleteOne(ctx, GetRecipeById(*oid))
+	result, err := r.db.DeleteOne(ctx, GetBudgetByIdAndVersion(ctx, *oid, version))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.DeletedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) RemoveMaterialByDimensionId(dimensionId *primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) RemoveMaterialByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.RemoveMaterialByDimensionId")
 	defer cancel()
 
-	_, err := r.db.UpdateMany(ctx, GetBudgetByDimensionId(*dimensionId), RemoveDimensionFromBudget(*dimensionId))
+	_, err := r.db.UpdateMany(ctx, GetBudgetByDimensionId(ctx, *dimensionId), RemoveDimensionFromBudget(*dimensionId))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return err
 }
 
-func (r *repository) UpdateBudgetByIdPrice(budgetId *primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) UpdateBudgetByIdPrice(ctx context.Context, budgetId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateBudgetByIdPrice")
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetRecipeById(*budgetId), SetBudgetPrice())
+	_, err := r.db.UpdateOne(ctx, GetRecipeById(ctx, *budgetId), SetBudgetPrice())
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return err
 }
 
-func (r *repository) UpdateBudgetsPrice() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) UpdateMaterialDimensionPrice(ctx context.Context, dimensionId *primitive.ObjectID, price float64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateMaterialDimensionPrice")
 	defer cancel()
 
-	_, err := r.db.UpdateMany(ctx, All(), SetBudgetPrice())
+	_, err := r.db.UpdateMany(ctx, GetBudgetByDimensionId(ctx, *dimensionId), SetMaterialDimensionPrice(price), GetArrayFiltersForMaterialsByDimensionId(*dimensionId))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return err
 }
 
-func (r *repository) UpdateMaterialDimensionPrice(dimensionId *primitive.ObjectID, price float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) UpdateMaterialsPrice(ctx context.Context, packageId *primitive.ObjectID, budget BudgetDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateMaterialsPrice")
 	defer cancel()
 
-	_, err := r.db.UpdateMany(ctx, GetBudgetByDimensionId(*dimensionId), SetMaterialDimensionPrice(price), GetArrayFiltersForMaterialsByDimensionId(*dimensionId))
+	result, err := r.db.UpdateOne(ctx, GetBudgetByIdAndDimensionId(ctx, budget.ID, *packageId, budget.Version), SetMaterialPrice(budget))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) UpdateMaterialsPrice(packageId *primitive.ObjectID, budget BudgetDTO) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) ReassignMaterial(ctx context.Context, from *primitive.ObjectID, to *primitive.ObjectID, name string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.ReassignMaterial")
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetBudgetByDimensionId(*packageId), SetMaterialPrice(budget))
+	_, err := r.db.UpdateMany(ctx, GetBudgetByLineMaterialId(ctx, *from), SetLineMaterial(*to, name), GetArrayFiltersForLinesByMaterialId(*from))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 	}
 
 	return err
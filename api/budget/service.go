@@ -1,114 +1,210 @@
 package budget
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type service struct {
 	budgetRepository BudgetRepository
+	audit            audit.Recorder
+	metrics          *core.Metrics
 }
 
 type BudgetService interface {
-	GetAllBudgets() (int, *[]BudgetDTO)
-	GetBudget(r *http.Request) (int, *BudgetDTO)
-	CreateBudget(r *http.Request) (int, *BudgetDTO)
-	UpdateBudgetName(r *http.Request) (int, *BudgetDTO)
-	DeleteBudget(r *http.Request) (int, *primitive.ObjectID)
+	GetAllBudgets(ctx context.Context, r *http.Request) (*core.Page[BudgetDTO], *core.ApiError)
+	GetBudget(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError)
+	CreateBudget(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError)
+	UpdateBudgetName(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError)
+	DeleteBudget(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError)
 }
 
-var budgetServiceInstance *service
+func (s *service) GetAllBudgets(ctx context.Context, r *http.Request) (*core.Page[BudgetDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "BudgetService.GetAllBudgets")
+	defer span.End()
 
-func (s *service) GetAllBudgets() (int, *[]BudgetDTO) {
-	recipes := s.budgetRepository.FindAllBudgets()
-	return http.StatusOK, recipes
+	page, apiErr := core.ParsePageRequest(r, BudgetSortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	filter, apiErr := parseBudgetFilter(r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	budgets, total, err := s.budgetRepository.FindBudgets(ctx, *filter, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(budgets, total, *page), nil
 }
 
-func (s *service) GetBudget(r *http.Request) (int, *BudgetDTO) {
+func (s *service) GetBudget(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "BudgetService.GetBudget")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
-	budget := s.budgetRepository.FindBudgetByOID(oid)
+	budget := s.budgetRepository.FindBudgetByOID(ctx, oid)
 
 	if budget == nil {
-		return http.StatusNotFound, nil
+		return nil, core.NewNotFoundError("presupuesto")
 	}
 
-	return http.StatusOK, budget
+	return budget, nil
 }
 
-func (s *service) CreateBudget(r *http.Request) (int, *BudgetDTO) {
+func (s *service) CreateBudget(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "BudgetService.CreateBudget")
+	defer span.End()
+
 	var budgetName *BudgetNameDTO = &BudgetNameDTO{}
 
-	invalidBody := core.DecodeBody(r, budgetName)
+	apiErr := core.DecodeBody(r, budgetName)
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	oid := s.budgetRepository.CreateBudget(budgetName)
+	oid := s.budgetRepository.CreateBudget(ctx, budgetName)
 
 	if oid == nil {
-		return http.StatusInternalServerError, nil
+		return nil, core.NewInternalError(nil)
 	}
 
-	budget := s.budgetRepository.FindBudgetByOID(oid)
+	s.metrics.BudgetCreated()
+
+	budget := s.budgetRepository.FindBudgetByOID(ctx, oid)
 
 	if budget == nil {
-		return http.StatusInternalServerError, nil
+		return nil, core.NewInternalError(nil)
 	}
 
-	return http.StatusCreated, budget
+	s.audit.Record(ctx, audit.Change(audit.EntityBudget, *oid, audit.OperationCreate, nil, audit.NewSnapshot(budget)))
+
+	return budget, nil
 }
 
-func (s *service) UpdateBudgetName(r *http.Request) (int, *BudgetDTO) {
+func (s *service) UpdateBudgetName(ctx context.Context, r *http.Request) (*BudgetDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "BudgetService.UpdateBudgetName")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
 	var budget *BudgetNameDTO = &BudgetNameDTO{}
 
-	invalidBody := core.DecodeBody(r, budget)
+	apiErr := core.DecodeBody(r, budget)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	budgetFound := s.budgetRepository.FindBudgetByOID(ctx, oid)
+
+	if budgetFound == nil {
+		return nil, core.NewNotFoundError("presupuesto")
+	}
+
+	apiErr = core.CheckIfMatch(r, budgetFound.Version, "presupuesto")
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	err := s.budgetRepository.UpdateBudgetName(oid, budget)
+	err := s.budgetRepository.UpdateBudgetName(ctx, oid, budgetFound.Version, budget)
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr = core.AsWriteError(err, "presupuesto")
+		return nil, apiErr
 	}
 
-	budgetUpdated := s.budgetRepository.FindBudgetByOID(oid)
+	budgetUpdated := s.budgetRepository.FindBudgetByOID(ctx, oid)
 
 	if budgetUpdated == nil {
-		return http.StatusInternalServerError, nil
+		return nil, core.NewNotFoundError("presupuesto")
 	}
 
-	return http.StatusOK, budgetUpdated
+	s.audit.Record(ctx, audit.Change(audit.EntityBudget, *oid, audit.OperationUpdate, audit.NewSnapshot(budgetFound), audit.NewSnapshot(budgetUpdated)))
+
+	return budgetUpdated, nil
 }
 
-func (s *service) DeleteBudget(r *http.Request) (int, *primitive.ObjectID) {
+func (s *service) DeleteBudget(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "BudgetService.DeleteBudget")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	budgetFound := s.budgetRepository.FindBudgetByOID(ctx, oid)
+
+	// deleting a missing budget succeeds, there is nothing to overwrite
+	if budgetFound == nil {
+		return oid, nil
 	}
 
-	err := s.budgetRepository.DeleteBudget(oid)
+	apiErr := core.CheckIfMatch(r, budgetFound.Version, "presupuesto")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	err := s.budgetRepository.DeleteBudget(ctx, oid, budgetFound.Version)
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr = core.AsWriteError(err, "presupuesto")
+		return nil, apiErr
+	}
+
+	s.audit.Record(ctx, audit.Change(audit.EntityBudget, *oid, audit.OperationDelete, audit.NewSnapshot(budgetFound), nil))
+
+	return oid, nil
+}
+
+func parseBudgetFilter(r *http.Request) (*BudgetFilter, *core.ApiError) {
+	minPrice, apiErr := core.QueryFloat(r, "minPrice")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	maxPrice, apiErr := core.QueryFloat(r, "maxPrice")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	materialId, apiErr := core.QueryObjectId(r, "materialId")
+
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	return http.StatusOK, oid
+	return &BudgetFilter{
+		NamePrefix: r.URL.Query().Get("name"),
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		MaterialId: materialId,
+	}, nil
 }
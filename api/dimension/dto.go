@@ -5,3 +5,9 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 type DimensionDTO struct {
 	ID primitive.ObjectID `bson:"_id" json:"id" validate:"required"`
 }
+
+var DimensionSortFields = []string{"metric", "quantity"}
+
+type DimensionFilter struct {
+	Metric string
+}
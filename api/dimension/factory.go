@@ -1,36 +1,32 @@
 package dimension
 
 import (
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/api/budget"
 	"github.com/lucasbravi2019/arquitectura/api/material"
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetDimensionHandlerInstance() *handler {
-	if dimensionHandlerInstance == nil {
-		dimensionHandlerInstance = &handler{
-			service: GetDimensionServiceInstance(),
-		}
+func NewDimensionHandler(service DimensionService) DimensionHandler {
+	return &handler{
+		service: service,
 	}
-	return dimensionHandlerInstance
 }
 
-func GetDimensionServiceInstance() *service {
-	if dimensionServiceInstance == nil {
-		dimensionServiceInstance = &service{
-			dimensionRepository: GetDimensionRepositoryInstance(),
-			materialRepository:  material.GetMaterialRepositoryInstance(),
-			budgetRepository:    budget.GetBudgetRepositoryInstance(),
-		}
+func NewDimensionService(dimensionRepository DimensionRepository, materialRepository material.MaterialRepository, budgetRepository budget.BudgetRepository, unitOfWork core.UnitOfWork, recorder audit.Recorder) DimensionService {
+	return &service{
+		dimensionRepository: dimensionRepository,
+		materialRepository:  materialRepository,
+		budgetRepository:    budgetRepository,
+		unitOfWork:          unitOfWork,
+		audit:               recorder,
 	}
-	return dimensionServiceInstance
 }
 
-func GetDimensionRepositoryInstance() *repository {
-	if dimensionRepositoryInstance == nil {
-		dimensionRepositoryInstance = &repository{
-			db: core.GetDatabaseConnection().Collection("dimensions"),
-		}
+func NewDimensionRepository(db *mongo.Database, timeouts core.QueryTimeouts) DimensionRepository {
+	return &repository{
+		db:       db.Collection("dimensions"),
+		timeouts: timeouts,
 	}
-	return dimensionRepositoryInstance
 }
@@ -3,7 +3,9 @@ package dimension
 import (
 	"net/http"
 
+	"github.com/lucasbravi2019/arquitectura/api/material"
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type handler struct {
@@ -12,44 +14,63 @@ type handler struct {
 
 type DimensionHandler interface {
 	GetDimensions(w http.ResponseWriter, r *http.Request)
+	GetDimension(w http.ResponseWriter, r *http.Request)
 	CreateDimension(w http.ResponseWriter, r *http.Request)
 	UpdateDimension(w http.ResponseWriter, r *http.Request)
 	DeleteDimension(w http.ResponseWriter, r *http.Request)
 	AddDimensionToMaterial(w http.ResponseWriter, r *http.Request)
 	RemoveDimensionFromMaterials(w http.ResponseWriter, r *http.Request)
-	GetDimensionRoutes() []core.Route
+	GetDimensionRoutes() core.Routes
 }
 
-var dimensionHandlerInstance *handler
-
 func (h *handler) GetDimensions(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.GetDimensions()
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.GetDimensions(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetDimension(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetDimension(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) CreateDimension(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.CreateDimension(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.CreateDimension(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
 }
 
 func (h *handler) UpdateDimension(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.UpdateDimension(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.UpdateDimension(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) DeleteDimension(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.DeleteDimension(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.DeleteDimension(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) AddDimensionToMaterial(w http.ResponseWriter, r *http.Request) {
-	statusCode := h.service.AddDimensionToMaterial(r)
-	core.EncodeJsonResponse(w, statusCode, nil)
+	err := h.service.AddDimensionToMaterial(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, nil, err)
 }
 
 func (h *handler) RemoveDimensionFromMaterials(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.RemoveDimensionFromMaterials(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.RemoveDimensionFromMaterials(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) GetDimensionRoutes() core.Routes {
@@ -58,31 +79,87 @@ func (h *handler) GetDimensionRoutes() core.Routes {
 			Path:        "/dimensions",
 			HandlerFunc: h.GetDimensions,
 			Method:      "GET",
+			Permission:  core.PermissionReadDimensions,
+			Summary:     "Lista las dimensiones",
+			Response:    core.Page[Dimension]{},
+			QueryParams: map[string]string{
+				"limit":     "Cantidad de dimensiones por pagina, hasta 100",
+				"pageToken": "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":      "Orden: metric, quantity o createdAt, con el prefijo - para descendente",
+				"metric":    "Unidad de medida",
+			},
+		},
+		core.Route{
+			Path:        "/dimensions/{id}",
+			HandlerFunc: h.GetDimension,
+			Method:      "GET",
+			Permission:  core.PermissionReadDimensions,
+			Summary:     "Obtiene una dimension",
+			Response:    Dimension{},
+			PathParams: map[string]string{
+				"id": "ID de la dimension",
+			},
 		},
 		core.Route{
 			Path:        "/dimensions",
 			HandlerFunc: h.CreateDimension,
 			Method:      "POST",
+			Permission:  core.PermissionWriteDimensions,
+			Idempotent:  true,
+			Summary:     "Crea una dimension",
+			Request:     Dimension{},
+			Response:    Dimension{},
+			Status:      http.StatusCreated,
 		},
 		core.Route{
 			Path:        "/dimensions/{id}",
 			HandlerFunc: h.UpdateDimension,
 			Method:      "PUT",
+			Permission:  core.PermissionWriteDimensions,
+			Conditional: true,
+			Summary:     "Modifica una dimension",
+			Request:     Dimension{},
+			Response:    Dimension{},
+			PathParams: map[string]string{
+				"id": "ID de la dimension",
+			},
 		},
 		core.Route{
 			Path:        "/dimensions/{id}",
 			HandlerFunc: h.DeleteDimension,
 			Method:      "DELETE",
+			Permission:  core.PermissionDeleteDimensions,
+			Conditional: true,
+			Summary:     "Elimina una dimension, la quita de los materiales y presupuestos y recalcula sus precios",
+			Response:    primitive.ObjectID{},
+			PathParams: map[string]string{
+				"id": "ID de la dimension",
+			},
 		},
 		core.Route{
 			Path:        "/dimensions/{dimensionId}/materials/{materialId}",
 			HandlerFunc: h.AddDimensionToMaterial,
 			Method:      "PUT",
+			Permission:  core.PermissionWriteMaterials,
+			Conditional: true,
+			Summary:     "Agrega una dimension con su precio a un material",
+			Request:     material.MaterialDimensionPriceDTO{},
+			Response:    "",
+			PathParams: map[string]string{
+				"dimensionId": "ID de la dimension",
+				"materialId":  "ID del material",
+			},
 		},
 		core.Route{
 			Path:        "/dimensions/{id}/materials",
 			HandlerFunc: h.RemoveDimensionFromMaterials,
 			Method:      "DELETE",
+			Permission:  core.PermissionWriteMaterials,
+			Summary:     "Quita una dimension de todos los materiales",
+			Response:    primitive.ObjectID{},
+			PathParams: map[string]string{
+				"id": "ID de la dimension",
+			},
 		},
 	}
 }
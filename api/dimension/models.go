@@ -6,4 +6,8 @@ type Dimension struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Metric   string             `bson:"metric" json:"metric" validate:"required"`
 	Quantity float64            `bson:"quantity" json:"quantity" validate:"required"`
+	// Version is ignored in request bodies; If-Match carries it.
+	Version int64 `bson:"version" json:"version"`
+	// OrganizationID is set by the repository from the request principal.
+	OrganizationID primitive.ObjectID `bson:"organizationId" json:"-"`
 }
@@ -1,17 +1,45 @@
 package dimension
 
 import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetDimensionById(packageId primitive.ObjectID) bson.M {
-	return bson.M{"_id": packageId}
+var dimensionSortFields = map[string]string{
+	"metric":           "metric",
+	"quantity":         "quantity",
+	core.SortCreatedAt: "_id",
+}
+
+func FilterDimensions(ctx context.Context, filter DimensionFilter) bson.M {
+	query := core.TenantFilter(ctx, bson.M{})
+
+	if filter.Metric != "" {
+		query["metric"] = filter.Metric
+	}
+
+	return query
+}
+
+func GetDimensionById(ctx context.Context, packageId primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"_id": packageId})
+}
+
+// GetDimensionByIdAndVersion matches the dimension only while it has the
+// version.
+func GetDimensionByIdAndVersion(ctx context.Context, oid primitive.ObjectID, version int64) bson.M {
+	return core.WithVersion(GetDimensionById(ctx, oid), version)
 }
 
 func UpdateDimensionById(body Dimension) bson.M {
-	return bson.M{"$set": bson.M{
-		"metric":   body.Metric,
-		"quantity": body.Quantity,
-	}}
+	return bson.M{
+		"$set": bson.M{
+			"metric":   body.Metric,
+			"quantity": body.Quantity,
+		},
+		"$inc": core.IncrementVersion(),
+	}
 }
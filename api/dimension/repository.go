@@ -2,60 +2,71 @@ package dimension
 
 import (
 	"context"
-	"log"
-	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type repository struct {
-	db *mongo.Collection
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
 }
 
 type DimensionRepository interface {
-	GetDimensions() *[]Dimension
-	GetDimensionById(oid *primitive.ObjectID) *Dimension
-	CreateDimension(body *Dimension) *primitive.ObjectID
-	UpdateDimension(oid *primitive.ObjectID, body *Dimension) error
-	DeleteDimension(oid *primitive.ObjectID) error
+	FindDimensions(ctx context.Context, filter DimensionFilter, page core.PageRequest) ([]Dimension, int64, error)
+	GetDimensionById(ctx context.Context, oid *primitive.ObjectID) *Dimension
+	CreateDimension(ctx context.Context, body *Dimension) *primitive.ObjectID
+	// UpdateDimension and DeleteDimension write only while the dimension has
+	// the version, returning core.ErrVersionConflict otherwise.
+	UpdateDimension(ctx context.Context, oid *primitive.ObjectID, version int64, body *Dimension) error
+	DeleteDimension(ctx context.Context, oid *primitive.ObjectID, version int64) error
 }
 
-var dimensionRepositoryInstance *repository
+func (r *repository) FindDimensions(ctx context.Context, filter DimensionFilter, page core.PageRequest) ([]Dimension, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.FindDimensions")
+	defer cancel()
 
-func (r *repository) GetDimensions() *[]Dimension {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+	query := FilterDimensions(ctx, filter)
 
-	defer cancel()
+	total, err := r.db.CountDocuments(ctx, query)
 
-	cursor, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
 
-	var dimensions *[]Dimension = &[]Dimension{}
+	cursor, err := r.db.Find(ctx, query, page.FindOptions(dimensionSortFields))
 
 	if err != nil {
-		log.Println(err.Error())
-		return dimensions
+		core.LogError(ctx, err)
+		return nil, 0, err
 	}
 
-	err = cursor.All(ctx, dimensions)
+	var dimensions []Dimension = []Dimension{}
+
+	err = cursor.All(ctx, &dimensions)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return nil, 0, err
 	}
 
-	return dimensions
+	return dimensions, total, nil
 }
 
-func (r *repository) CreateDimension(body *Dimension) *primitive.ObjectID {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) CreateDimension(ctx context.Context, body *Dimension) *primitive.ObjectID {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.CreateDimension")
 
 	defer cancel()
 
+	body.OrganizationID = core.TenantFromContext(ctx)
+	body.Version = 1
+
 	result, err := r.db.InsertOne(ctx, body)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return nil
 	}
 
@@ -68,44 +79,54 @@ func (r *repository) CreateDimension(body *Dimension) *primitive.ObjectID {
 	return &id
 }
 
-func (r *repository) UpdateDimension(oid *primitive.ObjectID, body *Dimension) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) UpdateDimension(ctx context.Context, oid *primitive.ObjectID, version int64, body *Dimension) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.UpdateDimension")
 
 	defer cancel()
 
-	_, err := r.db.UpdateOne(ctx, GetDimensionById(*oid), UpdateDimensionById(*body))
+	result, err := r.db.UpdateOne(ctx, GetDimensionByIdAndVersion(ctx, *oid, version), UpdateDimensionById(*body))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
 	}
 
-	return err
+	return nil
 }
 
-func (r *repository) DeleteDimension(oid *primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) DeleteDimension(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.DeleteDimension")
 
 	defer cancel()
 
-	_, err := r.db.DeleteOne(ctx, GetDimensionById(*oid))
+	result, err := r.db.DeleteOne(ctx, GetDimensionByIdAndVersion(ctx, *oid, version))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return core.ErrVersionConflict
 	}
 
-	return err
+	return nil
 }
 
-func (r *repository) GetDimensionById(oid *primitive.ObjectID) *Dimension {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) GetDimensionById(ctx context.Context, oid *primitive.ObjectID) *Dimension {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.GetDimensionById")
 	defer cancel()
 
 	var envase *Dimension = &Dimension{}
 
-	err := r.db.FindOne(ctx, GetDimensionById(*oid)).Decode(envase)
+	err := r.db.FindOne(ctx, GetDimensionById(ctx, *oid)).Decode(envase)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return nil
 	}
 
@@ -1,9 +1,11 @@
 package dimension
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/api/budget"
 	"github.com/lucasbravi2019/arquitectura/api/material"
 	"github.com/lucasbravi2019/arquitectura/core"
@@ -14,139 +16,269 @@ type service struct {
 	dimensionRepository DimensionRepository
 	materialRepository  material.MaterialRepository
 	budgetRepository    budget.BudgetRepository
+	unitOfWork          core.UnitOfWork
+	audit               audit.Recorder
 }
 
 type DimensionService interface {
-	GetDimensions() (int, *[]Dimension)
-	CreateDimension(r *http.Request) (int, *Dimension)
-	UpdateDimension(r *http.Request) (int, *Dimension)
-	DeleteDimension(r *http.Request) (int, *primitive.ObjectID)
-	AddDimensionToMaterial(r *http.Request) int
-	RemoveDimensionFromMaterials(r *http.Request) (int, *primitive.ObjectID)
+	GetDimensions(ctx context.Context, r *http.Request) (*core.Page[Dimension], *core.ApiError)
+	GetDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError)
+	CreateDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError)
+	UpdateDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError)
+	DeleteDimension(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError)
+	AddDimensionToMaterial(ctx context.Context, r *http.Request) *core.ApiError
+	RemoveDimensionFromMaterials(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError)
 }
 
-var dimensionServiceInstance *service
+func (s *service) GetDimensions(ctx context.Context, r *http.Request) (*core.Page[Dimension], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.GetDimensions")
+	defer span.End()
 
-func (s *service) GetDimensions() (int, *[]Dimension) {
-	dimensions := s.dimensionRepository.GetDimensions()
+	page, apiErr := core.ParsePageRequest(r, DimensionSortFields...)
 
-	if dimensions == nil {
-		return http.StatusInternalServerError, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	return http.StatusOK, dimensions
+	var filter *DimensionFilter = &DimensionFilter{
+		Metric: r.URL.Query().Get("metric"),
+	}
+
+	dimensions, total, err := s.dimensionRepository.FindDimensions(ctx, *filter, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(dimensions, total, *page), nil
+}
+
+func (s *service) GetDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.GetDimension")
+	defer span.End()
+
+	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if oid == nil {
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	dimension := s.dimensionRepository.GetDimensionById(ctx, oid)
+
+	if dimension == nil {
+		return nil, core.NewNotFoundError("dimension")
+	}
+
+	return dimension, nil
 }
 
-func (s *service) CreateDimension(r *http.Request) (int, *Dimension) {
+func (s *service) CreateDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.CreateDimension")
+	defer span.End()
+
 	var dimensionRequest *Dimension = &Dimension{}
 
-	invalidBody := core.DecodeBody(r, dimensionRequest)
+	apiErr := core.DecodeBody(r, dimensionRequest)
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	id := s.dimensionRepository.CreateDimension(dimensionRequest)
+	id := s.dimensionRepository.CreateDimension(ctx, dimensionRequest)
 
 	if id == nil {
-		return http.StatusInternalServerError, nil
+		return nil, core.NewInternalError(nil)
 	}
 
-	dimension := s.dimensionRepository.GetDimensionById(id)
+	dimension := s.dimensionRepository.GetDimensionById(ctx, id)
 
 	if dimension == nil {
-		return http.StatusNotFound, nil
+		return nil, core.NewNotFoundError("dimension")
 	}
 
-	return http.StatusCreated, dimension
+	s.audit.Record(ctx, audit.Change(audit.EntityDimension, *id, audit.OperationCreate, nil, audit.NewSnapshot(dimension)))
+
+	return dimension, nil
 }
 
-func (s *service) UpdateDimension(r *http.Request) (int, *Dimension) {
+func (s *service) UpdateDimension(ctx context.Context, r *http.Request) (*Dimension, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.UpdateDimension")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
 	var dimensionRequest *Dimension = &Dimension{}
 
-	invalidBody := core.DecodeBody(r, dimensionRequest)
+	apiErr := core.DecodeBody(r, dimensionRequest)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	dimensionFound := s.dimensionRepository.GetDimensionById(ctx, oid)
+
+	if dimensionFound == nil {
+		return nil, core.NewNotFoundError("dimension")
+	}
+
+	apiErr = core.CheckIfMatch(r, dimensionFound.Version, "dimension")
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	err := s.dimensionRepository.UpdateDimension(oid, dimensionRequest)
+	err := s.dimensionRepository.UpdateDimension(ctx, oid, dimensionFound.Version, dimensionRequest)
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr = core.AsWriteError(err, "dimension")
+		return nil, apiErr
 	}
 
-	dimension := s.dimensionRepository.GetDimensionById(oid)
+	dimension := s.dimensionRepository.GetDimensionById(ctx, oid)
 
 	if dimension == nil {
-		return http.StatusNotFound, nil
+		return nil, core.NewNotFoundError("dimension")
 	}
 
-	return http.StatusOK, dimension
+	s.audit.Record(ctx, audit.Change(audit.EntityDimension, *oid, audit.OperationUpdate, audit.NewSnapshot(dimensionFound), audit.NewSnapshot(dimension)))
+
+	return dimension, nil
 }
 
-func (s *service) DeleteDimension(r *http.Request) (int, *primitive.ObjectID) {
+func (s *service) DeleteDimension(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.DeleteDimension")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
-	err := s.dimensionRepository.DeleteDimension(oid)
+	var changes []audit.Entry
 
-	if err != nil {
-		return http.StatusInternalServerError, nil
-	}
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		dimensionFound := s.dimensionRepository.GetDimensionById(ctx, oid)
+		materialsFound := s.materialRepository.FindMaterialsByDimensionId(ctx, oid)
+		budgetsFound := s.budgetRepository.FindBudgetsByDimensionId(ctx, oid)
 
-	var materialDimension *material.MaterialDimensionDTO = &material.MaterialDimensionDTO{
-		DimensionOid: *oid,
-	}
+		// a missing dimension is still pulled from the materials and
+		// budgets that kept it
+		if dimensionFound != nil {
+			apiErr := core.CheckIfMatch(r, dimensionFound.Version, "dimension")
 
-	err = s.materialRepository.RemoveDimensionFromMaterials(*materialDimension)
+			if apiErr != nil {
+				return apiErr
+			}
 
-	if err != nil {
-		return http.StatusInternalServerError, nil
-	}
+			err := s.dimensionRepository.DeleteDimension(ctx, oid, dimensionFound.Version)
 
-	err = s.budgetRepository.RemoveMaterialByDimensionId(oid)
+			if err != nil {
+				return err
+			}
+		}
 
-	if err != nil {
-		return http.StatusInternalServerError, nil
-	}
+		var materialDimension *material.MaterialDimensionDTO = &material.MaterialDimensionDTO{
+			DimensionOid: *oid,
+		}
+
+		err := s.materialRepository.RemoveDimensionFromMaterials(ctx, *materialDimension)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.budgetRepository.RemoveMaterialByDimensionId(ctx, oid)
 
-	err = s.budgetRepository.UpdateBudgetsPrice()
+		if err != nil {
+			return err
+		}
+
+		for i := range budgetsFound {
+			err = s.budgetRepository.UpdateBudgetByIdPrice(ctx, &budgetsFound[i].ID)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		changes = []audit.Entry{}
+
+		if dimensionFound != nil {
+			changes = append(changes, audit.Change(audit.EntityDimension, *oid, audit.OperationDelete, audit.NewSnapshot(dimensionFound), nil))
+		}
+
+		for _, change := range s.materialChanges(ctx, materialsFound, audit.OperationRemoveDimension) {
+			changes = append(changes, change.CausedBy(audit.EntityDimension, audit.OperationDelete))
+		}
+
+		for _, change := range s.budgetChanges(ctx, budgetsFound, audit.OperationRemoveMaterial) {
+			changes = append(changes, change.CausedBy(audit.EntityDimension, audit.OperationDelete))
+		}
+
+		return nil
+	})
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr := core.AsWriteError(err, "dimension")
+		return nil, apiErr
 	}
 
-	return http.StatusOK, oid
+	s.audit.Record(ctx, changes...)
+
+	return oid, nil
 }
 
-func (s *service) AddDimensionToMaterial(r *http.Request) int {
+func (s *service) AddDimensionToMaterial(ctx context.Context, r *http.Request) *core.ApiError {
+	ctx, span := core.StartSpan(ctx, "DimensionService.AddDimensionToMaterial")
+	defer span.End()
+
 	materialOid := mux.Vars(r)["materialId"]
 	dimensionOid := mux.Vars(r)["dimensionId"]
 	materialId := core.ConvertHexToObjectId(materialOid)
 	dimensionId := core.ConvertHexToObjectId(dimensionOid)
 
+	if materialId == nil {
+		return core.NewInvalidIdError("materialId")
+	}
+
+	if dimensionId == nil {
+		return core.NewInvalidIdError("dimensionId")
+	}
+
 	var priceDTO *material.MaterialDimensionPriceDTO = &material.MaterialDimensionPriceDTO{}
 
-	invalidBody := core.DecodeBody(r, priceDTO)
+	apiErr := core.DecodeBody(r, priceDTO)
+
+	if apiErr != nil {
+		return apiErr
+	}
+
+	materialFound := s.materialRepository.FindMaterialByOID(ctx, materialId)
 
-	if invalidBody {
-		return http.StatusBadRequest
+	if materialFound == nil {
+		return core.NewNotFoundError("material")
 	}
 
-	envase := s.dimensionRepository.GetDimensionById(dimensionId)
+	apiErr = core.CheckIfMatch(r, materialFound.Version, "material")
+
+	if apiErr != nil {
+		return apiErr
+	}
+
+	envase := s.dimensionRepository.GetDimensionById(ctx, dimensionId)
 
 	if envase == nil {
-		return http.StatusNotFound
+		return core.NewNotFoundError("dimension")
+	}
+
+	// the material keeps the price it already has for the dimension
+	if material.HasDimension(materialFound.Dimensions, *dimensionId) {
+		return nil
 	}
 
 	var materialDimension *material.MaterialDimension = &material.MaterialDimension{
@@ -156,27 +288,72 @@ func (s *service) AddDimensionToMaterial(r *http.Request) int {
 		Price:    priceDTO.Price,
 	}
 
-	err := s.materialRepository.AddDimensionToMaterial(materialId, dimensionId, materialDimension)
+	err := s.materialRepository.AddDimensionToMaterial(ctx, materialId, materialFound.Version, dimensionId, materialDimension)
 
 	if err != nil {
-		return http.StatusInternalServerError
+		apiErr = core.AsWriteError(err, "material")
+		return apiErr
 	}
 
-	return http.StatusOK
+	s.audit.Record(ctx, s.materialChanges(ctx, []material.MaterialDTO{*materialFound}, audit.OperationAddDimension)...)
+
+	return nil
 }
 
-func (s *service) RemoveDimensionFromMaterials(r *http.Request) (int, *primitive.ObjectID) {
-	dimensionId := core.ConvertHexToObjectId(mux.Vars(r)["dimensionId"])
+func (s *service) RemoveDimensionFromMaterials(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "DimensionService.RemoveDimensionFromMaterials")
+	defer span.End()
+
+	dimensionId := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if dimensionId == nil {
+		return nil, core.NewInvalidIdError("id")
+	}
 
 	var ingredientPackageDto *material.MaterialDimensionDTO = &material.MaterialDimensionDTO{
 		DimensionOid: *dimensionId,
 	}
 
-	err := s.materialRepository.RemoveDimensionFromMaterials(*ingredientPackageDto)
+	materialsFound := s.materialRepository.FindMaterialsByDimensionId(ctx, dimensionId)
+
+	err := s.materialRepository.RemoveDimensionFromMaterials(ctx, *ingredientPackageDto)
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		return nil, core.NewInternalError(err)
+	}
+
+	s.audit.Record(ctx, s.materialChanges(ctx, materialsFound, audit.OperationRemoveDimension)...)
+
+	return dimensionId, nil
+}
+
+// materialChanges reads the materials again after the operation and pairs
+// them with their state before it.
+func (s *service) materialChanges(ctx context.Context, materialsFound []material.MaterialDTO, operation string) []audit.Entry {
+	changes := []audit.Entry{}
+
+	for i := range materialsFound {
+		updated := s.materialRepository.FindMaterialByOID(ctx, &materialsFound[i].ID)
+
+		if updated != nil {
+			changes = append(changes, audit.Change(audit.EntityMaterial, updated.ID, operation, audit.NewSnapshot(materialsFound[i]), audit.NewSnapshot(updated)))
+		}
+	}
+
+	return changes
+}
+
+// budgetChanges is materialChanges for budgets.
+func (s *service) budgetChanges(ctx context.Context, budgetsFound []budget.BudgetDTO, operation string) []audit.Entry {
+	changes := []audit.Entry{}
+
+	for i := range budgetsFound {
+		updated := s.budgetRepository.FindBudgetByOID(ctx, &budgetsFound[i].ID)
+
+		if updated != nil {
+			changes = append(changes, audit.Change(audit.EntityBudget, updated.ID, operation, audit.NewSnapshot(budgetsFound[i]), audit.NewSnapshot(updated)))
+		}
 	}
 
-	return http.StatusOK, dimensionId
+	return changes
 }
@@ -0,0 +1,61 @@
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+//go:embed swagger.html
+var swaggerPage []byte
+
+type handler struct {
+	document *core.OpenApiDocument
+}
+
+type DocsHandler interface {
+	GetOpenApi(w http.ResponseWriter, r *http.Request)
+	GetSwaggerUi(w http.ResponseWriter, r *http.Request)
+	GetDocsRoutes() core.Routes
+}
+
+func NewDocsHandler(document *core.OpenApiDocument) DocsHandler {
+	return &handler{
+		document: document,
+	}
+}
+
+// GetOpenApi writes the bare specification, without the Response envelope,
+// so code generators and Swagger UI can read it.
+func (h *handler) GetOpenApi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.document)
+}
+
+func (h *handler) GetSwaggerUi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(swaggerPage)
+}
+
+func (h *handler) GetDocsRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/openapi.json",
+			HandlerFunc: h.GetOpenApi,
+			Method:      "GET",
+			Public:      true,
+			Summary:     "Especificacion OpenAPI de la API",
+		},
+		core.Route{
+			Path:        "/docs",
+			HandlerFunc: h.GetSwaggerUi,
+			Method:      "GET",
+			Public:      true,
+			Summary:     "Documentacion interactiva de la API",
+		},
+	}
+}
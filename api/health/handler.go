@@ -0,0 +1,60 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	monitor *core.HealthMonitor
+}
+
+type HealthHandler interface {
+	Live(w http.ResponseWriter, r *http.Request)
+	Ready(w http.ResponseWriter, r *http.Request)
+	GetHealthRoutes() core.Routes
+}
+
+func NewHealthHandler(monitor *core.HealthMonitor) HealthHandler {
+	return &handler{
+		monitor: monitor,
+	}
+}
+
+func (h *handler) Live(w http.ResponseWriter, r *http.Request) {
+	core.EncodeJsonResponse(w, http.StatusOK, core.HealthReport{Status: core.HealthStatusUp}, nil)
+}
+
+func (h *handler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.monitor.Report()
+
+	statusCode := http.StatusOK
+
+	if report.Status != core.HealthStatusUp {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	core.EncodeJsonResponse(w, statusCode, report, nil)
+}
+
+func (h *handler) GetHealthRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/health/live",
+			HandlerFunc: h.Live,
+			Method:      "GET",
+			Public:      true,
+			Summary:     "Indica si el proceso esta vivo",
+			Response:    core.HealthReport{},
+		},
+		core.Route{
+			Path:        "/health/ready",
+			HandlerFunc: h.Ready,
+			Method:      "GET",
+			Public:      true,
+			Summary:     "Indica si el servicio puede atender peticiones, con el estado de cada componente",
+			Response:    core.HealthReport{},
+		},
+	}
+}
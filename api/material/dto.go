@@ -8,10 +8,17 @@ type MaterialNameDTO struct {
 	Name string `json:"name" validate:"required"`
 }
 
+// MergeMaterialDTO names the material merged into the one of the path, which
+// is deleted afterwards.
+type MergeMaterialDTO struct {
+	MaterialID primitive.ObjectID `json:"materialId" validate:"required"`
+}
+
 type MaterialDTO struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Name       string             `bson:"name,omitempty" json:"name,omitempty" validate:"required"`
 	Dimensions []DimensionDTO     `bson:"dimensions,omitempty" json:"dimensions,omitempty"`
+	Version    int64              `bson:"version" json:"version"`
 }
 
 type DimensionDTO struct {
@@ -43,3 +50,10 @@ type MaterialDetailsDTO struct {
 	Metric   string  `json:"metric,omitempty"`
 	Quantity float32 `json:"quantity,omitempty"`
 }
+
+var MaterialSortFields = []string{"name"}
+
+type MaterialFilter struct {
+	NamePrefix string
+	Metric     string
+}
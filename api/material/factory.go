@@ -1,34 +1,31 @@
 package material
 
 import (
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/api/budget"
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetMaterialHandlerInstance() *handler {
-	if materialHandlerInstance == nil {
-		materialHandlerInstance = &handler{
-			service: GetMaterialServiceInstance(),
-		}
+func NewMaterialHandler(service MaterialService) MaterialHandler {
+	return &handler{
+		service: service,
 	}
-	return materialHandlerInstance
 }
 
-func GetMaterialServiceInstance() *service {
-	if materialServiceInstance == nil {
-		materialServiceInstance = &service{
-			materialRepository: GetMaterialRepositoryInstance(),
-			budgetRepository:   budget.GetBudgetRepositoryInstance(),
-		}
+func NewMaterialService(materialRepository MaterialRepository, budgetRepository budget.BudgetRepository, unitOfWork core.UnitOfWork, recorder audit.Recorder, metrics *core.Metrics) MaterialService {
+	return &service{
+		materialRepository: materialRepository,
+		budgetRepository:   budgetRepository,
+		unitOfWork:         unitOfWork,
+		audit:              recorder,
+		metrics:            metrics,
 	}
-	return materialServiceInstance
 }
 
-func GetMaterialRepositoryInstance() *repository {
-	if materialRepositoryInstance == nil {
-		materialRepositoryInstance = &repository{
-			materialCollection: core.GetDatabaseConnection().Collection("materials"),
-		}
+func NewMaterialRepository(db *mongo.Database, timeouts core.QueryTimeouts) MaterialRepository {
+	return &repository{
+		materialCollection: db.Collection("materials"),
+		timeouts:           timeouts,
 	}
-	return materialRepositoryInstance
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type handler struct {
@@ -12,46 +13,79 @@ type handler struct {
 
 type MaterialHandler interface {
 	GetAllMaterials(w http.ResponseWriter, r *http.Request)
+	GetMaterial(w http.ResponseWriter, r *http.Request)
 	CreateMaterial(w http.ResponseWriter, r *http.Request)
 	UpdateMaterial(w http.ResponseWriter, r *http.Request)
 	DeleteMaterial(w http.ResponseWriter, r *http.Request)
-	AddMaterialToRecipe(w http.ResponseWriter, r *http.Request)
-	AddPackageToMaterial(w http.ResponseWriter, r *http.Request)
-	RemovePackageFromMaterials(w http.ResponseWriter, r *http.Request)
+	AddMaterialToBudget(w http.ResponseWriter, r *http.Request)
 	ChangeMaterialPrice(w http.ResponseWriter, r *http.Request)
+	MergeMaterials(w http.ResponseWriter, r *http.Request)
 	GetMaterialRoutes() core.Routes
 }
 
-var materialHandlerInstance *handler
-
 func (h *handler) GetAllMaterials(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.GetAllMaterials()
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.GetAllMaterials(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetMaterial(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetMaterial(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) CreateMaterial(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.CreateMaterial(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.CreateMaterial(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
 }
 
 func (h *handler) UpdateMaterial(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.UpdateMaterial(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.UpdateMaterial(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) DeleteMaterial(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.DeleteMaterial(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.DeleteMaterial(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) AddMaterialToBudget(w http.ResponseWriter, r *http.Request) {
-	statusCode := h.service.AddMaterialToBudget(r)
-	core.EncodeJsonResponse(w, statusCode, nil)
+	err := h.service.AddMaterialToBudget(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, nil, err)
 }
 
 func (h *handler) ChangeMaterialPrice(w http.ResponseWriter, r *http.Request) {
-	statusCode, body := h.service.ChangeMaterialPrice(r)
-	core.EncodeJsonResponse(w, statusCode, body)
+	body, err := h.service.ChangeMaterialPrice(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) MergeMaterials(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.MergeMaterials(r.Context(), r)
+
+	if body != nil {
+		core.SetETag(w, body.Version)
+	}
+
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
 }
 
 func (h *handler) GetMaterialRoutes() core.Routes {
@@ -60,31 +94,106 @@ func (h *handler) GetMaterialRoutes() core.Routes {
 			Path:        "/materials",
 			HandlerFunc: h.GetAllMaterials,
 			Method:      "GET",
+			Permission:  core.PermissionReadMaterials,
+			Summary:     "Lista los materiales con sus dimensiones",
+			Response:    core.Page[MaterialDTO]{},
+			QueryParams: map[string]string{
+				"limit":     "Cantidad de materiales por pagina, hasta 100",
+				"pageToken": "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":      "Orden: name o createdAt, con el prefijo - para descendente",
+				"name":      "Prefijo del nombre, sin distinguir mayusculas",
+				"metric":    "Unidad de medida de alguna de sus dimensiones",
+			},
+		},
+		core.Route{
+			Path:        "/materials/{id}",
+			HandlerFunc: h.GetMaterial,
+			Method:      "GET",
+			Permission:  core.PermissionReadMaterials,
+			Summary:     "Obtiene un material con sus dimensiones",
+			Response:    MaterialDTO{},
+			PathParams: map[string]string{
+				"id": "ID del material",
+			},
 		},
 		core.Route{
 			Path:        "/materials",
 			HandlerFunc: h.CreateMaterial,
 			Method:      "POST",
+			Permission:  core.PermissionWriteMaterials,
+			Idempotent:  true,
+			Summary:     "Crea un material",
+			Request:     MaterialNameDTO{},
+			Response:    MaterialDTO{},
+			Status:      http.StatusCreated,
 		},
 		core.Route{
 			Path:        "/materials/{id}",
 			HandlerFunc: h.UpdateMaterial,
 			Method:      "PUT",
+			Permission:  core.PermissionWriteMaterials,
+			Conditional: true,
+			Summary:     "Renombra un material",
+			Request:     MaterialNameDTO{},
+			Response:    MaterialDTO{},
+			PathParams: map[string]string{
+				"id": "ID del material",
+			},
 		},
 		core.Route{
 			Path:        "/materials/{id}/price",
 			HandlerFunc: h.ChangeMaterialPrice,
 			Method:      "PUT",
+			Permission:  core.PermissionWritePrices,
+			Conditional: true,
+			Summary:     "Cambia el precio de una dimension de material y recalcula los presupuestos que la usan",
+			Request:     MaterialDimensionPriceDTO{},
+			Response:    MaterialDTO{},
+			PathParams: map[string]string{
+				"id": "ID de la dimension del material",
+			},
+		},
+		core.Route{
+			Path:        "/materials/{id}/merge",
+			HandlerFunc: h.MergeMaterials,
+			Method:      "POST",
+			Permission:  core.PermissionWriteMaterials,
+			Conditional: true,
+			Summary:     "Combina otro material en este: suma sus dimensiones, le pasa sus lineas de presupuesto y lo elimina",
+			Request:     MergeMaterialDTO{},
+			Response:    MaterialDTO{},
+			PathParams: map[string]string{
+				"id": "ID del material que se conserva",
+			},
 		},
 		core.Route{
 			Path:        "/materials/{id}",
 			HandlerFunc: h.DeleteMaterial,
 			Method:      "DELETE",
+			Permission:  core.PermissionWriteMaterials,
+			Conditional: true,
+			Summary:     "Elimina un material",
+			Response:    primitive.ObjectID{},
+			PathParams: map[string]string{
+				"id": "ID del material",
+			},
 		},
 		core.Route{
 			Path:        "/materials/{materialId}/budgets/{budgetId}",
 			HandlerFunc: h.AddMaterialToBudget,
 			Method:      "PUT",
+			Permission:  core.PermissionWriteBudgets,
+			// each call reprices the budget, so it gets its own, tighter limit
+			RateLimitGroup: "budgetLines",
+			Conditional:    true,
+			Idempotent:     true,
+			Summary:        "Agrega un material a un presupuesto",
+			Request:        MaterialDetailsDTO{},
+			Response:       "",
+			PathParams: map[string]string{
+				"materialId": "ID del material",
+				"budgetId":   "ID del presupuesto",
+			},
 		},
 	}
 }
@@ -8,6 +8,9 @@ type Material struct {
 	ID         primitive.ObjectID  `bson:"_id,omitempty" validate:"required"`
 	Name       string              `bson:"name" validate:"required"`
 	Dimensions []MaterialDimension `bson:"dimensions" validate:"required"`
+	Version    int64               `bson:"version"`
+	// OrganizationID is set by the repository from the request principal.
+	OrganizationID primitive.ObjectID `bson:"organizationId"`
 }
 
 type MaterialDimension struct {
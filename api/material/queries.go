@@ -1,62 +1,120 @@
 package material
 
 import (
-	"strings"
+	"context"
+	"regexp"
 
+	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func All() bson.M {
-	return bson.M{}
+var materialSortFields = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "_id",
 }
 
-func GetMaterialById(oid primitive.ObjectID) bson.M {
-	return bson.M{"_id": oid}
+// NameIndex keeps material names unique within the organization, ignoring
+// case and accents.
+func NameIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: core.TenantField, Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().
+			SetName("materials_name").
+			SetUnique(true).
+			SetCollation(NameCollation()),
+	}
 }
 
-func GetMaterialByDimensionId(packageId primitive.ObjectID) bson.M {
-	return bson.M{
-		"dimensions._id": packageId,
+// NameCollation compares names ignoring case and accents.
+func NameCollation() *options.Collation {
+	return &options.Collation{Locale: "es", Strength: 1}
+}
+
+// DimensionIndex serves GetMaterialByDimensionId, which every dimension price
+// change and deletion runs.
+func DimensionIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: core.TenantField, Value: 1}, {Key: "dimensions._id", Value: 1}},
+		Options: options.Index().SetName("materials_dimension"),
 	}
 }
 
-func GetAggregateCreateMaterials(ingredient *MaterialNameDTO) mongo.Pipeline {
-	project := bson.D{
-		{Key: "$project", Value: bson.D{
-			{Key: "name", Value: bson.D{
-				{Key: "$toLower", Value: "$name"},
-			}},
-		}},
+// NamesByCreation lists the id, organization and name of every material,
+// oldest first.
+func NamesByCreation() *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"name": 1, core.TenantField: 1})
+}
+
+func FilterMaterials(ctx context.Context, filter MaterialFilter) bson.M {
+	query := core.TenantFilter(ctx, bson.M{})
+
+	if filter.NamePrefix != "" {
+		query["name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.NamePrefix), "$options": "i"}
+	}
+
+	if filter.Metric != "" {
+		query["dimensions.metric"] = filter.Metric
 	}
 
-	match := bson.D{
-		{Key: "$match", Value: bson.D{
-			{Key: "name", Value: strings.ToLower(ingredient.Name)},
-		}},
+	return query
+}
+
+func GetMaterialById(ctx context.Context, oid primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{"_id": oid})
+}
+
+// GetMaterialByIdAndVersion matches the material only while it has the
+// version.
+func GetMaterialByIdAndVersion(ctx context.Context, oid primitive.ObjectID, version int64) bson.M {
+	return core.WithVersion(GetMaterialById(ctx, oid), version)
+}
+
+func GetMaterialByDimensionId(ctx context.Context, packageId primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, bson.M{
+		"dimensions._id": packageId,
+	})
+}
+
+// GetMaterialByName matches the materials named like name under
+// NameCollation, other than the excluded one.
+func GetMaterialByName(ctx context.Context, name string, excludedId *primitive.ObjectID) bson.M {
+	query := core.TenantFilter(ctx, bson.M{"name": name})
+
+	if excludedId != nil {
+		query["_id"] = bson.M{"$ne": *excludedId}
 	}
 
-	return mongo.Pipeline{project, match}
+	return query
+}
+
+func GetMaterialWithoutExistingDimension(ctx context.Context, materialId primitive.ObjectID, version int64, dimensionId primitive.ObjectID) bson.M {
+	return core.WithVersion(core.TenantFilter(ctx, bson.M{"_id": materialId, "dimensions._id": bson.M{"$ne": dimensionId}}), version)
 }
 
-func GetMaterialWithoutExistingDimension(materialId primitive.ObjectID, dimensionId primitive.ObjectID) bson.D {
-	return bson.D{{"_id", materialId}, {"dimensions._id", bson.D{{"$ne", dimensionId}}}}
+func GetMaterialByDimensionIdAndVersion(ctx context.Context, dimensionId primitive.ObjectID, version int64) bson.M {
+	return core.WithVersion(GetMaterialByDimensionId(ctx, dimensionId), version)
 }
 
 func UpdateMaterialName(dto MaterialNameDTO) bson.M {
-	return bson.M{"$set": bson.M{"name": dto.Name}}
+	return bson.M{"$set": bson.M{"name": dto.Name}, "$inc": core.IncrementVersion()}
 }
 
 func PushDimensionIntoMaterial(envase MaterialDimension) bson.M {
-	return bson.M{"$addToSet": bson.M{
-		"dimensions": envase,
-	}}
+	return bson.M{
+		"$addToSet": bson.M{
+			"dimensions": envase,
+		},
+		"$inc": core.IncrementVersion(),
+	}
 }
 
 func PullDimensionFromMaterials(dimension MaterialDimensionDTO) bson.M {
-	return bson.M{"$pull": bson.M{"dimensions": bson.M{"_id": dimension.DimensionOid}}}
+	return bson.M{"$pull": bson.M{"dimensions": bson.M{"_id": dimension.DimensionOid}}, "$inc": core.IncrementVersion()}
 }
 
 func SetMaterialPrice(price float64) bson.M {
@@ -64,6 +122,7 @@ func SetMaterialPrice(price float64) bson.M {
 		"$set": bson.M{
 			"dimensions.$[dimension].price": price,
 		},
+		"$inc": core.IncrementVersion(),
 	}
 }
 
@@ -2,82 +2,182 @@ package material
 
 import (
 	"context"
-	"log"
-	"time"
+	"errors"
+	"fmt"
 
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrDuplicateMaterialName rejects names that differ from the name of another
+// material of the organization only in case or accents.
+var ErrDuplicateMaterialName = errors.New("ya existe un material con ese nombre")
+
 type repository struct {
-	materialCollection  *mongo.Collection
-	dimensionCollection *mongo.Collection
-	budgetCollection    *mongo.Collection
+	materialCollection *mongo.Collection
+	timeouts           core.QueryTimeouts
 }
 
 type MaterialRepository interface {
-	GetAllMaterials() []MaterialDTO
-	FindMaterialByOID(oid *primitive.ObjectID) *MaterialDTO
-	FindMaterialByPackageId(packageId *primitive.ObjectID) *MaterialDTO
-	ValidateExistingMaterial(MaterialName *MaterialNameDTO) error
-	CreateMaterial(Material *Material) *primitive.ObjectID
-	UpdateMaterial(oid *primitive.ObjectID, dto *MaterialNameDTO) error
-	DeleteMaterial(oid *primitive.ObjectID) error
-	AddDimensionToMaterial(MaterialOid *primitive.ObjectID, packageOid *primitive.ObjectID, envase *MaterialDimension) error
-	RemoveDimensionFromMaterials(dto MaterialDimensionDTO) error
-	ChangeMaterialPrice(packageOid *primitive.ObjectID, priceDTO *MaterialDimensionPriceDTO) error
-}
-
-var materialRepositoryInstance *repository
-
-func (r *repository) GetAllMaterials() []MaterialDTO {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	FindMaterials(ctx context.Context, filter MaterialFilter, page core.PageRequest) ([]MaterialDTO, int64, error)
+	FindMaterialByOID(ctx context.Context, oid *primitive.ObjectID) *MaterialDTO
+	FindMaterialByPackageId(ctx context.Context, packageId *primitive.ObjectID) *MaterialDTO
+	FindMaterialsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []MaterialDTO
+	// ValidateExistingMaterial returns ErrDuplicateMaterialName when another
+	// material than excludedId, if any, has the name.
+	ValidateExistingMaterial(ctx context.Context, materialName *MaterialNameDTO, excludedId *primitive.ObjectID) error
+	// CreateMaterial returns ErrDuplicateMaterialName when the name is taken,
+	// by a material created since ValidateExistingMaterial checked it.
+	CreateMaterial(ctx context.Context, Material *Material) (*primitive.ObjectID, error)
+	// UpdateMaterial, DeleteMaterial, AddDimensionToMaterial and
+	// ChangeMaterialPrice write only while the material has the version,
+	// returning core.ErrVersionConflict otherwise. AddDimensionToMaterial
+	// expects a dimension the material does not have yet. Every write
+	// increments the version.
+	//
+	// UpdateMaterial returns ErrDuplicateMaterialName when the name is taken.
+	UpdateMaterial(ctx context.Context, oid *primitive.ObjectID, version int64, dto *MaterialNameDTO) error
+	DeleteMaterial(ctx context.Context, oid *primitive.ObjectID, version int64) error
+	AddDimensionToMaterial(ctx context.Context, MaterialOid *primitive.ObjectID, version int64, packageOid *primitive.ObjectID, envase *MaterialDimension) error
+	RemoveDimensionFromMaterials(ctx context.Context, dto MaterialDimensionDTO) error
+	ChangeMaterialPrice(ctx context.Context, packageOid *primitive.ObjectID, version int64, priceDTO *MaterialDimensionPriceDTO) error
+}
 
-	results, err := r.materialCollection.Find(ctx, All())
+// EnsureDimensionIndex creates the index of the materials by dimension, if
+// missing.
+func EnsureDimensionIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("materials").Indexes().CreateOne(ctx, DimensionIndex())
+	return err
+}
+
+// EnsureNameIndex creates the unique name index, if missing. Existing
+// duplicates must be renamed first, see RenameDuplicateNames.
+func EnsureNameIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("materials").Indexes().CreateOne(ctx, NameIndex())
+	return err
+}
+
+// RenameDuplicateNames appends a counter to the name of the materials that
+// repeat an older material of their organization, ignoring case and accents.
+func RenameDuplicateNames(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("materials")
+
+	cursor, err := collection.Find(ctx, bson.M{}, NamesByCreation())
 
 	if err != nil {
-		log.Println(err.Error())
+		return err
 	}
 
-	var materials *[]MaterialDTO = &[]MaterialDTO{}
+	var materials []Material = []Material{}
 
-	err = results.All(ctx, materials)
+	err = cursor.All(ctx, &materials)
 
 	if err != nil {
-		log.Println(err.Error())
+		return err
 	}
 
-	if len(*materials) < 1 {
-		return []MaterialDTO{}
+	taken := map[primitive.ObjectID]map[string]bool{}
+
+	for _, material := range materials {
+		if taken[material.OrganizationID] == nil {
+			taken[material.OrganizationID] = map[string]bool{}
+		}
+
+		name := UniqueName(material.Name, taken[material.OrganizationID])
+
+		if name == material.Name {
+			continue
+		}
+
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": material.ID}, UpdateMaterialName(MaterialNameDTO{Name: name}))
+
+		if err != nil {
+			return err
+		}
+
+		core.Log(ctx, core.LogLevelWarn, "Material renombrado por tener un nombre repetido", core.LogFields{
+			"materialId": material.ID.Hex(),
+			"from":       material.Name,
+			"to":         name,
+		})
+	}
+
+	return nil
+}
+
+// UniqueName returns the name, or the name followed by the first counter
+// free in taken, and marks the result as taken. Names are compared
+// normalized, see core.NormalizeText.
+func UniqueName(name string, taken map[string]bool) string {
+	unique := name
+
+	for i := 2; taken[core.NormalizeText(unique)]; i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+
+	taken[core.NormalizeText(unique)] = true
+
+	return unique
+}
+
+func (r *repository) FindMaterials(ctx context.Context, filter MaterialFilter, page core.PageRequest) ([]MaterialDTO, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterials")
+	defer cancel()
+
+	query := FilterMaterials(ctx, filter)
+
+	total, err := r.materialCollection.CountDocuments(ctx, query)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	results, err := r.materialCollection.Find(ctx, query, page.FindOptions(materialSortFields))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	var materials []MaterialDTO = []MaterialDTO{}
+
+	err = results.All(ctx, &materials)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
 	}
 
-	return *materials
+	return materials, total, nil
 }
 
-func (r *repository) FindMaterialByOID(oid *primitive.ObjectID) *MaterialDTO {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) FindMaterialByOID(ctx context.Context, oid *primitive.ObjectID) *MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialByOID")
 	defer cancel()
 
 	var material *MaterialDTO = &MaterialDTO{}
 
-	err := r.materialCollection.FindOne(ctx, GetMaterialById(*oid)).Decode(material)
+	err := r.materialCollection.FindOne(ctx, GetMaterialById(ctx, *oid)).Decode(material)
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return nil
 	}
 
 	return material
 }
 
-func (r *repository) FindMaterialByPackageId(packageId *primitive.ObjectID) *MaterialDTO {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) FindMaterialByPackageId(ctx context.Context, packageId *primitive.ObjectID) *MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialByPackageId")
 	defer cancel()
 
 	var material *MaterialDTO = &MaterialDTO{}
 
-	err := r.materialCollection.FindOne(ctx, GetMaterialByDimensionId(*packageId)).Decode(material)
+	err := r.materialCollection.FindOne(ctx, GetMaterialByDimensionId(ctx, *packageId)).Decode(material)
 
 	if err != nil {
 		return nil
@@ -86,110 +186,155 @@ func (r *repository) FindMaterialByPackageId(packageId *primitive.ObjectID) *Mat
 	return material
 }
 
-func (r *repository) CreateMaterial(material *Material) *primitive.ObjectID {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) FindMaterialsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialsByDimensionId")
 	defer cancel()
 
-	insertResult, err := r.materialCollection.InsertOne(ctx, *material)
+	cursor, err := r.materialCollection.Find(ctx, GetMaterialByDimensionId(ctx, *dimensionId))
+
+	var materials []MaterialDTO = []MaterialDTO{}
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return materials
 	}
 
-	id := insertResult.InsertedID.(primitive.ObjectID)
+	err = cursor.All(ctx, &materials)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
 
-	return &id
+	return materials
 }
 
-func (r *repository) UpdateMaterial(oid *primitive.ObjectID, dto *MaterialNameDTO) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) CreateMaterial(ctx context.Context, material *Material) (*primitive.ObjectID, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.CreateMaterial")
 	defer cancel()
 
-	_, err := r.materialCollection.UpdateOne(ctx, GetMaterialById(*oid), UpdateMaterialName(*dto))
+	material.OrganizationID = core.TenantFromContext(ctx)
+	material.Version = 1
+
+	insertResult, err := r.materialCollection.InsertOne(ctx, *material)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateMaterialName
+	}
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return nil, err
 	}
 
-	return err
+	id := insertResult.InsertedID.(primitive.ObjectID)
+
+	return &id, nil
 }
 
-func (r *repository) DeleteMaterial(oid *primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) UpdateMaterial(ctx context.Context, oid *primitive.ObjectID, version int64, dto *MaterialNameDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.UpdateMaterial")
 	defer cancel()
 
-	_, err := r.materialCollection.DeleteOne(ctx, GetMaterialById(*oid))
+	result, err := r.materialCollection.UpdateOne(ctx, GetMaterialByIdAndVersion(ctx, *oid, version), UpdateMaterialName(*dto))
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateMaterialName
+	}
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) AddDimensionToMaterial(MaterialOid *primitive.ObjectID, packageOid *primitive.ObjectID, dimension *MaterialDimension) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) DeleteMaterial(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.DeleteMaterial")
 	defer cancel()
 
-	_, err := r.materialCollection.UpdateOne(ctx, GetMaterialWithoutExistingDimension(*MaterialOid, *packageOid), PushDimensionIntoMaterial(*dimension))
+	result, err := r.materialCollection.DeleteOne(ctx, GetMaterialByIdAndVersion(ctx, *oid, version))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
+	if result.DeletedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
 }
 
-func (r *repository) RemoveDimensionFromMaterials(dto MaterialDimensionDTO) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) AddDimensionToMaterial(ctx context.Context, MaterialOid *primitive.ObjectID, version int64, packageOid *primitive.ObjectID, dimension *MaterialDimension) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.AddDimensionToMaterial")
 	defer cancel()
 
-	_, err := r.materialCollection.UpdateMany(ctx, GetMaterialByDimensionId(dto.DimensionOid), PullDimensionFromMaterials(dto))
+	result, err := r.materialCollection.UpdateOne(ctx, GetMaterialWithoutExistingDimension(ctx, *MaterialOid, version, *packageOid), PushDimensionIntoMaterial(*dimension))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
+		return err
 	}
 
-	return err
-}
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
 
-func (r *repository) ChangeMaterialPrice(dimensionId *primitive.ObjectID, priceDTO *MaterialDimensionPriceDTO) error {
+	return nil
+}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+func (r *repository) RemoveDimensionFromMaterials(ctx context.Context, dto MaterialDimensionDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.RemoveDimensionFromMaterials")
 	defer cancel()
 
-	_, err := r.materialCollection.UpdateOne(ctx, GetMaterialByDimensionId(*dimensionId), SetMaterialPrice(priceDTO.Price), GetArrayFilterForPackageId(*dimensionId))
+	_, err := r.materialCollection.UpdateMany(ctx, GetMaterialByDimensionId(ctx, dto.DimensionOid), PullDimensionFromMaterials(dto))
 
 	if err != nil {
-		log.Println(err.Error())
-		return err
+		core.LogError(ctx, err)
 	}
 
-	return nil
+	return err
 }
 
-func (r *repository) ValidateExistingMaterial(MaterialName *MaterialNameDTO) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (r *repository) ChangeMaterialPrice(ctx context.Context, dimensionId *primitive.ObjectID, version int64, priceDTO *MaterialDimensionPriceDTO) error {
+
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.ChangeMaterialPrice")
 	defer cancel()
 
-	cursor, err := r.materialCollection.Aggregate(ctx, GetAggregateCreateMaterials(MaterialName))
+	result, err := r.materialCollection.UpdateOne(ctx, GetMaterialByDimensionIdAndVersion(ctx, *dimensionId, version), SetMaterialPrice(priceDTO.Price), GetArrayFilterForPackageId(*dimensionId))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return err
 	}
 
-	var MaterialsDuplicated *[]MaterialDTO = &[]MaterialDTO{}
+	if result.MatchedCount == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
+}
 
-	err = cursor.All(ctx, MaterialsDuplicated)
+func (r *repository) ValidateExistingMaterial(ctx context.Context, materialName *MaterialNameDTO, excludedId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.ValidateExistingMaterial")
+	defer cancel()
+
+	duplicated, err := r.materialCollection.CountDocuments(ctx, GetMaterialByName(ctx, materialName.Name, excludedId),
+		options.Count().SetCollation(NameCollation()).SetLimit(1))
 
 	if err != nil {
-		log.Println(err.Error())
+		core.LogError(ctx, err)
 		return err
 	}
 
-	if len(*MaterialsDuplicated) > 0 {
-		return err
+	if duplicated > 0 {
+		return ErrDuplicateMaterialName
 	}
 
 	return nil
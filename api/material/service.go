@@ -1,12 +1,13 @@
 package material
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/api/audit"
 	"github.com/lucasbravi2019/arquitectura/api/budget"
 	"github.com/lucasbravi2019/arquitectura/core"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,32 +16,91 @@ import (
 type service struct {
 	materialRepository MaterialRepository
 	budgetRepository   budget.BudgetRepository
+	unitOfWork         core.UnitOfWork
+	audit              audit.Recorder
+	metrics            *core.Metrics
 }
 
 type MaterialService interface {
-	GetAllMaterials() (int, []MaterialDTO)
-	CreateMaterial(r *http.Request) (int, *MaterialDTO)
-	UpdateMaterial(r *http.Request) (int, *MaterialDTO)
-	DeleteMaterial(r *http.Request) (int, *primitive.ObjectID)
-	AddMaterialToBudget(r *http.Request) int
-	ChangeMaterialPrice(r *http.Request) (int, *MaterialDTO)
+	GetAllMaterials(ctx context.Context, r *http.Request) (*core.Page[MaterialDTO], *core.ApiError)
+	GetMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError)
+	CreateMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError)
+	UpdateMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError)
+	DeleteMaterial(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError)
+	AddMaterialToBudget(ctx context.Context, r *http.Request) *core.ApiError
+	ChangeMaterialPrice(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError)
+	MergeMaterials(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError)
 }
 
-var materialServiceInstance *service
+const (
+	ErrorCodeMetricMismatch  = "METRIC_MISMATCH"
+	ErrorCodeInvalidQuantity = "INVALID_QUANTITY"
+	ErrorCodeSelfMerge       = "SELF_MERGE"
+)
+
+// repriceAttempts bounds how many times ChangeMaterialPrice reads a budget
+// again when another write changes it between the read and the update.
+const repriceAttempts = 3
+
+func (s *service) GetAllMaterials(ctx context.Context, r *http.Request) (*core.Page[MaterialDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.GetAllMaterials")
+	defer span.End()
+
+	page, apiErr := core.ParsePageRequest(r, MaterialSortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
 
-func (s *service) GetAllMaterials() (int, []MaterialDTO) {
-	Materials := s.materialRepository.GetAllMaterials()
+	var filter *MaterialFilter = &MaterialFilter{
+		NamePrefix: r.URL.Query().Get("name"),
+		Metric:     r.URL.Query().Get("metric"),
+	}
+
+	materials, total, err := s.materialRepository.FindMaterials(ctx, *filter, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
 
-	return http.StatusOK, Materials
+	return core.NewPage(materials, total, *page), nil
 }
 
-func (s *service) CreateMaterial(r *http.Request) (int, *MaterialDTO) {
+func (s *service) GetMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.GetMaterial")
+	defer span.End()
+
+	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if oid == nil {
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	material := s.materialRepository.FindMaterialByOID(ctx, oid)
+
+	if material == nil {
+		return nil, core.NewNotFoundError("material")
+	}
+
+	return material, nil
+}
+
+func (s *service) CreateMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.CreateMaterial")
+	defer span.End()
+
 	var MaterialDto *MaterialNameDTO = &MaterialNameDTO{}
 
-	invalidBody := core.DecodeBody(r, MaterialDto)
+	apiErr := core.DecodeBody(r, MaterialDto)
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	apiErr = s.validateName(ctx, MaterialDto, nil)
+
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
 	var MaterialEntity *Material = &Material{
@@ -48,107 +108,176 @@ func (s *service) CreateMaterial(r *http.Request) (int, *MaterialDTO) {
 		Dimensions: []MaterialDimension{},
 	}
 
-	MaterialCreatedId := s.materialRepository.CreateMaterial(MaterialEntity)
+	MaterialCreatedId, err := s.materialRepository.CreateMaterial(ctx, MaterialEntity)
 
-	if MaterialCreatedId == nil {
-		return http.StatusInternalServerError, nil
+	if errors.Is(err, ErrDuplicateMaterialName) {
+		return nil, duplicateNameError(MaterialDto.Name)
+	}
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
 	}
 
-	MaterialCreated := s.materialRepository.FindMaterialByOID(MaterialCreatedId)
+	MaterialCreated := s.materialRepository.FindMaterialByOID(ctx, MaterialCreatedId)
 
 	if MaterialCreated == nil {
-		return http.StatusNotFound, nil
+		return nil, core.NewNotFoundError("material")
 	}
 
-	return http.StatusCreated, MaterialCreated
+	s.audit.Record(ctx, audit.Change(audit.EntityMaterial, *MaterialCreatedId, audit.OperationCreate, nil, audit.NewSnapshot(MaterialCreated)))
+
+	return MaterialCreated, nil
 }
 
-func (s *service) UpdateMaterial(r *http.Request) (int, *MaterialDTO) {
+func (s *service) UpdateMaterial(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.UpdateMaterial")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
 	var Material *MaterialNameDTO = &MaterialNameDTO{}
 
-	invalidBody := core.DecodeBody(r, Material)
+	apiErr := core.DecodeBody(r, Material)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	MaterialFound := s.materialRepository.FindMaterialByOID(ctx, oid)
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if MaterialFound == nil {
+		return nil, core.NewNotFoundError("material")
 	}
 
-	err := s.materialRepository.UpdateMaterial(oid, Material)
+	apiErr = core.CheckIfMatch(r, MaterialFound.Version, "material")
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	apiErr = s.validateName(ctx, Material, oid)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	err := s.materialRepository.UpdateMaterial(ctx, oid, MaterialFound.Version, Material)
+
+	if errors.Is(err, ErrDuplicateMaterialName) {
+		apiErr = duplicateNameError(Material.Name)
+		return nil, apiErr
+	}
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr = core.AsWriteError(err, "material")
+		return nil, apiErr
 	}
 
-	MaterialUpdated := s.materialRepository.FindMaterialByOID(oid)
+	MaterialUpdated := s.materialRepository.FindMaterialByOID(ctx, oid)
 
 	if MaterialUpdated == nil {
-		return http.StatusNotFound, nil
+		return nil, core.NewNotFoundError("material")
 	}
 
-	return http.StatusOK, MaterialUpdated
+	s.audit.Record(ctx, audit.Change(audit.EntityMaterial, *oid, audit.OperationUpdate, audit.NewSnapshot(MaterialFound), audit.NewSnapshot(MaterialUpdated)))
+
+	return MaterialUpdated, nil
 }
 
-func (s *service) DeleteMaterial(r *http.Request) (int, *primitive.ObjectID) {
+func (s *service) DeleteMaterial(ctx context.Context, r *http.Request) (*primitive.ObjectID, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.DeleteMaterial")
+	defer span.End()
+
 	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
 
 	if oid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	MaterialFound := s.materialRepository.FindMaterialByOID(ctx, oid)
+
+	// deleting a missing material succeeds, there is nothing to overwrite
+	if MaterialFound == nil {
+		return oid, nil
+	}
+
+	apiErr := core.CheckIfMatch(r, MaterialFound.Version, "material")
+
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	err := s.materialRepository.DeleteMaterial(oid)
+	err := s.materialRepository.DeleteMaterial(ctx, oid, MaterialFound.Version)
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr = core.AsWriteError(err, "material")
+		return nil, apiErr
 	}
 
-	return http.StatusOK, oid
+	s.audit.Record(ctx, audit.Change(audit.EntityMaterial, *oid, audit.OperationDelete, audit.NewSnapshot(MaterialFound), nil))
+
+	return oid, nil
 }
 
-func (s *service) AddMaterialToBudget(r *http.Request) int {
+func (s *service) AddMaterialToBudget(ctx context.Context, r *http.Request) *core.ApiError {
+	ctx, span := core.StartSpan(ctx, "MaterialService.AddMaterialToBudget")
+	defer span.End()
+
 	budgetId := core.ConvertHexToObjectId(mux.Vars(r)["budgetId"])
+
+	if budgetId == nil {
+		return core.NewInvalidIdError("budgetId")
+	}
+
 	materialId := core.ConvertHexToObjectId(mux.Vars(r)["materialId"])
 
-	if budgetId == nil || materialId == nil {
-		return http.StatusBadRequest
+	if materialId == nil {
+		return core.NewInvalidIdError("materialId")
 	}
 
-	budgetDTO := s.budgetRepository.FindBudgetByOID(budgetId)
+	budgetDTO := s.budgetRepository.FindBudgetByOID(ctx, budgetId)
 
 	if budgetDTO == nil {
-		return http.StatusNotFound
+		return core.NewNotFoundError("presupuesto")
 	}
 
-	materialDTO := s.materialRepository.FindMaterialByOID(materialId)
+	apiErr := core.CheckIfMatch(r, budgetDTO.Version, "presupuesto")
+
+	if apiErr != nil {
+		return apiErr
+	}
+
+	materialDTO := s.materialRepository.FindMaterialByOID(ctx, materialId)
 
 	if materialDTO == nil {
-		return http.StatusNotFound
+		return core.NewNotFoundError("material")
 	}
 
 	var materialDetails *MaterialDetailsDTO = &MaterialDetailsDTO{}
 
-	invalidBody := core.DecodeBody(r, materialDetails)
+	apiErr = core.DecodeBody(r, materialDetails)
 
-	if invalidBody {
-		return http.StatusBadRequest
+	if apiErr != nil {
+		return apiErr
 	}
 
-	err := validate(materialDTO, materialDetails)
+	apiErr = validate(ctx, materialDTO, materialDetails)
 
-	if err != nil {
-		return http.StatusBadRequest
+	if apiErr != nil {
+		return apiErr
 	}
 
 	dimension := getMaterialDimension(materialDetails.Metric, materialDTO.Dimensions)
 
 	var budgetMaterial *budget.BudgetMaterial = &budget.BudgetMaterial{
-		ID:       primitive.NewObjectID(),
-		Quantity: materialDetails.Quantity,
-		Name:     materialDTO.Name,
+		ID:         primitive.NewObjectID(),
+		MaterialID: materialDTO.ID,
+		Quantity:   materialDetails.Quantity,
+		Name:       materialDTO.Name,
 		Dimension: budget.BudgetMaterialDimension{
 			ID:       dimension.ID,
 			Metric:   dimension.Metric,
@@ -158,89 +287,357 @@ func (s *service) AddMaterialToBudget(r *http.Request) int {
 		Price: float64(materialDetails.Quantity) / dimension.Quantity * dimension.Price,
 	}
 
-	err = s.budgetRepository.AddMaterialToBudget(budgetId, budgetMaterial)
+	err := s.budgetRepository.AddMaterialToBudget(ctx, budgetId, budgetDTO.Version, budgetMaterial)
 
 	if err != nil {
-		return http.StatusInternalServerError
+		apiErr = core.AsWriteError(err, "presupuesto")
+		return apiErr
 	}
 
-	err = s.budgetRepository.UpdateBudgetByIdPrice(budgetId)
+	err = s.budgetRepository.UpdateBudgetByIdPrice(ctx, budgetId)
 
 	if err != nil {
-		log.Println(err.Error())
-		return http.StatusInternalServerError
+		core.LogError(ctx, err)
+		return core.NewInternalError(err)
 	}
 
-	return http.StatusOK
+	s.audit.Record(ctx, audit.Change(audit.EntityBudget, *budgetId, audit.OperationAddMaterial,
+		audit.NewSnapshot(budgetDTO), audit.NewSnapshot(s.budgetRepository.FindBudgetByOID(ctx, budgetId))))
+
+	return nil
 }
 
-func (s *service) ChangeMaterialPrice(r *http.Request) (int, *MaterialDTO) {
+func (s *service) ChangeMaterialPrice(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.ChangeMaterialPrice")
+	defer span.End()
+
 	materialDimensionId := mux.Vars(r)["id"]
 	materialDimensionOid := core.ConvertHexToObjectId(materialDimensionId)
 
 	if materialDimensionOid == nil {
-		return http.StatusBadRequest, nil
+		return nil, core.NewInvalidIdError("id")
 	}
 
 	var materialDimensionPrice *MaterialDimensionPriceDTO = &MaterialDimensionPriceDTO{}
 
-	invalidBody := core.DecodeBody(r, materialDimensionPrice)
+	apiErr := core.DecodeBody(r, materialDimensionPrice)
 
-	if invalidBody {
-		return http.StatusBadRequest, nil
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	err := s.materialRepository.ChangeMaterialPrice(materialDimensionOid, materialDimensionPrice)
+	var materialUpdated *MaterialDTO
+	var propagated int
+	var changes []audit.Entry
+
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		materialFound := s.materialRepository.FindMaterialByPackageId(ctx, materialDimensionOid)
+
+		if materialFound == nil {
+			return core.NewNotFoundError("material")
+		}
+
+		apiErr := core.CheckIfMatch(r, materialFound.Version, "material")
+
+		if apiErr != nil {
+			return apiErr
+		}
+
+		budgetsFound := map[primitive.ObjectID]audit.Snapshot{}
+
+		for _, found := range s.budgetRepository.FindBudgetsByDimensionId(ctx, materialDimensionOid) {
+			budgetsFound[found.ID] = audit.NewSnapshot(found)
+		}
+
+		err := s.materialRepository.ChangeMaterialPrice(ctx, materialDimensionOid, materialFound.Version, materialDimensionPrice)
+
+		if err != nil {
+			return err
+		}
+
+		materialUpdated = s.materialRepository.FindMaterialByPackageId(ctx, materialDimensionOid)
+
+		if materialUpdated == nil {
+			return core.NewNotFoundError("material")
+		}
+
+		changes = []audit.Entry{
+			audit.Change(audit.EntityMaterial, materialUpdated.ID, audit.OperationChangePrice, audit.NewSnapshot(materialFound), audit.NewSnapshot(materialUpdated)),
+		}
+
+		err = s.budgetRepository.UpdateMaterialDimensionPrice(ctx, materialDimensionOid, materialDimensionPrice.Price)
+
+		if err != nil {
+			return err
+		}
+
+		budgets := s.budgetRepository.FindBudgetsByDimensionId(ctx, materialDimensionOid)
+
+		propagated = 0
+
+		for i := range budgets {
+			repriced, err := s.repriceBudget(ctx, materialDimensionOid, budgets[i], nil)
+
+			if err != nil {
+				return err
+			}
+
+			// deleted since it was read
+			if repriced == nil {
+				continue
+			}
+
+			propagated++
+
+			changes = append(changes, audit.Change(audit.EntityBudget, repriced.ID, audit.OperationReprice,
+				budgetsFound[repriced.ID], audit.NewSnapshot(repriced)).CausedBy(audit.EntityMaterial, audit.OperationChangePrice))
+		}
+
+		return nil
+	})
 
 	if err != nil {
-		return http.StatusInternalServerError, nil
+		apiErr := core.AsWriteError(err, "material")
+		return nil, apiErr
 	}
 
-	materialUpdated := s.materialRepository.FindMaterialByPackageId(materialDimensionOid)
+	s.audit.Record(ctx, changes...)
+
+	s.metrics.MaterialPriceChanged()
+	s.metrics.BudgetPricesPropagated(propagated)
+
+	return materialUpdated, nil
+}
+
+// repriceBudget recalculates the lines of the budget and writes them while it
+// has the version read, reading it again when another write got in between.
+// Lines of the survivor, if any, first take the price of their dimension in
+// it. It returns nil when the budget was deleted meanwhile.
+func (s *service) repriceBudget(ctx context.Context, dimensionId *primitive.ObjectID, found budget.BudgetDTO, survivor *MaterialDTO) (*budget.BudgetDTO, error) {
+	for attempt := 1; ; attempt++ {
+		if survivor != nil {
+			for j := range found.Materials {
+				if found.Materials[j].MaterialID != survivor.ID {
+					continue
+				}
+
+				for _, dimension := range survivor.Dimensions {
+					if dimension.ID == found.Materials[j].Dimension.ID {
+						found.Materials[j].Dimension.Price = dimension.Price
+					}
+				}
+			}
+		}
+
+		var recipePrice float64 = 0
+		for j := 0; j < len(found.Materials); j++ {
+			found.Materials[j].Price = found.Materials[j].Quantity / found.Materials[j].Dimension.Quantity * found.Materials[j].Dimension.Price
+			recipePrice += found.Materials[j].Price
+		}
+		found.Price = recipePrice * 3
+
+		err := s.budgetRepository.UpdateMaterialsPrice(ctx, dimensionId, found)
 
-	if materialUpdated == nil {
-		return http.StatusInternalServerError, nil
+		if !errors.Is(err, core.ErrVersionConflict) {
+			return &found, err
+		}
+
+		if attempt == repriceAttempts {
+			return nil, core.NewPreconditionFailedError("presupuesto")
+		}
+
+		reread := s.budgetRepository.FindBudgetByOID(ctx, &found.ID)
+
+		if reread == nil {
+			return nil, nil
+		}
+
+		found = *reread
 	}
+}
 
-	budget := s.budgetRepository.FindBudgetsByDimensionId(materialDimensionOid)
+// MergeMaterials moves the dimensions and budget lines of the material in the
+// body to the material of the path and deletes it. Dimensions both materials
+// have keep the price of the material of the path, and the budgets with moved
+// lines are repriced with it.
+func (s *service) MergeMaterials(ctx context.Context, r *http.Request) (*MaterialDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "MaterialService.MergeMaterials")
+	defer span.End()
 
-	if len(budget) == 0 {
-		return http.StatusOK, materialUpdated
+	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if oid == nil {
+		return nil, core.NewInvalidIdError("id")
 	}
 
-	err = s.budgetRepository.UpdateMaterialDimensionPrice(materialDimensionOid, materialDimensionPrice.Price)
+	var merge *MergeMaterialDTO = &MergeMaterialDTO{}
 
-	if err != nil {
-		return http.StatusInternalServerError, nil
+	apiErr := core.DecodeBody(r, merge)
+
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	for i := 0; i < len(budget); i++ {
-		var recipePrice float64 = 0
-		for j := 0; j < len(budget[i].Materials); j++ {
-			budget[i].Materials[j].Price = budget[i].Materials[j].Quantity / budget[i].Materials[j].Dimension.Quantity * budget[i].Materials[j].Dimension.Price
-			recipePrice += budget[i].Materials[j].Price
+	if merge.MaterialID == *oid {
+		apiErr = core.NewApiError(http.StatusBadRequest, ErrorCodeSelfMerge, "Un material no puede combinarse consigo mismo")
+		return nil, apiErr
+	}
+
+	var materialMerged *MaterialDTO
+	var changes []audit.Entry
+
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		materialFound := s.materialRepository.FindMaterialByOID(ctx, oid)
+
+		if materialFound == nil {
+			return core.NewNotFoundError("material")
+		}
+
+		apiErr := core.CheckIfMatch(r, materialFound.Version, "material")
+
+		if apiErr != nil {
+			return apiErr
+		}
+
+		mergedFound := s.materialRepository.FindMaterialByOID(ctx, &merge.MaterialID)
+
+		if mergedFound == nil {
+			return core.NewNotFoundError("material")
+		}
+
+		budgetsFound := s.budgetRepository.FindBudgetsByMaterialId(ctx, &mergedFound.ID)
+
+		version := materialFound.Version
+
+		for _, dimension := range mergedFound.Dimensions {
+			if HasDimension(materialFound.Dimensions, dimension.ID) {
+				continue
+			}
+
+			err := s.materialRepository.AddDimensionToMaterial(ctx, oid, version, &dimension.ID, &MaterialDimension{
+				ID:       dimension.ID,
+				Metric:   dimension.Metric,
+				Quantity: dimension.Quantity,
+				Price:    dimension.Price,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			version++
+		}
+
+		// without new dimensions, a write of the same name still fails when
+		// the material changed since it was read
+		if version == materialFound.Version {
+			err := s.materialRepository.UpdateMaterial(ctx, oid, version, &MaterialNameDTO{Name: materialFound.Name})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err := s.budgetRepository.ReassignMaterial(ctx, &mergedFound.ID, oid, materialFound.Name)
+
+		if err != nil {
+			return err
 		}
-		budget[i].Price = recipePrice * 3
 
-		err := s.budgetRepository.UpdateMaterialsPrice(materialDimensionOid, budget[i])
+		err = s.materialRepository.DeleteMaterial(ctx, &mergedFound.ID, mergedFound.Version)
 
 		if err != nil {
-			log.Println(err.Error())
+			return err
+		}
+
+		materialMerged = s.materialRepository.FindMaterialByOID(ctx, oid)
+
+		if materialMerged == nil {
+			return core.NewNotFoundError("material")
+		}
+
+		changes = []audit.Entry{
+			audit.Change(audit.EntityMaterial, *oid, audit.OperationMerge, audit.NewSnapshot(materialFound), audit.NewSnapshot(materialMerged)),
+			audit.Change(audit.EntityMaterial, mergedFound.ID, audit.OperationDelete, audit.NewSnapshot(mergedFound), nil).
+				CausedBy(audit.EntityMaterial, audit.OperationMerge),
 		}
+
+		for _, found := range budgetsFound {
+			reassigned := s.budgetRepository.FindBudgetByOID(ctx, &found.ID)
+
+			if reassigned == nil {
+				continue
+			}
+
+			repriced, err := s.repriceBudget(ctx, lineDimension(found, mergedFound.ID), *reassigned, materialMerged)
+
+			if err != nil {
+				return err
+			}
+
+			// deleted since it was read
+			if repriced == nil {
+				continue
+			}
+
+			changes = append(changes, audit.Change(audit.EntityBudget, found.ID, audit.OperationReassign,
+				audit.NewSnapshot(found), audit.NewSnapshot(repriced)).
+				CausedBy(audit.EntityMaterial, audit.OperationMerge))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		apiErr := core.AsWriteError(err, "material")
+		return nil, apiErr
 	}
 
-	return http.StatusOK, materialUpdated
+	s.audit.Record(ctx, changes...)
+
+	return materialMerged, nil
 }
 
-func validate(Material *MaterialDTO, MaterialDetails *MaterialDetailsDTO) error {
+// lineDimension returns the dimension of the first line of the material in
+// the budget.
+func lineDimension(found budget.BudgetDTO, materialId primitive.ObjectID) *primitive.ObjectID {
+	for _, line := range found.Materials {
+		if line.MaterialID == materialId {
+			return &line.Dimension.ID
+		}
+	}
+
+	return nil
+}
+
+// validateName rejects the name when a material other than excludedId has it.
+func (s *service) validateName(ctx context.Context, dto *MaterialNameDTO, excludedId *primitive.ObjectID) *core.ApiError {
+	err := s.materialRepository.ValidateExistingMaterial(ctx, dto, excludedId)
+
+	if errors.Is(err, ErrDuplicateMaterialName) {
+		return duplicateNameError(dto.Name)
+	}
+
+	if err != nil {
+		return core.NewInternalError(err)
+	}
+
+	return nil
+}
+
+func duplicateNameError(name string) *core.ApiError {
+	return core.NewConflictError("Ya existe un material con el nombre " + name)
+}
+
+func validate(ctx context.Context, Material *MaterialDTO, MaterialDetails *MaterialDetailsDTO) *core.ApiError {
 	if !MaterialMetricMatches(MaterialDetails.Metric, Material.Dimensions) {
-		log.Println("La unidad de medida no coincide")
-		return errors.New("la unidad de medida no coincide")
+		core.Log(ctx, core.LogLevelWarn, "La unidad de medida no coincide", nil)
+		return core.NewApiError(http.StatusBadRequest, ErrorCodeMetricMismatch, "La unidad de medida no coincide")
 	}
 
 	if MaterialDetails.Quantity == 0 {
-		log.Println("La cantidad del Materiale no puede ser 0")
-		return errors.New("la cantidad del Materiale no puede ser 0")
+		core.Log(ctx, core.LogLevelWarn, "La cantidad del material no puede ser 0", nil)
+		return core.NewApiError(http.StatusBadRequest, ErrorCodeInvalidQuantity, "La cantidad del material no puede ser 0")
 	}
 	return nil
 }
@@ -254,6 +651,16 @@ func MaterialMetricMatches(metric string, dimensions []DimensionDTO) bool {
 	return false
 }
 
+// HasDimension reports whether the dimensions include the one with the id.
+func HasDimension(dimensions []DimensionDTO, dimensionId primitive.ObjectID) bool {
+	for _, dimension := range dimensions {
+		if dimension.ID == dimensionId {
+			return true
+		}
+	}
+	return false
+}
+
 func getMaterialDimension(metric string, dimensions []DimensionDTO) *DimensionDTO {
 	for _, dimension := range dimensions {
 		if fmt.Sprintf("%g %s", dimension.Quantity, dimension.Metric) == metric {
@@ -0,0 +1,51 @@
+package organization
+
+import (
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrganizationDTO struct {
+	ID        primitive.ObjectID `json:"id"`
+	Name      string             `json:"name"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
+// CreateOrganizationDTO creates the organization with its first admin, who
+// then creates the rest of its users.
+type CreateOrganizationDTO struct {
+	Name  string               `json:"name" validate:"required,max=100"`
+	Admin OrganizationAdminDTO `json:"admin" validate:"required"`
+}
+
+type OrganizationAdminDTO struct {
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type CreatedOrganizationDTO struct {
+	Organization OrganizationDTO `json:"organization"`
+	Admin        user.UserDTO    `json:"admin"`
+}
+
+var OrganizationSortFields = []string{"name"}
+
+func NewOrganizationDTO(organization *Organization) *OrganizationDTO {
+	return &OrganizationDTO{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		CreatedAt: organization.CreatedAt,
+	}
+}
+
+func NewOrganizationDTOs(organizations []Organization) []OrganizationDTO {
+	dtos := make([]OrganizationDTO, len(organizations))
+
+	for i := range organizations {
+		dtos[i] = *NewOrganizationDTO(&organizations[i])
+	}
+
+	return dtos
+}
@@ -0,0 +1,28 @@
+package organization
+
+import (
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewOrganizationHandler(service OrganizationService) OrganizationHandler {
+	return &handler{
+		service: service,
+	}
+}
+
+func NewOrganizationService(organizationRepository OrganizationRepository, userService user.UserService, unitOfWork core.UnitOfWork) OrganizationService {
+	return &service{
+		organizationRepository: organizationRepository,
+		userService:            userService,
+		unitOfWork:             unitOfWork,
+	}
+}
+
+func NewOrganizationRepository(db *mongo.Database, timeouts core.QueryTimeouts) OrganizationRepository {
+	return &repository{
+		db:       db.Collection("organizations"),
+		timeouts: timeouts,
+	}
+}
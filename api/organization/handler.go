@@ -0,0 +1,68 @@
+package organization
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	service OrganizationService
+}
+
+type OrganizationHandler interface {
+	GetAllOrganizations(w http.ResponseWriter, r *http.Request)
+	CreateOrganization(w http.ResponseWriter, r *http.Request)
+	GetCurrentOrganization(w http.ResponseWriter, r *http.Request)
+	GetOrganizationRoutes() core.Routes
+}
+
+func (h *handler) GetAllOrganizations(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetAllOrganizations(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.CreateOrganization(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
+}
+
+func (h *handler) GetCurrentOrganization(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetCurrentOrganization(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetOrganizationRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/organizations",
+			HandlerFunc: h.GetAllOrganizations,
+			Method:      "GET",
+			Permission:  core.PermissionManageOrganizations,
+			Summary:     "Lista las organizaciones de la instancia",
+			Response:    core.Page[OrganizationDTO]{},
+			QueryParams: map[string]string{
+				"limit":     "Cantidad de organizaciones por pagina, hasta 100",
+				"pageToken": "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":      "Orden: name o createdAt, con el prefijo - para descendente",
+			},
+		},
+		core.Route{
+			Path:        "/organizations",
+			HandlerFunc: h.CreateOrganization,
+			Method:      "POST",
+			Permission:  core.PermissionManageOrganizations,
+			Summary:     "Crea una organizacion con su primer usuario administrador",
+			Request:     CreateOrganizationDTO{},
+			Response:    CreatedOrganizationDTO{},
+			Status:      http.StatusCreated,
+		},
+		core.Route{
+			Path:        "/organizations/current",
+			HandlerFunc: h.GetCurrentOrganization,
+			Method:      "GET",
+			Summary:     "Devuelve la organizacion del usuario autenticado",
+			Response:    OrganizationDTO{},
+		},
+	}
+}
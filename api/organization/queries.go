@@ -0,0 +1,31 @@
+package organization
+
+import (
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var organizationSortFields = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "_id",
+}
+
+// tenantCollections hold documents scoped by organization.
+var tenantCollections = []string{"budgets", "materials", "dimensions", "users"}
+
+func All() bson.M {
+	return bson.M{}
+}
+
+func GetOrganizationById(oid primitive.ObjectID) bson.M {
+	return bson.M{"_id": oid}
+}
+
+func WithoutTenant() bson.M {
+	return bson.M{core.TenantField: bson.M{"$exists": false}}
+}
+
+func SetDefaultTenant() bson.M {
+	return bson.M{"$set": bson.M{core.TenantField: core.DefaultOrganizationID}}
+}
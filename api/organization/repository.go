@@ -0,0 +1,100 @@
+package organization
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type repository struct {
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
+}
+
+type OrganizationRepository interface {
+	FindOrganizations(ctx context.Context, page core.PageRequest) ([]Organization, int64, error)
+	FindOrganizationByOID(ctx context.Context, oid *primitive.ObjectID) *Organization
+	CreateOrganization(ctx context.Context, organization *Organization) error
+}
+
+// AssignLegacyDocuments moves the documents written before organizations
+// existed into the default organization.
+func AssignLegacyDocuments(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range tenantCollections {
+		_, err := db.Collection(collection).UpdateMany(ctx, WithoutTenant(), SetDefaultTenant())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) FindOrganizations(ctx context.Context, page core.PageRequest) ([]Organization, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.FindOrganizations")
+	defer cancel()
+
+	total, err := r.db.CountDocuments(ctx, All())
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	cursor, err := r.db.Find(ctx, All(), page.FindOptions(organizationSortFields))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	var organizations []Organization = []Organization{}
+
+	err = cursor.All(ctx, &organizations)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return organizations, total, nil
+}
+
+func (r *repository) FindOrganizationByOID(ctx context.Context, oid *primitive.ObjectID) *Organization {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.FindOrganizationByOID")
+	defer cancel()
+
+	var organization *Organization = &Organization{}
+
+	err := r.db.FindOne(ctx, GetOrganizationById(*oid)).Decode(organization)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			core.LogError(ctx, err)
+		}
+
+		return nil
+	}
+
+	return organization
+}
+
+func (r *repository) CreateOrganization(ctx context.Context, organization *Organization) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.CreateOrganization")
+	defer cancel()
+
+	if organization.ID.IsZero() {
+		organization.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.InsertOne(ctx, organization)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
@@ -0,0 +1,125 @@
+package organization
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type service struct {
+	organizationRepository OrganizationRepository
+	userService            user.UserService
+	unitOfWork             core.UnitOfWork
+}
+
+type OrganizationService interface {
+	GetAllOrganizations(ctx context.Context, r *http.Request) (*core.Page[OrganizationDTO], *core.ApiError)
+	CreateOrganization(ctx context.Context, r *http.Request) (*CreatedOrganizationDTO, *core.ApiError)
+	GetCurrentOrganization(ctx context.Context, r *http.Request) (*OrganizationDTO, *core.ApiError)
+	// EnsureDefaultOrganization creates the organization that owns the data
+	// written before organizations existed.
+	EnsureDefaultOrganization(ctx context.Context) error
+}
+
+func (s *service) GetAllOrganizations(ctx context.Context, r *http.Request) (*core.Page[OrganizationDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "OrganizationService.GetAllOrganizations")
+	defer span.End()
+
+	page, apiErr := core.ParsePageRequest(r, OrganizationSortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	organizations, total, err := s.organizationRepository.FindOrganizations(ctx, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(NewOrganizationDTOs(organizations), total, *page), nil
+}
+
+func (s *service) CreateOrganization(ctx context.Context, r *http.Request) (*CreatedOrganizationDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "OrganizationService.CreateOrganization")
+	defer span.End()
+
+	var created *CreateOrganizationDTO = &CreateOrganizationDTO{}
+
+	apiErr := core.DecodeBody(r, created)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	organization := &Organization{
+		ID:        primitive.NewObjectID(),
+		Name:      created.Name,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var admin *user.UserDTO
+
+	// a taken username, the likely failure, leaves no organization behind
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		err := s.organizationRepository.CreateOrganization(ctx, organization)
+
+		if err != nil {
+			return err
+		}
+
+		var apiErr *core.ApiError
+
+		admin, apiErr = s.userService.CreateOrganizationUser(ctx, organization.ID, user.CreateUserDTO{
+			Username: created.Admin.Username,
+			Password: created.Admin.Password,
+			Roles:    []string{core.RoleAdmin},
+		})
+
+		if apiErr != nil {
+			return apiErr
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, core.AsApiError(err)
+	}
+
+	return &CreatedOrganizationDTO{
+		Organization: *NewOrganizationDTO(organization),
+		Admin:        *admin,
+	}, nil
+}
+
+func (s *service) GetCurrentOrganization(ctx context.Context, r *http.Request) (*OrganizationDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "OrganizationService.GetCurrentOrganization")
+	defer span.End()
+
+	oid := core.TenantFromContext(ctx)
+
+	organization := s.organizationRepository.FindOrganizationByOID(ctx, &oid)
+
+	if organization == nil {
+		return nil, core.NewNotFoundError("organizacion")
+	}
+
+	return NewOrganizationDTO(organization), nil
+}
+
+func (s *service) EnsureDefaultOrganization(ctx context.Context) error {
+	if s.organizationRepository.FindOrganizationByOID(ctx, &core.DefaultOrganizationID) != nil {
+		return nil
+	}
+
+	return s.organizationRepository.CreateOrganization(ctx, &Organization{
+		ID:        core.DefaultOrganizationID,
+		Name:      core.DefaultOrganizationName,
+		CreatedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,28 @@
+package search
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	HitTypeMaterial   = "material"
+	HitTypeBudget     = "budget"
+	HitTypeBudgetLine = "budgetLine"
+)
+
+var HitTypes = []string{HitTypeMaterial, HitTypeBudget, HitTypeBudgetLine}
+
+// SearchHitDTO is one search result. Budget line hits also carry the budget
+// that contains them and the material they were created from.
+type SearchHitDTO struct {
+	Type       string              `json:"type"`
+	ID         primitive.ObjectID  `json:"id"`
+	Name       string              `json:"name"`
+	Score      float64             `json:"score"`
+	BudgetID   *primitive.ObjectID `json:"budgetId,omitempty"`
+	BudgetName string              `json:"budgetName,omitempty"`
+	MaterialID *primitive.ObjectID `json:"materialId,omitempty"`
+}
+
+type SearchResultDTO struct {
+	Query string         `json:"query"`
+	Hits  []SearchHitDTO `json:"hits"`
+}
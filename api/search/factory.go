@@ -0,0 +1,26 @@
+package search
+
+import (
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewSearchHandler(service SearchService) SearchHandler {
+	return &handler{
+		service: service,
+	}
+}
+
+func NewSearchService(searchRepository SearchRepository) SearchService {
+	return &service{
+		searchRepository: searchRepository,
+	}
+}
+
+func NewSearchRepository(db *mongo.Database, timeouts core.QueryTimeouts) SearchRepository {
+	return &repository{
+		materials: db.Collection("materials"),
+		budgets:   db.Collection("budgets"),
+		timeouts:  timeouts,
+	}
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	service SearchService
+}
+
+type SearchHandler interface {
+	Search(w http.ResponseWriter, r *http.Request)
+	GetSearchRoutes() core.Routes
+}
+
+func (h *handler) Search(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.Search(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetSearchRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/search",
+			HandlerFunc: h.Search,
+			Method:      "GET",
+			Permission:  core.PermissionReadMaterials,
+			Summary:     "Busca materiales, presupuestos y lineas de presupuesto por nombre, sin distinguir mayusculas ni acentos",
+			Response:    SearchResultDTO{},
+			QueryParams: map[string]string{
+				"q":     "Texto a buscar, de al menos 2 caracteres",
+				"types": "Tipos de resultado separados por coma: material, budget, budgetLine",
+				"limit": "Cantidad maxima de resultados, hasta 100",
+			},
+		},
+	}
+}
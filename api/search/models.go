@@ -0,0 +1,50 @@
+package search
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type MaterialDocument struct {
+	ID   primitive.ObjectID `bson:"_id"`
+	Name string             `bson:"name"`
+}
+
+type BudgetDocument struct {
+	ID        primitive.ObjectID   `bson:"_id"`
+	Name      string               `bson:"name"`
+	Materials []BudgetLineDocument `bson:"materials"`
+}
+
+type BudgetLineDocument struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	MaterialID primitive.ObjectID `bson:"materialId,omitempty"`
+	Name       string             `bson:"name"`
+}
+
+// Hits splits a budget into the hit of the budget itself and one hit per line,
+// so each can be ranked on its own name.
+func (b BudgetDocument) Hits() []SearchHitDTO {
+	hits := []SearchHitDTO{{
+		Type: HitTypeBudget,
+		ID:   b.ID,
+		Name: b.Name,
+	}}
+
+	for _, line := range b.Materials {
+		budgetId := b.ID
+		hit := SearchHitDTO{
+			Type:       HitTypeBudgetLine,
+			ID:         line.ID,
+			Name:       line.Name,
+			BudgetID:   &budgetId,
+			BudgetName: b.Name,
+		}
+
+		if !line.MaterialID.IsZero() {
+			materialId := line.MaterialID
+			hit.MaterialID = &materialId
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits
+}
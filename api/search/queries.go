@@ -0,0 +1,96 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Spanish stemming lets "ladrillos" find "ladrillo"; text indexes are already
+// case and diacritic insensitive.
+const textIndexLanguage = "spanish"
+
+func MaterialTextIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "name", Value: "text"}},
+		Options: options.Index().
+			SetName("materials_text").
+			SetDefaultLanguage(textIndexLanguage),
+	}
+}
+
+func BudgetTextIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "name", Value: "text"}, {Key: "materials.name", Value: "text"}},
+		Options: options.Index().
+			SetName("budgets_text").
+			SetDefaultLanguage(textIndexLanguage).
+			SetWeights(bson.D{{Key: "name", Value: 2}, {Key: "materials.name", Value: 1}}),
+	}
+}
+
+func TextSearch(ctx context.Context, terms []string) bson.M {
+	return core.TenantFilter(ctx, bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}})
+}
+
+func TextSearchOptions(limit int) *options.FindOptions {
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+
+	return options.Find().SetProjection(score).SetSort(score).SetLimit(int64(limit))
+}
+
+// PrefixSearch finds the documents with a word in any of the fields starting
+// with the stem of a term, like the other storages do; the text index only
+// finds whole words. The regular expressions cannot use an index, so it only
+// runs for the candidates TextSearch did not return.
+func PrefixSearch(ctx context.Context, terms []string, excluded []primitive.ObjectID, fields ...string) bson.M {
+	prefixes := []bson.M{}
+
+	for _, field := range fields {
+		for _, term := range terms {
+			prefixes = append(prefixes, bson.M{field: primitive.Regex{Pattern: wordPrefixPattern(core.SearchStem(term)), Options: "i"}})
+		}
+	}
+
+	return core.TenantFilter(ctx, bson.M{"$or": prefixes, "_id": bson.M{"$nin": excluded}})
+}
+
+func PrefixSearchOptions(limit int) *options.FindOptions {
+	return options.Find().SetLimit(int64(limit))
+}
+
+// accentedLetters are the letters the text indexes and core.NormalizeText
+// fold into the plain ones.
+var accentedLetters = map[rune]string{
+	'a': "aáàâäAÁÀÂÄ",
+	'e': "eéèêëEÉÈÊË",
+	'i': "iíìîïIÍÌÎÏ",
+	'o': "oóòôöOÓÒÔÖ",
+	'u': "uúùûüUÚÙÛÜ",
+	'n': "nñNÑ",
+	'c': "cçCÇ",
+}
+
+// wordPrefixPattern matches a word starting with the normalized prefix,
+// whatever its case and accents.
+func wordPrefixPattern(prefix string) string {
+	var pattern strings.Builder
+
+	pattern.WriteString(`(^|[^\p{L}\p{N}])`)
+
+	for _, r := range prefix {
+		if letters, ok := accentedLetters[r]; ok {
+			pattern.WriteString("[" + letters + "]")
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return pattern.String()
+}
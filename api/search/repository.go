@@ -0,0 +1,127 @@
+package search
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type repository struct {
+	materials *mongo.Collection
+	budgets   *mongo.Collection
+	timeouts  core.QueryTimeouts
+}
+
+// SearchRepository finds the candidates of a search: the documents with a word
+// starting with the stem of a term, see core.SearchStem. Candidates are ranked
+// by the service, so every storage returns the same hits in the same order.
+type SearchRepository interface {
+	SearchMaterials(ctx context.Context, terms []string, limit int) ([]SearchHitDTO, error)
+	SearchBudgets(ctx context.Context, terms []string, limit int) ([]SearchHitDTO, error)
+}
+
+// EnsureSearchIndexes creates the text indexes used by the search, if missing.
+func EnsureSearchIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("materials").Indexes().CreateOne(ctx, MaterialTextIndex())
+
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("budgets").Indexes().CreateOne(ctx, BudgetTextIndex())
+
+	return err
+}
+
+func (r *repository) SearchMaterials(ctx context.Context, terms []string, limit int) ([]SearchHitDTO, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.SearchMaterials")
+	defer cancel()
+
+	materials, err := findCandidates(ctx, r.materials, terms, limit, func(material MaterialDocument) primitive.ObjectID {
+		return material.ID
+	}, "name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []SearchHitDTO{}
+
+	for _, material := range materials {
+		hits = append(hits, SearchHitDTO{Type: HitTypeMaterial, ID: material.ID, Name: material.Name})
+	}
+
+	return hits, nil
+}
+
+func (r *repository) SearchBudgets(ctx context.Context, terms []string, limit int) ([]SearchHitDTO, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.SearchBudgets")
+	defer cancel()
+
+	budgets, err := findCandidates(ctx, r.budgets, terms, limit, func(budget BudgetDocument) primitive.ObjectID {
+		return budget.ID
+	}, "name", "materials.name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []SearchHitDTO{}
+
+	for _, budget := range budgets {
+		hits = append(hits, budget.Hits()...)
+	}
+
+	return hits, nil
+}
+
+// findCandidates returns the documents found through the text index and then,
+// while under the limit, the ones with a word in the fields starting with the
+// stem of a term, which the index misses.
+func findCandidates[T any](ctx context.Context, collection *mongo.Collection, terms []string, limit int, id func(T) primitive.ObjectID, fields ...string) ([]T, error) {
+	var documents []T = []T{}
+
+	cursor, err := collection.Find(ctx, TextSearch(ctx, terms), TextSearchOptions(limit))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &documents)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	if len(documents) >= limit {
+		return documents, nil
+	}
+
+	found := []primitive.ObjectID{}
+
+	for _, document := range documents {
+		found = append(found, id(document))
+	}
+
+	cursor, err = collection.Find(ctx, PrefixSearch(ctx, terms, found, fields...), PrefixSearchOptions(limit-len(documents)))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	var prefixed []T = []T{}
+
+	err = cursor.All(ctx, &prefixed)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	return append(documents, prefixed...), nil
+}
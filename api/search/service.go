@@ -0,0 +1,174 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+const (
+	defaultSearchLimit = 20
+	minQueryLength     = 2
+	// each collection returns more candidates than hits requested, since the
+	// storage order can differ from the final ranking
+	candidatesPerHit = 5
+)
+
+type service struct {
+	searchRepository SearchRepository
+}
+
+type SearchService interface {
+	Search(ctx context.Context, r *http.Request) (*SearchResultDTO, *core.ApiError)
+}
+
+func (s *service) Search(ctx context.Context, r *http.Request) (*SearchResultDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "SearchService.Search")
+	defer span.End()
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	terms := core.SearchTerms(query)
+
+	if len([]rune(query)) < minQueryLength || len(terms) == 0 {
+		return nil, core.NewInvalidQueryError("q", "La busqueda debe tener al menos "+strconv.Itoa(minQueryLength)+" caracteres")
+	}
+
+	limit, apiErr := parseLimit(r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	types, apiErr := parseTypes(r)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	types = allowedTypes(ctx, types)
+
+	candidates := []SearchHitDTO{}
+
+	if containsType(types, HitTypeMaterial) {
+		materials, err := s.searchRepository.SearchMaterials(ctx, terms, limit*candidatesPerHit)
+
+		if err != nil {
+			return nil, core.NewInternalError(err)
+		}
+
+		candidates = append(candidates, materials...)
+	}
+
+	if containsType(types, HitTypeBudget) || containsType(types, HitTypeBudgetLine) {
+		budgets, err := s.searchRepository.SearchBudgets(ctx, terms, limit*candidatesPerHit)
+
+		if err != nil {
+			return nil, core.NewInternalError(err)
+		}
+
+		candidates = append(candidates, budgets...)
+	}
+
+	return &SearchResultDTO{Query: query, Hits: rank(candidates, terms, types, limit)}, nil
+}
+
+// rank scores every candidate on its own name, drops the ones that do not
+// match, like the budget hit of a budget found by one of its lines, and keeps
+// the best ones.
+func rank(candidates []SearchHitDTO, terms []string, types []string, limit int) []SearchHitDTO {
+	hits := []SearchHitDTO{}
+
+	for _, hit := range candidates {
+		if !containsType(types, hit.Type) {
+			continue
+		}
+
+		hit.Score = core.SearchScore(terms, hit.Name)
+
+		if hit.Score > 0 {
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return len(hits[i].Name) < len(hits[j].Name)
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+func parseLimit(r *http.Request) (int, *core.ApiError) {
+	value := r.URL.Query().Get("limit")
+
+	if value == "" {
+		return defaultSearchLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+
+	if err != nil || limit < 1 || limit > core.MaxPageLimit {
+		return 0, core.NewInvalidQueryError("limit", "El valor debe estar entre 1 y "+strconv.Itoa(core.MaxPageLimit))
+	}
+
+	return limit, nil
+}
+
+func parseTypes(r *http.Request) ([]string, *core.ApiError) {
+	value := r.URL.Query().Get("types")
+
+	if value == "" {
+		return HitTypes, nil
+	}
+
+	types := strings.Split(value, ",")
+
+	for _, hitType := range types {
+		if !containsType(HitTypes, hitType) {
+			return nil, core.NewInvalidQueryError("types", "El valor debe ser una lista de: "+strings.Join(HitTypes, ", "))
+		}
+	}
+
+	return types, nil
+}
+
+// allowedTypes leaves out budgets and their lines for principals that cannot
+// read them; the route itself requires reading materials.
+func allowedTypes(ctx context.Context, types []string) []string {
+	principal := core.PrincipalFromContext(ctx)
+
+	if principal == nil || principal.HasPermission(core.PermissionReadBudgets) {
+		return types
+	}
+
+	allowed := []string{}
+
+	for _, hitType := range types {
+		if hitType == HitTypeMaterial {
+			allowed = append(allowed, hitType)
+		}
+	}
+
+	return allowed
+}
+
+func containsType(types []string, hitType string) bool {
+	for _, candidate := range types {
+		if candidate == hitType {
+			return true
+		}
+	}
+
+	return false
+}
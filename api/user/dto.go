@@ -0,0 +1,69 @@
+package user
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserDTO struct {
+	ID        primitive.ObjectID `json:"id"`
+	Username  string             `json:"username"`
+	Roles     []string           `json:"roles"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
+// bcrypt ignores everything past 72 bytes, so longer passwords are rejected
+// instead of silently truncated. Users without roles are created as viewers.
+type CreateUserDTO struct {
+	Username string   `json:"username" validate:"required,min=3,max=50"`
+	Password string   `json:"password" validate:"required,min=8,max=72"`
+	Roles    []string `json:"roles" validate:"omitempty,dive,oneof=admin purchasing estimator viewer superadmin"`
+}
+
+type UserRolesDTO struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,oneof=admin purchasing estimator viewer superadmin"`
+}
+
+var UserSortFields = []string{"username"}
+
+type LoginDTO struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type TokenDTO struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+func NewUserDTOs(users []User) []UserDTO {
+	dtos := make([]UserDTO, len(users))
+
+	for i := range users {
+		dtos[i] = *NewUserDTO(&users[i])
+	}
+
+	return dtos
+}
+
+func NewUserDTO(user *User) *UserDTO {
+	roles := user.Roles
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return &UserDTO{
+		ID:        user.ID,
+		Username:  user.Username,
+		Roles:     roles,
+		CreatedAt: user.CreatedAt,
+	}
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewUserHandler(service UserService) UserHandler {
+	return &handler{
+		service: service,
+	}
+}
+
+func NewUserService(userRepository UserRepository, tokens *core.TokenIssuer, bcryptCost int) UserService {
+	return &service{
+		userRepository: userRepository,
+		tokens:         tokens,
+		bcryptCost:     bcryptCost,
+	}
+}
+
+func NewUserRepository(db *mongo.Database, timeouts core.QueryTimeouts) UserRepository {
+	return &repository{
+		db:       db.Collection("users"),
+		timeouts: timeouts,
+	}
+}
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type handler struct {
+	service UserService
+}
+
+type UserHandler interface {
+	Login(w http.ResponseWriter, r *http.Request)
+	RefreshToken(w http.ResponseWriter, r *http.Request)
+	GetAllUsers(w http.ResponseWriter, r *http.Request)
+	CreateUser(w http.ResponseWriter, r *http.Request)
+	UpdateUserRoles(w http.ResponseWriter, r *http.Request)
+	GetCurrentUser(w http.ResponseWriter, r *http.Request)
+	GetUserRoutes() core.Routes
+}
+
+func (h *handler) Login(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.Login(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.RefreshToken(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetAllUsers(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.CreateUser(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusCreated, body, err)
+}
+
+func (h *handler) UpdateUserRoles(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.UpdateUserRoles(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.GetCurrentUser(r.Context(), r)
+	core.EncodeJsonResponse(w, http.StatusOK, body, err)
+}
+
+func (h *handler) GetUserRoutes() core.Routes {
+	return core.Routes{
+		core.Route{
+			Path:        "/auth/login",
+			HandlerFunc: h.Login,
+			Method:      "POST",
+			Public:      true,
+			Summary:     "Valida las credenciales y emite un token de acceso y uno de refresco",
+			Request:     LoginDTO{},
+			Response:    TokenDTO{},
+		},
+		core.Route{
+			Path:        "/auth/refresh",
+			HandlerFunc: h.RefreshToken,
+			Method:      "POST",
+			Public:      true,
+			Summary:     "Emite un nuevo par de tokens a partir de un token de refresco",
+			Request:     RefreshTokenDTO{},
+			Response:    TokenDTO{},
+		},
+		core.Route{
+			Path:        "/users",
+			HandlerFunc: h.GetAllUsers,
+			Method:      "GET",
+			Permission:  core.PermissionManageUsers,
+			Summary:     "Lista los usuarios con sus roles",
+			Response:    core.Page[UserDTO]{},
+			QueryParams: map[string]string{
+				"limit":     "Cantidad de usuarios por pagina, hasta 100",
+				"pageToken": "Token de la pagina siguiente devuelto en nextPageToken",
+				"sort":      "Orden: username o createdAt, con el prefijo - para descendente",
+			},
+		},
+		core.Route{
+			Path:        "/users",
+			HandlerFunc: h.CreateUser,
+			Method:      "POST",
+			Permission:  core.PermissionManageUsers,
+			Summary:     "Crea un usuario; sin roles se crea como viewer",
+			Request:     CreateUserDTO{},
+			Response:    UserDTO{},
+			Status:      http.StatusCreated,
+		},
+		core.Route{
+			Path:        "/users/{id}/roles",
+			HandlerFunc: h.UpdateUserRoles,
+			Method:      "PUT",
+			Permission:  core.PermissionManageUsers,
+			Summary:     "Reemplaza los roles de un usuario; aplican cuando renueva sus tokens",
+			Request:     UserRolesDTO{},
+			Response:    UserDTO{},
+			PathParams: map[string]string{
+				"id": "ID del usuario",
+			},
+		},
+		core.Route{
+			Path:        "/users/me",
+			HandlerFunc: h.GetCurrentUser,
+			Method:      "GET",
+			Summary:     "Devuelve el usuario autenticado",
+			Response:    UserDTO{},
+		},
+	}
+}
@@ -0,0 +1,23 @@
+package user
+
+import (
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type User struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Username string             `bson:"username"`
+	// OrganizationID is the organization the user works in; its tokens are
+	// scoped to it.
+	OrganizationID primitive.ObjectID `bson:"organizationId"`
+	PasswordHash   string             `bson:"passwordHash"`
+	Roles          []string           `bson:"roles"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+}
+
+func (u *User) Principal() core.Principal {
+	return core.Principal{UserID: u.ID, Username: u.Username, OrganizationID: u.OrganizationID, Roles: u.Roles}
+}
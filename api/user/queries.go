@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var userSortFields = map[string]string{
+	"username":         "username",
+	core.SortCreatedAt: "_id",
+}
+
+// NormalizeUsername makes usernames case insensitive; they are stored
+// normalized so lookups stay exact matches.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func UsernameIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetName("users_username").SetUnique(true),
+	}
+}
+
+func GetUsersByOrganization(ctx context.Context) bson.M {
+	return core.TenantFilter(ctx, bson.M{})
+}
+
+func GetUserById(oid primitive.ObjectID) bson.M {
+	return bson.M{"_id": oid}
+}
+
+// GetOrganizationUserById finds the user only in the request organization,
+// for the writes an organization admin makes.
+func GetOrganizationUserById(ctx context.Context, oid primitive.ObjectID) bson.M {
+	return core.TenantFilter(ctx, GetUserById(oid))
+}
+
+func GetUserByUsername(username string) bson.M {
+	return bson.M{"username": NormalizeUsername(username)}
+}
+
+func UpdateUserRoles(roles []string) bson.M {
+	return bson.M{"$set": bson.M{"roles": roles}}
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrDuplicateUsername = errors.New("ya existe un usuario con ese nombre")
+
+type repository struct {
+	db       *mongo.Collection
+	timeouts core.QueryTimeouts
+}
+
+type UserRepository interface {
+	FindUsers(ctx context.Context, page core.PageRequest) ([]User, int64, error)
+	FindUserByOID(ctx context.Context, oid *primitive.ObjectID) *User
+	FindUserByUsername(ctx context.Context, username string) *User
+	// CreateUser returns ErrDuplicateUsername when the username is taken.
+	CreateUser(ctx context.Context, user *User) error
+	UpdateUserRoles(ctx context.Context, oid *primitive.ObjectID, roles []string) error
+}
+
+// EnsureUserIndexes creates the unique username index, if missing.
+func EnsureUserIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().CreateOne(ctx, UsernameIndex())
+	return err
+}
+
+func (r *repository) FindUsers(ctx context.Context, page core.PageRequest) ([]User, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUsers")
+	defer cancel()
+
+	filter := GetUsersByOrganization(ctx)
+
+	total, err := r.db.CountDocuments(ctx, filter)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	cursor, err := r.db.Find(ctx, filter, page.FindOptions(userSortFields))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	var users []User = []User{}
+
+	err = cursor.All(ctx, &users)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *repository) FindUserByOID(ctx context.Context, oid *primitive.ObjectID) *User {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUserByOID")
+	defer cancel()
+
+	return r.findUser(ctx, GetUserById(*oid))
+}
+
+func (r *repository) FindUserByUsername(ctx context.Context, username string) *User {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUserByUsername")
+	defer cancel()
+
+	return r.findUser(ctx, GetUserByUsername(username))
+}
+
+func (r *repository) CreateUser(ctx context.Context, user *User) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.CreateUser")
+	defer cancel()
+
+	user.Username = NormalizeUsername(user.Username)
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.InsertOne(ctx, user)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateUsername
+	}
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateUserRoles(ctx context.Context, oid *primitive.ObjectID, roles []string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.UpdateUserRoles")
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, GetOrganizationUserById(ctx, *oid), UpdateUserRoles(roles))
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func (r *repository) findUser(ctx context.Context, filter any) *User {
+	var user *User = &User{}
+
+	err := r.db.FindOne(ctx, filter).Decode(user)
+
+	if err != nil {
+		// an unknown username is an expected outcome of a failed login
+		if err != mongo.ErrNoDocuments {
+			core.LogError(ctx, err)
+		}
+
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,335 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type service struct {
+	userRepository UserRepository
+	tokens         *core.TokenIssuer
+	bcryptCost     int
+	dummyHashOnce  sync.Once
+	dummyHash      []byte
+}
+
+type UserService interface {
+	Login(ctx context.Context, r *http.Request) (*TokenDTO, *core.ApiError)
+	RefreshToken(ctx context.Context, r *http.Request) (*TokenDTO, *core.ApiError)
+	GetAllUsers(ctx context.Context, r *http.Request) (*core.Page[UserDTO], *core.ApiError)
+	CreateUser(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError)
+	UpdateUserRoles(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError)
+	GetCurrentUser(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError)
+	// CreateOrganizationUser creates the user in the given organization
+	// rather than in the one of the principal.
+	CreateOrganizationUser(ctx context.Context, organizationId primitive.ObjectID, created CreateUserDTO) (*UserDTO, *core.ApiError)
+	// EnsureUser creates the user with the roles in the default organization
+	// when its username is free. An existing user of the default organization
+	// is left as it is, so roles revoked since are not granted again, and one
+	// of another organization is an error. It reports whether the user was
+	// created.
+	EnsureUser(ctx context.Context, username string, password string, roles []string) (bool, error)
+}
+
+func (s *service) Login(ctx context.Context, r *http.Request) (*TokenDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.Login")
+	defer span.End()
+
+	var credentials *LoginDTO = &LoginDTO{}
+
+	apiErr := core.DecodeBody(r, credentials)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	user := s.userRepository.FindUserByUsername(ctx, credentials.Username)
+
+	if user == nil {
+		// compare anyway so unknown usernames take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(s.getDummyHash(), []byte(credentials.Password))
+		return nil, core.NewUnauthorizedError("Usuario o contraseña incorrectos")
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password))
+
+	if err != nil {
+		core.Log(ctx, core.LogLevelWarn, "Contraseña incorrecta para el usuario "+user.Username, nil)
+		return nil, core.NewUnauthorizedError("Usuario o contraseña incorrectos")
+	}
+
+	return s.issueTokens(user)
+}
+
+func (s *service) RefreshToken(ctx context.Context, r *http.Request) (*TokenDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.RefreshToken")
+	defer span.End()
+
+	var refresh *RefreshTokenDTO = &RefreshTokenDTO{}
+
+	apiErr := core.DecodeBody(r, refresh)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	principal, err := s.tokens.Parse(refresh.RefreshToken, core.TokenTypeRefresh)
+
+	if err != nil {
+		core.Log(ctx, core.LogLevelWarn, "Token de refresco rechazado: "+err.Error(), nil)
+		return nil, core.NewUnauthorizedError("El token de refresco no es valido o expiro")
+	}
+
+	// the user may have been removed since the token was issued
+	user := s.userRepository.FindUserByOID(ctx, &principal.UserID)
+
+	if user == nil {
+		return nil, core.NewUnauthorizedError("El token de refresco no es valido o expiro")
+	}
+
+	return s.issueTokens(user)
+}
+
+func (s *service) GetAllUsers(ctx context.Context, r *http.Request) (*core.Page[UserDTO], *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.GetAllUsers")
+	defer span.End()
+
+	page, apiErr := core.ParsePageRequest(r, UserSortFields...)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	users, total, err := s.userRepository.FindUsers(ctx, *page)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return core.NewPage(NewUserDTOs(users), total, *page), nil
+}
+
+func (s *service) CreateUser(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	var created *CreateUserDTO = &CreateUserDTO{}
+
+	apiErr := core.DecodeBody(r, created)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	apiErr = checkGrantable(ctx, created.Roles)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	user, apiErr := s.CreateOrganizationUser(ctx, core.TenantFromContext(ctx), *created)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return user, nil
+}
+
+func (s *service) CreateOrganizationUser(ctx context.Context, organizationId primitive.ObjectID, created CreateUserDTO) (*UserDTO, *core.ApiError) {
+	if len(created.Roles) == 0 {
+		created.Roles = []string{core.RoleViewer}
+	}
+
+	user, err := s.createUser(ctx, organizationId, created.Username, created.Password, created.Roles)
+
+	if errors.Is(err, ErrDuplicateUsername) {
+		return nil, core.NewConflictError("Ya existe un usuario con el nombre " + NormalizeUsername(created.Username))
+	}
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return NewUserDTO(user), nil
+}
+
+func (s *service) UpdateUserRoles(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.UpdateUserRoles")
+	defer span.End()
+
+	oid := core.ConvertHexToObjectId(mux.Vars(r)["id"])
+
+	if oid == nil {
+		return nil, core.NewInvalidIdError("id")
+	}
+
+	var userRoles *UserRolesDTO = &UserRolesDTO{}
+
+	apiErr := core.DecodeBody(r, userRoles)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	apiErr = checkGrantable(ctx, userRoles.Roles)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	user := s.userRepository.FindUserByOID(ctx, oid)
+
+	// users of other organizations are not visible to their admins
+	if user == nil || user.OrganizationID != core.TenantFromContext(ctx) {
+		return nil, core.NewNotFoundError("usuario")
+	}
+
+	// organization admins cannot take the superadmin role away either, and
+	// EnsureUser would not grant it again
+	apiErr = checkGrantable(ctx, user.Roles)
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// an admin demoting itself could leave nobody able to manage roles
+	principal := core.PrincipalFromContext(ctx)
+
+	if principal != nil && principal.UserID == user.ID && principal.HasRole(core.RoleAdmin) && !containsRole(userRoles.Roles, core.RoleAdmin) {
+		return nil, core.NewConflictError("No puede quitarse el rol admin a si mismo")
+	}
+
+	err := s.userRepository.UpdateUserRoles(ctx, oid, userRoles.Roles)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	user.Roles = userRoles.Roles
+
+	return NewUserDTO(user), nil
+}
+
+func (s *service) GetCurrentUser(ctx context.Context, r *http.Request) (*UserDTO, *core.ApiError) {
+	ctx, span := core.StartSpan(ctx, "UserService.GetCurrentUser")
+	defer span.End()
+
+	principal := core.PrincipalFromContext(ctx)
+
+	if principal == nil {
+		return nil, core.NewUnauthorizedError("Se requiere un token de acceso")
+	}
+
+	user := s.userRepository.FindUserByOID(ctx, &principal.UserID)
+
+	if user == nil {
+		return nil, core.NewNotFoundError("usuario")
+	}
+
+	return NewUserDTO(user), nil
+}
+
+func (s *service) EnsureUser(ctx context.Context, username string, password string, roles []string) (bool, error) {
+	existing := s.userRepository.FindUserByUsername(ctx, username)
+
+	if existing != nil && existing.OrganizationID != core.DefaultOrganizationID {
+		return false, fmt.Errorf("%w en otra organizacion: %s", ErrDuplicateUsername, existing.Username)
+	}
+
+	if existing != nil {
+		return false, nil
+	}
+
+	_, err := s.createUser(ctx, core.DefaultOrganizationID, username, password, roles)
+
+	if errors.Is(err, ErrDuplicateUsername) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *service) createUser(ctx context.Context, organizationId primitive.ObjectID, username string, password string, roles []string) (*User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Username:       NormalizeUsername(username),
+		OrganizationID: organizationId,
+		PasswordHash:   string(hash),
+		Roles:          roles,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	err = s.userRepository.CreateUser(ctx, user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *service) issueTokens(user *User) (*TokenDTO, *core.ApiError) {
+	accessToken, err := s.tokens.Issue(user.Principal(), core.TokenTypeAccess)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	refreshToken, err := s.tokens.Issue(user.Principal(), core.TokenTypeRefresh)
+
+	if err != nil {
+		return nil, core.NewInternalError(err)
+	}
+
+	return &TokenDTO{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.tokens.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func (s *service) getDummyHash() []byte {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), s.bcryptCost)
+	})
+
+	return s.dummyHash
+}
+
+// checkGrantable keeps organization admins from granting the superadmin role,
+// which reaches every organization.
+func checkGrantable(ctx context.Context, roles []string) *core.ApiError {
+	principal := core.PrincipalFromContext(ctx)
+
+	if containsRole(roles, core.RoleSuperAdmin) && (principal == nil || !principal.HasRole(core.RoleSuperAdmin)) {
+		return core.NewForbiddenError(core.PermissionManageOrganizations)
+	}
+
+	return nil
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, candidate := range roles {
+		if candidate == role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/apikey"
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/api/docs"
+	"github.com/lucasbravi2019/arquitectura/api/health"
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/api/search"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Closer func(ctx context.Context) error
+
+type Repositories struct {
+	// UnitOfWork makes the cascades of the services atomic in the storage of
+	// the repositories.
+	UnitOfWork   core.UnitOfWork
+	Budget       budget.BudgetRepository
+	Material     material.MaterialRepository
+	Dimension    dimension.DimensionRepository
+	Search       search.SearchRepository
+	User         user.UserRepository
+	Organization organization.OrganizationRepository
+	ApiKey       apikey.ApiKeyRepository
+	Audit        audit.AuditRepository
+}
+
+type Services struct {
+	Budget       budget.BudgetService
+	Material     material.MaterialService
+	Dimension    dimension.DimensionService
+	Search       search.SearchService
+	User         user.UserService
+	Organization organization.OrganizationService
+	ApiKey       apikey.ApiKeyService
+	Audit        audit.AuditService
+}
+
+const (
+	ApiTitle   = "Api Arquitectura"
+	ApiVersion = "1.0.0"
+)
+
+type Handlers struct {
+	Health       health.HealthHandler
+	Docs         docs.DocsHandler
+	Budget       budget.BudgetHandler
+	Material     material.MaterialHandler
+	Dimension    dimension.DimensionHandler
+	Search       search.SearchHandler
+	User         user.UserHandler
+	Organization organization.OrganizationHandler
+	ApiKey       apikey.ApiKeyHandler
+	Audit        audit.AuditHandler
+}
+
+// Container holds every dependency of the application. It is assembled once
+// in main, and any repository can be replaced by another implementation of
+// its interface before calling NewContainer.
+type Container struct {
+	Settings *core.Settings
+	Monitor  *core.HealthMonitor
+	Metrics  *core.Metrics
+	Tokens   *core.TokenIssuer
+	// RateLimits keeps the rate limit buckets in process; replace it before
+	// building the router to share them between instances.
+	RateLimits core.RateLimitStore
+	// Idempotency keeps the responses replayed to retries in process, and is
+	// replaced the same way.
+	Idempotency  core.IdempotencyStore
+	Repositories Repositories
+	Services     Services
+	Handlers     Handlers
+	closers      []Closer
+}
+
+func NewMongoRepositories(db *mongo.Database, timeouts core.QueryTimeouts) Repositories {
+	return Repositories{
+		UnitOfWork:   core.NewMongoUnitOfWork(db),
+		Budget:       budget.NewBudgetRepository(db, timeouts),
+		Material:     material.NewMaterialRepository(db, timeouts),
+		Dimension:    dimension.NewDimensionRepository(db, timeouts),
+		Search:       search.NewSearchRepository(db, timeouts),
+		User:         user.NewUserRepository(db, timeouts),
+		Organization: organization.NewOrganizationRepository(db, timeouts),
+		ApiKey:       apikey.NewApiKeyRepository(db, timeouts),
+		Audit:        audit.NewAuditRepository(db, timeouts),
+	}
+}
+
+func NewContainer(settings *core.Settings, monitor *core.HealthMonitor, metrics *core.Metrics, repositories Repositories) *Container {
+	tokens := core.NewTokenIssuer(settings.Auth)
+
+	recorder := audit.NewAuditService(repositories.Audit)
+
+	services := Services{
+		Budget:    budget.NewBudgetService(repositories.Budget, recorder, metrics),
+		Material:  material.NewMaterialService(repositories.Material, repositories.Budget, repositories.UnitOfWork, recorder, metrics),
+		Dimension: dimension.NewDimensionService(repositories.Dimension, repositories.Material, repositories.Budget, repositories.UnitOfWork, recorder),
+		Search:    search.NewSearchService(repositories.Search),
+		User:      user.NewUserService(repositories.User, tokens, settings.Auth.BcryptCost),
+		ApiKey:    apikey.NewApiKeyService(repositories.ApiKey),
+		Audit:     recorder,
+	}
+
+	services.Organization = organization.NewOrganizationService(repositories.Organization, services.User, repositories.UnitOfWork)
+
+	handlers := Handlers{
+		Health:       health.NewHealthHandler(monitor),
+		Budget:       budget.NewBudgetHandler(services.Budget),
+		Material:     material.NewMaterialHandler(services.Material),
+		Dimension:    dimension.NewDimensionHandler(services.Dimension),
+		Search:       search.NewSearchHandler(services.Search),
+		User:         user.NewUserHandler(services.User),
+		Organization: organization.NewOrganizationHandler(services.Organization),
+		ApiKey:       apikey.NewApiKeyHandler(services.ApiKey),
+		Audit:        audit.NewAuditHandler(services.Audit),
+	}
+
+	handlers.Docs = docs.NewDocsHandler(core.NewOpenApiDocument(ApiTitle, ApiVersion,
+		handlers.Health.GetHealthRoutes(),
+		handlers.Budget.GetBudgetRoutes(),
+		handlers.Material.GetMaterialRoutes(),
+		handlers.Dimension.GetDimensionRoutes(),
+		handlers.Search.GetSearchRoutes(),
+		handlers.User.GetUserRoutes(),
+		handlers.Organization.GetOrganizationRoutes(),
+		handlers.ApiKey.GetApiKeyRoutes(),
+		handlers.Audit.GetAuditRoutes(),
+	))
+
+	return &Container{
+		Settings:     settings,
+		Monitor:      monitor,
+		Metrics:      metrics,
+		Tokens:       tokens,
+		RateLimits:   core.NewMemoryRateLimitStore(),
+		Idempotency:  core.NewMemoryIdempotencyStore(),
+		Repositories: repositories,
+		Services:     services,
+		Handlers:     handlers,
+	}
+}
+
+// OnClose registers a function to release a resource when the server stops.
+// Closers run in reverse registration order.
+func (c *Container) OnClose(closer Closer) {
+	c.closers = append(c.closers, closer)
+}
+
+func (c *Container) Close(ctx context.Context) error {
+	var firstErr error
+
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		err := c.closers[i](ctx)
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	testAdminUsername = "admin"
+	testAdminPassword = "supersecreto"
+)
+
+// newTestContainer assembles the container like main does, over the memory
+// storage, letting the test replace repositories before NewContainer.
+func newTestContainer(t *testing.T, replace func(repositories *Repositories)) *Container {
+	t.Helper()
+
+	return newStorageContainer(t, core.StorageDriverMemory, replace)
+}
+
+// newStorageContainer is newTestContainer over the storage driver. sqlite
+// uses a file of the test and Mongo the database at APP_TEST_DATABASE_URI,
+// skipping the test when unset; transactions need a replica set.
+func newStorageContainer(t *testing.T, driver string, replace func(repositories *Repositories)) *Container {
+	t.Helper()
+
+	settings := core.DefaultSettings()
+	settings.Storage.Driver = driver
+	settings.Storage.SQLite.Path = filepath.Join(t.TempDir(), "arquitectura.db")
+	settings.Auth.Secret = strings.Repeat("s", 32)
+	settings.Auth.BcryptCost = 4
+	settings.Auth.Admin = core.AdminSettings{Username: testAdminUsername, Password: testAdminPassword}
+	settings.Logging.LogBodies = false
+
+	if driver == core.StorageDriverMongo {
+		settings.Database.URI = os.Getenv("APP_TEST_DATABASE_URI")
+
+		if settings.Database.URI == "" {
+			t.Skip("APP_TEST_DATABASE_URI no esta definida")
+		}
+
+		settings.Database.Name = "arquitectura_test_" + primitive.NewObjectID().Hex()
+		t.Cleanup(func() { dropDatabase(settings.Database) })
+	}
+
+	monitor := core.NewHealthMonitor(settings.Health)
+	metrics := core.NewMetrics()
+
+	repositories, closeStorage, err := OpenStorage(settings, monitor, metrics)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	monitor.Start(ctx)
+
+	if replace != nil {
+		replace(&repositories)
+	}
+
+	container := NewContainer(settings, monitor, metrics, repositories)
+	container.OnClose(closeStorage)
+
+	t.Cleanup(func() {
+		cancel()
+		container.Close(context.Background())
+	})
+
+	if err := CreateDefaultOrganization(container); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CreateAdminUser(container); err != nil {
+		t.Fatal(err)
+	}
+
+	return container
+}
+
+func dropDatabase(settings core.DatabaseSettings) {
+	database, err := core.ConnectDatabase(settings)
+
+	if err != nil {
+		return
+	}
+
+	database.Drop(context.Background())
+	core.CloseDatabaseConnection(context.Background(), database)
+}
+
+func newTestRouter(t *testing.T, replace func(repositories *Repositories)) *mux.Router {
+	t.Helper()
+
+	return NewRouter(newTestContainer(t, replace))
+}
+
+func serve(router http.Handler, method string, path string, token string, header http.Header, body any) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	r := httptest.NewRequest(method, path, &payload)
+	r.Header.Set("Content-Type", "application/json")
+
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+// decodeBody reads the body out of the response envelope.
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, target any) {
+	t.Helper()
+
+	envelope := struct {
+		Body json.RawMessage `json:"body"`
+	}{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(envelope.Body, target); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func login(t *testing.T, router http.Handler) string {
+	t.Helper()
+
+	w := serve(router, "POST", "/auth/login", "", nil, user.LoginDTO{Username: testAdminUsername, Password: testAdminPassword})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var tokens user.TokenDTO
+	decodeBody(t, w, &tokens)
+
+	return tokens.AccessToken
+}
+
+func TestBudgetRoutes(t *testing.T) {
+	router := newTestRouter(t, nil)
+	token := login(t, router)
+
+	w := serve(router, "POST", "/budgets", token, nil, budget.BudgetNameDTO{Name: "casa"})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /budgets: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var created budget.BudgetDTO
+	decodeBody(t, w, &created)
+
+	path := "/budgets/" + created.ID.Hex()
+	current := http.Header{core.IfMatchHeader: {core.ETag(created.Version)}}
+	stale := http.Header{core.IfMatchHeader: {core.ETag(created.Version + 1)}}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		header     http.Header
+		body       any
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "without token", method: "GET", path: path, wantStatus: http.StatusUnauthorized},
+		{name: "get", method: "GET", path: path, token: token, wantStatus: http.StatusOK, wantETag: core.ETag(created.Version)},
+		{name: "invalid id", method: "GET", path: "/budgets/invalido", token: token, wantStatus: http.StatusBadRequest},
+		{name: "empty name", method: "PUT", path: path, token: token, header: current, body: budget.BudgetNameDTO{}, wantStatus: http.StatusBadRequest},
+		{name: "stale If-Match", method: "PUT", path: path, token: token, header: stale, body: budget.BudgetNameDTO{Name: "otra"}, wantStatus: http.StatusPreconditionFailed},
+		{name: "current If-Match", method: "PUT", path: path, token: token, header: current, body: budget.BudgetNameDTO{Name: "otra"}, wantStatus: http.StatusOK, wantETag: core.ETag(created.Version + 1)},
+		{name: "delete with the replaced version", method: "DELETE", path: path, token: token, header: current, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path, tt.token, tt.header, tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if etag := w.Header().Get(core.ETagHeader); etag != tt.wantETag {
+				t.Errorf("ETag %q, se esperaba %q", etag, tt.wantETag)
+			}
+		})
+	}
+}
+
+// failingBudgetRepository stands in for a storage that is down.
+type failingBudgetRepository struct {
+	budget.BudgetRepository
+}
+
+func (r failingBudgetRepository) FindBudgets(ctx context.Context, filter budget.BudgetFilter, page core.PageRequest) ([]budget.BudgetDTO, int64, error) {
+	return nil, 0, errors.New("sin conexion")
+}
+
+func TestReplacedRepository(t *testing.T) {
+	router := newTestRouter(t, func(repositories *Repositories) {
+		repositories.Budget = failingBudgetRepository{BudgetRepository: repositories.Budget}
+	})
+	token := login(t, router)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+	}{
+		{name: "failing method", method: "GET", path: "/budgets", wantStatus: http.StatusInternalServerError},
+		{name: "embedded method", method: "POST", path: "/budgets", body: budget.BudgetNameDTO{Name: "casa"}, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path, token, nil, tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
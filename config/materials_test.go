@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// racingMaterials finds every name free, like a request that validated the
+// name before a concurrent one created it, so only the storage rejects it.
+type racingMaterials struct {
+	material.MaterialRepository
+}
+
+func (r racingMaterials) ValidateExistingMaterial(ctx context.Context, materialName *material.MaterialNameDTO, excludedId *primitive.ObjectID) error {
+	return nil
+}
+
+func TestDuplicateMaterialName(t *testing.T) {
+	drivers := []string{core.StorageDriverMemory, core.StorageDriverSQLite, core.StorageDriverMongo}
+
+	for _, driver := range drivers {
+		t.Run(driver, func(t *testing.T) {
+			container := newStorageContainer(t, driver, func(repositories *Repositories) {
+				repositories.Material = racingMaterials{MaterialRepository: repositories.Material}
+			})
+			router := NewRouter(container)
+			token := login(t, router)
+
+			w := serve(router, "POST", "/materials", token, nil, material.MaterialNameDTO{Name: "Arena"})
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("POST /materials: status %d: %s", w.Code, w.Body.String())
+			}
+
+			w = serve(router, "POST", "/materials", token, nil, material.MaterialNameDTO{Name: "Cal"})
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("POST /materials: status %d: %s", w.Code, w.Body.String())
+			}
+
+			var other created
+			decodeBody(t, w, &other)
+
+			tests := []struct {
+				name     string
+				method   string
+				path     string
+				material string
+			}{
+				{name: "create", method: "POST", path: "/materials", material: "ARENA"},
+				{name: "create with accents", method: "POST", path: "/materials", material: "Aréna"},
+				{name: "rename", method: "PUT", path: "/materials/" + other.ID.Hex(), material: "ARENA"},
+				{name: "rename with accents", method: "PUT", path: "/materials/" + other.ID.Hex(), material: "árena"},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					w := serve(router, tt.method, tt.path, token, nil, material.MaterialNameDTO{Name: tt.material})
+
+					if w.Code != http.StatusConflict {
+						t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusConflict, w.Body.String())
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMergeMaterials(t *testing.T) {
+	drivers := []string{core.StorageDriverMemory, core.StorageDriverSQLite, core.StorageDriverMongo}
+
+	for _, driver := range drivers {
+		t.Run(driver, func(t *testing.T) {
+			container := newStorageContainer(t, driver, nil)
+			router := NewRouter(container)
+			token := login(t, router)
+
+			dimensionId, survivorId, budgetId := createBudgetLine(t, router, token)
+
+			w := serve(router, "POST", "/materials", token, nil, material.MaterialNameDTO{Name: "Cal"})
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("POST /materials: status %d: %s", w.Code, w.Body.String())
+			}
+
+			var merged created
+			decodeBody(t, w, &merged)
+
+			steps := []struct {
+				path string
+				body any
+			}{
+				{path: "/dimensions/" + dimensionId.Hex() + "/materials/" + merged.ID.Hex(), body: material.MaterialDimensionPriceDTO{Price: 20}},
+				{path: "/materials/" + merged.ID.Hex() + "/budgets/" + budgetId.Hex(), body: map[string]any{"metric": "1 KG", "quantity": 2}},
+			}
+
+			for _, step := range steps {
+				w := serve(router, "PUT", step.path, token, nil, step.body)
+
+				if w.Code >= http.StatusBadRequest {
+					t.Fatalf("PUT %s: status %d: %s", step.path, w.Code, w.Body.String())
+				}
+			}
+
+			ctx := context.Background()
+			survivor := container.Repositories.Material.FindMaterialByOID(ctx, &survivorId)
+			path := "/materials/" + survivorId.Hex() + "/merge"
+			body := material.MergeMaterialDTO{MaterialID: merged.ID}
+
+			w = serve(router, "POST", path, token, http.Header{core.IfMatchHeader: {core.ETag(survivor.Version + 1)}}, body)
+
+			if w.Code != http.StatusPreconditionFailed {
+				t.Fatalf("stale If-Match: status %d, se esperaba %d: %s", w.Code, http.StatusPreconditionFailed, w.Body.String())
+			}
+
+			w = serve(router, "POST", path, token, http.Header{core.IfMatchHeader: {core.ETag(survivor.Version)}}, body)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			if etag := w.Header().Get(core.ETagHeader); etag != core.ETag(survivor.Version+1) {
+				t.Errorf("ETag %q, se esperaba %q", etag, core.ETag(survivor.Version+1))
+			}
+
+			found := container.Repositories.Budget.FindBudgetByOID(ctx, &budgetId)
+
+			for _, line := range found.Materials {
+				if line.MaterialID != survivorId || line.Dimension.Price != 10 || line.Price != 20 {
+					t.Errorf("linea %+v, se esperaba del material %s a precio 10", line, survivorId.Hex())
+				}
+			}
+
+			if found.Price != 120 {
+				t.Errorf("precio %v, se esperaba 120", found.Price)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/api/search"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/lucasbravi2019/arquitectura/storage/sqlite"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoMigrations are recorded in core.MigrationsCollection. The first ones
+// ran on every start before migrations existed; they are idempotent, so
+// databases that already have their indexes record them unchanged.
+var mongoMigrations = []core.Migration[*mongo.Database]{
+	{Version: 1, Name: "search_text_indexes", Up: search.EnsureSearchIndexes},
+	{Version: 2, Name: "users_username_index", Up: user.EnsureUserIndexes},
+	{Version: 3, Name: "assign_legacy_documents", Up: organization.AssignLegacyDocuments},
+	{Version: 4, Name: "audit_log_indexes", Up: audit.EnsureAuditIndexes},
+	{Version: 5, Name: "budgets_lookup_indexes", Up: budget.EnsureBudgetIndexes},
+	{Version: 6, Name: "materials_dimension_index", Up: material.EnsureDimensionIndex},
+	{Version: 7, Name: "rename_duplicate_material_names", Up: material.RenameDuplicateNames},
+	{Version: 8, Name: "materials_name_unique_index", Up: material.EnsureNameIndex},
+	{Version: 9, Name: "backfill_budget_line_material_ids", Up: budget.BackfillMaterialIds},
+	{Version: 10, Name: "backfill_versions", Up: core.BackfillVersions("budgets", "materials", "dimensions")},
+}
+
+// Migrate applies the pending migrations of the configured storage, for the
+// migrate command. The memory storage starts empty and has none.
+func Migrate(settings *core.Settings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), settings.Database.MigrationTimeout)
+	defer cancel()
+
+	var applied int
+
+	switch settings.Storage.Driver {
+	case core.StorageDriverMongo:
+		database, err := core.ConnectDatabase(settings.Database)
+
+		if err != nil {
+			return err
+		}
+
+		defer core.CloseDatabaseConnection(context.Background(), database)
+
+		applied, err = migrateMongo(ctx, database)
+
+		if err != nil {
+			return err
+		}
+	case core.StorageDriverSQLite:
+		if settings.Storage.SQLite.Path == "" {
+			return fmt.Errorf("la ruta de la base de datos sqlite es obligatoria")
+		}
+
+		db, err := sqlite.Open(ctx, settings.Storage.SQLite.Path)
+
+		if err != nil {
+			return err
+		}
+
+		defer db.Close()
+
+		applied, err = sqlite.Migrate(ctx, db)
+
+		if err != nil {
+			return err
+		}
+	case core.StorageDriverMemory:
+	default:
+		return fmt.Errorf("driver de almacenamiento desconocido: %s", settings.Storage.Driver)
+	}
+
+	core.Log(ctx, core.LogLevelInfo, "Migraciones completas", core.LogFields{
+		"driver":  settings.Storage.Driver,
+		"applied": applied,
+	})
+
+	return nil
+}
+
+func migrateMongo(ctx context.Context, database *mongo.Database) (int, error) {
+	return core.RunMigrations(ctx, database, core.NewMongoMigrationHistory(database), mongoMigrations)
+}
+
+// prepareMongo applies the pending migrations, or only warns about them when
+// they run with the migrate command.
+func prepareMongo(settings core.DatabaseSettings, database *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), settings.MigrationTimeout)
+	defer cancel()
+
+	if settings.MigrateOnStartup {
+		_, err := migrateMongo(ctx, database)
+		return err
+	}
+
+	pending, err := core.PendingMigrations(ctx, core.NewMongoMigrationHistory(database), mongoMigrations)
+
+	if err == nil {
+		warnPendingMigrations(ctx, len(pending))
+	}
+
+	return err
+}
+
+// prepareSQLite is prepareMongo for sqlite databases.
+func prepareSQLite(settings core.DatabaseSettings, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), settings.MigrationTimeout)
+	defer cancel()
+
+	if settings.MigrateOnStartup {
+		_, err := sqlite.Migrate(ctx, db)
+		return err
+	}
+
+	pending, err := sqlite.PendingMigrations(ctx, db)
+
+	if err == nil {
+		warnPendingMigrations(ctx, pending)
+	}
+
+	return err
+}
+
+func warnPendingMigrations(ctx context.Context, pending int) {
+	if pending > 0 {
+		core.Log(ctx, core.LogLevelWarn, "Hay migraciones pendientes; aplicarlas con el comando migrate", core.LogFields{
+			"pending": pending,
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateDefaultOrganization creates the organization that owns the data
+// written before organizations existed, when missing.
+func CreateDefaultOrganization(container *Container) error {
+	ctx, cancel := context.WithTimeout(context.Background(), container.Settings.Database.ConnectTimeout)
+	defer cancel()
+
+	err := container.Services.Organization.EnsureDefaultOrganization(ctx)
+
+	if err != nil {
+		return fmt.Errorf("no se pudo crear la organizacion principal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+func TestCreateOrganization(t *testing.T) {
+	container := newTestContainer(t, nil)
+	router := NewRouter(container)
+	token := login(t, router)
+
+	tests := []struct {
+		name              string
+		admin             string
+		wantStatus        int
+		wantOrganizations int64
+	}{
+		{name: "new admin", admin: "lucia", wantStatus: http.StatusCreated, wantOrganizations: 2},
+		{name: "taken username", admin: testAdminUsername, wantStatus: http.StatusConflict, wantOrganizations: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, "POST", "/organizations", token, nil, organization.CreateOrganizationDTO{
+				Name:  "Obra " + tt.admin,
+				Admin: organization.OrganizationAdminDTO{Username: tt.admin, Password: testAdminPassword},
+			})
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			_, total, err := container.Repositories.Organization.FindOrganizations(context.Background(), core.PageRequest{Limit: 10})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != tt.wantOrganizations {
+				t.Errorf("hay %d organizaciones, se esperaba %d", total, tt.wantOrganizations)
+			}
+		})
+	}
+}
@@ -1,49 +1,143 @@
 package config
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
-	"github.com/lucasbravi2019/arquitectura/api/budget"
-	"github.com/lucasbravi2019/arquitectura/api/dimension"
-	"github.com/lucasbravi2019/arquitectura/api/material"
 	"github.com/lucasbravi2019/arquitectura/core"
 	"github.com/lucasbravi2019/arquitectura/middleware"
 )
 
-var apiRouterInstance *mux.Router
-
-func GetRouter() *mux.Router {
-	if apiRouterInstance == nil {
-		apiRouterInstance = mux.NewRouter()
-	}
-	return apiRouterInstance
+// RouteGuards build the middlewares that depend on the route. LimitAddress
+// runs first, then Authenticate on routes that are not public, followed by
+// the rate limit of the route group and its permission check. A nil
+// Authenticate leaves every route open, and nil LimitAddress and RateLimit
+// leave them unlimited. Idempotency, when set, wraps the
+// idempotent routes once the principal is known, and RequireIfMatch adds the
+// If-Match check to the conditional routes.
+type RouteGuards struct {
+	LimitAddress   middleware.Middleware
+	Authenticate   middleware.Middleware
+	RateLimit      func(group string) middleware.Middleware
+	Idempotency    func(route string) middleware.Middleware
+	RequireIfMatch bool
 }
 
-func RegisterRoutes(routes core.Routes) {
-	router := GetRouter()
+// RegisterRoutes wraps every route in the middlewares, adding the guards of
+// the route as the innermost ones. Nil guards register the routes as they are.
+func RegisterRoutes(router *mux.Router, routes core.Routes, guards *RouteGuards, middlewares ...middleware.Middleware) {
 	for _, route := range routes {
+		routeMiddlewares := middlewares[:len(middlewares):len(middlewares)]
+
+		if guards != nil {
+			routeMiddlewares = append(routeMiddlewares, guards.forRoute(route)...)
+		}
+
 		router.
 			Path(route.Path).
-			HandlerFunc(
-				middleware.RequestLoggerMiddleware(
-					middleware.DatabaseCheckMiddleware(
-						route.HandlerFunc))).
+			HandlerFunc(middleware.Chain(route.HandlerFunc, routeMiddlewares...)).
 			Methods(route.Method)
 	}
 }
 
-func StartApi() {
-	RegisterRoutes(budget.GetBudgetHandlerInstance().GetBudgetRoutes())
-	RegisterRoutes(material.GetMaterialHandlerInstance().GetMaterialRoutes())
-	RegisterRoutes(dimension.GetDimensionHandlerInstance().GetDimensionRoutes())
+func (g *RouteGuards) forRoute(route core.Route) []middleware.Middleware {
+	authenticated := g.Authenticate != nil && !route.Public
+	guards := []middleware.Middleware{}
+
+	if g.LimitAddress != nil {
+		guards = append(guards, g.LimitAddress)
+	}
+
+	if authenticated {
+		guards = append(guards, g.Authenticate)
+	}
+
+	if g.RateLimit != nil {
+		guards = append(guards, g.RateLimit(route.Group()))
+	}
+
+	if authenticated && route.Permission != "" {
+		guards = append(guards, middleware.AuthorizationMiddleware(route.Permission))
+	}
+
+	if g.Idempotency != nil && route.Idempotent {
+		guards = append(guards, g.Idempotency(route.Method+" "+route.Path))
+	}
+
+	if g.RequireIfMatch && route.Conditional {
+		guards = append(guards, middleware.PreconditionMiddleware())
+	}
+
+	return guards
+}
+
+func NewRouter(container *Container) *mux.Router {
+	router := mux.NewRouter()
+
+	RegisterRoutes(router, container.Handlers.Health.GetHealthRoutes(), nil)
+
+	if container.Settings.Docs.Enabled {
+		RegisterRoutes(router, container.Handlers.Docs.GetDocsRoutes(), nil)
+	}
+
+	if container.Settings.Metrics.Enabled {
+		router.Path(container.Settings.Metrics.Path).Handler(container.Metrics.Handler()).Methods("GET")
+	}
+
+	apiMiddlewares := []middleware.Middleware{
+		middleware.TracingMiddleware(),
+		middleware.RequestLoggerMiddleware(container.Settings.Logging),
+		middleware.MetricsMiddleware(container.Metrics),
+		middleware.DatabaseCheckMiddleware(container.Monitor),
+	}
+
+	guards := &RouteGuards{
+		RequireIfMatch: container.Settings.Server.RequireIfMatch,
+	}
+
+	if container.Settings.Auth.Enabled {
+		guards.Authenticate = middleware.AuthenticationMiddleware(container.Tokens, container.Services.ApiKey)
+	}
+
+	if container.Settings.RateLimit.Enabled {
+		guards.LimitAddress = middleware.AddressRateLimitMiddleware(container.Settings.RateLimit, container.RateLimits)
+		guards.RateLimit = func(group string) middleware.Middleware {
+			return middleware.RateLimitMiddleware(container.Settings.RateLimit, container.RateLimits, group)
+		}
+	}
+
+	if container.Settings.Idempotency.Enabled {
+		guards.Idempotency = func(route string) middleware.Middleware {
+			return middleware.IdempotencyMiddleware(container.Settings.Idempotency, container.Idempotency, route,
+				container.Settings.RateLimit.TrustForwardedFor)
+		}
+	}
+
+	RegisterRoutes(router, container.Handlers.User.GetUserRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Organization.GetOrganizationRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.ApiKey.GetApiKeyRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Budget.GetBudgetRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Material.GetMaterialRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Dimension.GetDimensionRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Search.GetSearchRoutes(), guards, apiMiddlewares...)
+	RegisterRoutes(router, container.Handlers.Audit.GetAuditRoutes(), guards, apiMiddlewares...)
+
+	return router
+}
+
+func CorsHandler(settings core.CorsSettings) func(http.Handler) http.Handler {
+	options := []handlers.CORSOption{
+		handlers.AllowedMethods(settings.AllowedMethods),
+		handlers.AllowedHeaders(settings.AllowedHeaders),
+		handlers.ExposedHeaders(settings.ExposedHeaders),
+		handlers.MaxAge(settings.MaxAge),
+		handlers.AllowedOrigins(settings.AllowedOrigins),
+	}
 
-	credentials := handlers.AllowCredentials()
-	methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE"})
-	ttl := handlers.MaxAge(3600)
-	origins := handlers.AllowedOrigins([]string{"*"})
+	if settings.AllowCredentials {
+		options = append(options, handlers.AllowCredentials())
+	}
 
-	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(credentials, methods, ttl, origins)(GetRouter())))
+	return handlers.CORS(options...)
 }
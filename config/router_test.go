@@ -0,0 +1,73 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+func TestAddressRateLimit(t *testing.T) {
+	container := newTestContainer(t, nil)
+	container.Settings.RateLimit.Address = core.RateLimitRule{Requests: 2, Period: time.Hour}
+	router := NewRouter(container)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "invalid token", token: "invalido", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token again", token: "invalido", wantStatus: http.StatusUnauthorized},
+		{name: "address limit reached", token: "invalido", wantStatus: http.StatusTooManyRequests},
+		{name: "without token from the same address", token: "", wantStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, "GET", "/budgets", tt.token, nil, nil)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	container := newTestContainer(t, nil)
+	container.Settings.RateLimit.Address = core.RateLimitRule{Requests: 4, Period: time.Hour}
+	router := NewRouter(container)
+	token := login(t, router)
+
+	tests := []struct {
+		name          string
+		wantStatus    int
+		wantLimit     string
+		wantRemaining string
+	}{
+		{name: "address bucket lower than the group one", wantStatus: http.StatusOK, wantLimit: "4", wantRemaining: "2"},
+		{name: "address bucket again", wantStatus: http.StatusOK, wantLimit: "4", wantRemaining: "1"},
+		{name: "last token of the address", wantStatus: http.StatusOK, wantLimit: "4", wantRemaining: "0"},
+		{name: "address limit reached", wantStatus: http.StatusTooManyRequests, wantLimit: "4", wantRemaining: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, "GET", "/budgets", token, nil, nil)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, se esperaba %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if limit := w.Header().Get("X-RateLimit-Limit"); limit != tt.wantLimit {
+				t.Errorf("X-RateLimit-Limit %q, se esperaba %q", limit, tt.wantLimit)
+			}
+
+			if remaining := w.Header().Get("X-RateLimit-Remaining"); remaining != tt.wantRemaining {
+				t.Errorf("X-RateLimit-Remaining %q, se esperaba %q", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
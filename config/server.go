@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	ExitOk              = 0
+	ExitServerError     = 1
+	ExitShutdownTimeout = 2
+)
+
+// StartApi serves the API until the process receives SIGINT or SIGTERM, then
+// drains in-flight requests within the configured deadline and releases the
+// container resources. It returns the process exit code.
+func StartApi(container *Container) int {
+	settings := container.Settings
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+
+	container.Monitor.Start(monitorCtx)
+
+	server := NewServer(container)
+
+	serverErrors := make(chan error, 1)
+
+	go func() {
+		log.Printf("Servidor escuchando en %s\n", settings.Server.Address)
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
+		log.Println(err.Error())
+		stopMonitor()
+		closeContainer(container)
+		return ExitServerError
+	case <-ctx.Done():
+		stop()
+		log.Println("Senal de apagado recibida, finalizando peticiones en curso")
+	}
+
+	stopMonitor()
+
+	return shutdown(server, container)
+}
+
+func NewServer(container *Container) *http.Server {
+	settings := container.Settings
+
+	return &http.Server{
+		Addr:              settings.Server.Address,
+		Handler:           CorsHandler(settings.Cors)(NewRouter(container)),
+		ReadTimeout:       settings.Server.ReadTimeout,
+		ReadHeaderTimeout: settings.Server.ReadHeaderTimeout,
+		WriteTimeout:      settings.Server.WriteTimeout,
+		IdleTimeout:       settings.Server.IdleTimeout,
+	}
+}
+
+func shutdown(server *http.Server, container *Container) int {
+	ctx, cancel := context.WithTimeout(context.Background(), container.Settings.Server.ShutdownTimeout)
+	defer cancel()
+
+	exitCode := ExitOk
+
+	err := server.Shutdown(ctx)
+
+	if err != nil {
+		log.Println("Las peticiones en curso no finalizaron a tiempo: " + err.Error())
+		exitCode = ExitShutdownTimeout
+	}
+
+	if closeContainer(container) != nil && exitCode == ExitOk {
+		exitCode = ExitServerError
+	}
+
+	if exitCode == ExitOk {
+		log.Println("Servidor detenido correctamente")
+	}
+
+	return exitCode
+}
+
+func closeContainer(container *Container) error {
+	ctx, cancel := context.WithTimeout(context.Background(), container.Settings.Database.ConnectTimeout)
+	defer cancel()
+
+	err := container.Close(ctx)
+
+	if err != nil {
+		log.Println("No se pudieron liberar los recursos: " + err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/lucasbravi2019/arquitectura/storage/memory"
+	"github.com/lucasbravi2019/arquitectura/storage/sqlite"
+)
+
+// OpenStorage builds the repositories of the configured storage driver and
+// registers its health check. The returned closer releases the connection.
+func OpenStorage(settings *core.Settings, monitor *core.HealthMonitor, metrics *core.Metrics) (Repositories, Closer, error) {
+	switch settings.Storage.Driver {
+	case core.StorageDriverMongo:
+		database, err := core.ConnectDatabase(settings.Database, metrics.CommandMonitor(), core.TracingCommandMonitor())
+
+		if err != nil {
+			return Repositories{}, nil, err
+		}
+
+		monitor.Register(core.DatabaseComponent, core.DatabaseHealthCheck(database))
+
+		err = prepareMongo(settings.Database, database)
+
+		ctx, cancel := context.WithTimeout(context.Background(), settings.Database.ConnectTimeout)
+		defer cancel()
+
+		var transactions bool
+
+		if err == nil {
+			transactions, err = core.SupportsTransactions(ctx, database)
+		}
+
+		if err != nil {
+			core.CloseDatabaseConnection(ctx, database)
+			return Repositories{}, nil, fmt.Errorf("no se pudo preparar la base de datos: %w", err)
+		}
+
+		closer := func(ctx context.Context) error {
+			return core.CloseDatabaseConnection(ctx, database)
+		}
+
+		repositories := NewMongoRepositories(database, settings.Database.QueryTimeouts)
+
+		if !transactions {
+			core.Log(ctx, core.LogLevelWarn, "El servidor de base de datos no admite transacciones; las operaciones en cascada se aplican sin ellas", nil)
+			repositories.UnitOfWork = core.NewDirectUnitOfWork()
+		}
+
+		return repositories, closer, nil
+	case core.StorageDriverMemory:
+		store := memory.NewStore()
+
+		monitor.Register(core.DatabaseComponent, store.Ping)
+
+		return NewMemoryRepositories(store), noopCloser, nil
+	case core.StorageDriverSQLite:
+		if settings.Storage.SQLite.Path == "" {
+			return Repositories{}, nil, fmt.Errorf("la ruta de la base de datos sqlite es obligatoria")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), settings.Database.ConnectTimeout)
+		defer cancel()
+
+		db, err := sqlite.Open(ctx, settings.Storage.SQLite.Path)
+
+		if err != nil {
+			return Repositories{}, nil, err
+		}
+
+		err = prepareSQLite(settings.Database, db)
+
+		if err != nil {
+			db.Close()
+			return Repositories{}, nil, fmt.Errorf("no se pudo preparar la base de datos: %w", err)
+		}
+
+		monitor.Register(core.DatabaseComponent, sqlite.HealthCheck(db))
+
+		closer := func(ctx context.Context) error {
+			return db.Close()
+		}
+
+		return NewSQLiteRepositories(db, settings.Database.QueryTimeouts), closer, nil
+	default:
+		return Repositories{}, nil, fmt.Errorf("driver de almacenamiento desconocido: %s", settings.Storage.Driver)
+	}
+}
+
+func NewMemoryRepositories(store *memory.Store) Repositories {
+	return Repositories{
+		UnitOfWork:   memory.NewUnitOfWork(store),
+		Budget:       memory.NewBudgetRepository(store),
+		Material:     memory.NewMaterialRepository(store),
+		Dimension:    memory.NewDimensionRepository(store),
+		Search:       memory.NewSearchRepository(store),
+		User:         memory.NewUserRepository(store),
+		Organization: memory.NewOrganizationRepository(store),
+		ApiKey:       memory.NewApiKeyRepository(store),
+		Audit:        memory.NewAuditRepository(store),
+	}
+}
+
+func NewSQLiteRepositories(db *sql.DB, timeouts core.QueryTimeouts) Repositories {
+	return Repositories{
+		UnitOfWork:   sqlite.NewUnitOfWork(db),
+		Budget:       sqlite.NewBudgetRepository(db, timeouts),
+		Material:     sqlite.NewMaterialRepository(db, timeouts),
+		Dimension:    sqlite.NewDimensionRepository(db, timeouts),
+		Search:       sqlite.NewSearchRepository(db, timeouts),
+		User:         sqlite.NewUserRepository(db, timeouts),
+		Organization: sqlite.NewOrganizationRepository(db, timeouts),
+		ApiKey:       sqlite.NewApiKeyRepository(db, timeouts),
+		Audit:        sqlite.NewAuditRepository(db, timeouts),
+	}
+}
+
+func noopCloser(ctx context.Context) error {
+	return nil
+}
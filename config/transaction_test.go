@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errStorage = errors.New("sin conexion")
+
+// failingBudgetWrites fails the last write of the cascades once failing is
+// set, after the material and dimension writes of the unit already ran.
+type failingBudgetWrites struct {
+	budget.BudgetRepository
+	failing *bool
+}
+
+func (r failingBudgetWrites) UpdateBudgetByIdPrice(ctx context.Context, budgetId *primitive.ObjectID) error {
+	if *r.failing {
+		return errStorage
+	}
+
+	return r.BudgetRepository.UpdateBudgetByIdPrice(ctx, budgetId)
+}
+
+func (r failingBudgetWrites) UpdateMaterialsPrice(ctx context.Context, dimensionId *primitive.ObjectID, updated budget.BudgetDTO) error {
+	if *r.failing {
+		return errStorage
+	}
+
+	return r.BudgetRepository.UpdateMaterialsPrice(ctx, dimensionId, updated)
+}
+
+type created struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func TestUnitOfWorkRollback(t *testing.T) {
+	drivers := []string{core.StorageDriverMemory, core.StorageDriverSQLite, core.StorageDriverMongo}
+
+	tests := []struct {
+		name   string
+		method string
+		path   func(dimensionId primitive.ObjectID) string
+		body   any
+	}{
+		{
+			name:   "change material price",
+			method: "PUT",
+			path:   func(dimensionId primitive.ObjectID) string { return "/materials/" + dimensionId.Hex() + "/price" },
+			body:   material.MaterialDimensionPriceDTO{Price: 25},
+		},
+		{
+			name:   "delete dimension",
+			method: "DELETE",
+			path:   func(dimensionId primitive.ObjectID) string { return "/dimensions/" + dimensionId.Hex() },
+		},
+	}
+
+	for _, driver := range drivers {
+		for _, tt := range tests {
+			t.Run(driver+"/"+tt.name, func(t *testing.T) {
+				failing := false
+
+				container := newStorageContainer(t, driver, func(repositories *Repositories) {
+					repositories.Budget = failingBudgetWrites{BudgetRepository: repositories.Budget, failing: &failing}
+				})
+				router := NewRouter(container)
+				token := login(t, router)
+
+				dimensionId, materialId, budgetId := createBudgetLine(t, router, token)
+
+				ctx := context.Background()
+				dimensionBefore := container.Repositories.Dimension.GetDimensionById(ctx, &dimensionId)
+				materialBefore := container.Repositories.Material.FindMaterialByOID(ctx, &materialId)
+				budgetBefore := container.Repositories.Budget.FindBudgetByOID(ctx, &budgetId)
+				entriesBefore := countAuditEntries(t, container)
+
+				failing = true
+
+				w := serve(router, tt.method, tt.path(dimensionId), token, nil, tt.body)
+
+				if w.Code != http.StatusInternalServerError {
+					t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusInternalServerError, w.Body.String())
+				}
+
+				if found := container.Repositories.Dimension.GetDimensionById(ctx, &dimensionId); !equalJSON(t, found, dimensionBefore) {
+					t.Errorf("la dimension cambio: %+v, antes %+v", found, dimensionBefore)
+				}
+
+				if found := container.Repositories.Material.FindMaterialByOID(ctx, &materialId); !equalJSON(t, found, materialBefore) {
+					t.Errorf("el material cambio: %+v, antes %+v", found, materialBefore)
+				}
+
+				if found := container.Repositories.Budget.FindBudgetByOID(ctx, &budgetId); !equalJSON(t, found, budgetBefore) {
+					t.Errorf("el presupuesto cambio: %+v, antes %+v", found, budgetBefore)
+				}
+
+				if entries := countAuditEntries(t, container); entries != entriesBefore {
+					t.Errorf("se registraron %d entradas de auditoria", entries-entriesBefore)
+				}
+			})
+		}
+	}
+}
+
+// createBudgetLine creates a dimension, a material with it and a budget with
+// a line of the material.
+func createBudgetLine(t *testing.T, router http.Handler, token string) (primitive.ObjectID, primitive.ObjectID, primitive.ObjectID) {
+	t.Helper()
+
+	var dimensionCreated, materialCreated, budgetCreated created
+
+	steps := []struct {
+		path   func() string
+		method string
+		body   any
+		target *created
+	}{
+		{
+			method: "POST",
+			path:   func() string { return "/dimensions" },
+			body:   dimension.Dimension{Metric: "KG", Quantity: 1},
+			target: &dimensionCreated,
+		},
+		{
+			method: "POST",
+			path:   func() string { return "/materials" },
+			body:   material.MaterialNameDTO{Name: "Arena"},
+			target: &materialCreated,
+		},
+		{
+			method: "PUT",
+			path: func() string {
+				return "/dimensions/" + dimensionCreated.ID.Hex() + "/materials/" + materialCreated.ID.Hex()
+			},
+			body: material.MaterialDimensionPriceDTO{Price: 10},
+		},
+		{
+			method: "POST",
+			path:   func() string { return "/budgets" },
+			body:   budget.BudgetNameDTO{Name: "Casa"},
+			target: &budgetCreated,
+		},
+		{
+			method: "PUT",
+			path: func() string {
+				return "/materials/" + materialCreated.ID.Hex() + "/budgets/" + budgetCreated.ID.Hex()
+			},
+			body: map[string]any{"metric": "1 KG", "quantity": 2},
+		},
+	}
+
+	for _, step := range steps {
+		w := serve(router, step.method, step.path(), token, nil, step.body)
+
+		if w.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s: status %d: %s", step.method, step.path(), w.Code, w.Body.String())
+		}
+
+		if step.target != nil {
+			decodeBody(t, w, step.target)
+		}
+	}
+
+	return dimensionCreated.ID, materialCreated.ID, budgetCreated.ID
+}
+
+func countAuditEntries(t *testing.T, container *Container) int64 {
+	t.Helper()
+
+	_, total, err := container.Repositories.Audit.FindEntries(context.Background(), audit.EntryFilter{}, core.PageRequest{Limit: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return total
+}
+
+func equalJSON(t *testing.T, a any, b any) bool {
+	t.Helper()
+
+	encodedA, err := json.Marshal(a)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedB, err := json.Marshal(b)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// CreateAdminUser creates the account configured in auth.admin in the default
+// organization, with the admin and superadmin roles, when its username is
+// free. Restarts leave the existing account and its roles as they are.
+// Nothing is created when no username is configured.
+func CreateAdminUser(container *Container) error {
+	admin := container.Settings.Auth.Admin
+
+	if admin.Username == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), container.Settings.Database.ConnectTimeout)
+	defer cancel()
+
+	created, err := container.Services.User.EnsureUser(ctx, admin.Username, admin.Password, []string{core.RoleAdmin, core.RoleSuperAdmin})
+
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el usuario administrador: %w", err)
+	}
+
+	if created {
+		log.Printf("Usuario administrador %s creado\n", admin.Username)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateAdminUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		existing  func(ctx context.Context, users user.UserRepository) error
+		wantErr   error
+		wantRoles []string
+	}{
+		{
+			name:     "revoked roles are not granted again",
+			username: testAdminUsername,
+			existing: func(ctx context.Context, users user.UserRepository) error {
+				admin := users.FindUserByUsername(ctx, testAdminUsername)
+				return users.UpdateUserRoles(ctx, &admin.ID, []string{core.RoleAdmin})
+			},
+			wantRoles: []string{core.RoleAdmin},
+		},
+		{
+			name:     "username of another organization",
+			username: "otro",
+			existing: func(ctx context.Context, users user.UserRepository) error {
+				return users.CreateUser(ctx, &user.User{Username: "otro", OrganizationID: primitive.NewObjectID(), Roles: []string{}})
+			},
+			wantErr: user.ErrDuplicateUsername,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := newTestContainer(t, nil)
+			users := container.Repositories.User
+			ctx := context.Background()
+
+			if err := tt.existing(ctx, users); err != nil {
+				t.Fatal(err)
+			}
+
+			container.Settings.Auth.Admin.Username = tt.username
+
+			err := CreateAdminUser(container)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+
+			if tt.wantRoles == nil {
+				return
+			}
+
+			if roles := users.FindUserByUsername(ctx, tt.username).Roles; !reflect.DeepEqual(roles, tt.wantRoles) {
+				t.Errorf("roles = %v, se esperaba %v", roles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestUpdateSuperAdminRoles(t *testing.T) {
+	container := newTestContainer(t, nil)
+	router := NewRouter(container)
+	token := login(t, router)
+
+	w := serve(router, "POST", "/users", token, nil, user.CreateUserDTO{Username: "lucia", Password: testAdminPassword, Roles: []string{core.RoleAdmin}})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /users: status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, "POST", "/auth/login", "", nil, user.LoginDTO{Username: "lucia", Password: testAdminPassword})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var tokens user.TokenDTO
+	decodeBody(t, w, &tokens)
+
+	ctx := context.Background()
+	superAdmin := container.Repositories.User.FindUserByUsername(ctx, testAdminUsername)
+
+	w = serve(router, "PUT", "/users/"+superAdmin.ID.Hex()+"/roles", tokens.AccessToken, nil, user.UserRolesDTO{Roles: []string{core.RoleAdmin}})
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+	}
+
+	if roles := container.Repositories.User.FindUserByUsername(ctx, testAdminUsername).Roles; !reflect.DeepEqual(roles, superAdmin.Roles) {
+		t.Errorf("roles = %v, se esperaba %v", roles, superAdmin.Roles)
+	}
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// ApiKeyHeader carries the API keys of integrations, which authenticate
+// without a user session.
+const ApiKeyHeader = "X-API-Key"
+
+// Principal identifies who makes the request. The authentication middleware
+// stores it in the request context, where services read it with
+// PrincipalFromContext.
+type Principal struct {
+	UserID         primitive.ObjectID
+	Username       string
+	OrganizationID primitive.ObjectID
+	Roles          []string
+	// API keys have no user nor roles; their scopes are the permissions they
+	// were issued with.
+	ApiKeyID primitive.ObjectID
+	Scopes   []string
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns nil for anonymous requests, e.g. when
+// authentication is disabled.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// ApiKeyVerifier resolves the principal of an API key. It fails for unknown,
+// malformed and revoked keys.
+type ApiKeyVerifier interface {
+	VerifyApiKey(ctx context.Context, key string) (*Principal, error)
+}
+
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	Username     string   `json:"username"`
+	Organization string   `json:"org,omitempty"`
+	Roles        []string `json:"roles"`
+	Type         string   `json:"typ"`
+}
+
+// TokenIssuer signs and verifies the access and refresh tokens. Refresh tokens
+// live longer and are only accepted to obtain a new pair, never as access
+// tokens. Access tokens carry the roles, so role changes apply once the
+// client refreshes its tokens.
+type TokenIssuer struct {
+	settings AuthSettings
+	method   jwt.SigningMethod
+}
+
+func NewTokenIssuer(settings AuthSettings) *TokenIssuer {
+	return &TokenIssuer{
+		settings: settings,
+		method:   jwt.SigningMethodHS256,
+	}
+}
+
+func (t *TokenIssuer) AccessTokenTTL() time.Duration {
+	return t.settings.AccessTokenTTL
+}
+
+func (t *TokenIssuer) Issue(principal Principal, tokenType string) (string, error) {
+	ttl := t.settings.AccessTokenTTL
+
+	if tokenType == TokenTypeRefresh {
+		ttl = t.settings.RefreshTokenTTL
+	}
+
+	now := time.Now()
+
+	claims := TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			Issuer:    t.settings.Issuer,
+			Subject:   principal.UserID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Username:     principal.Username,
+		Organization: principal.OrganizationID.Hex(),
+		Roles:        principal.Roles,
+		Type:         tokenType,
+	}
+
+	return jwt.NewWithClaims(t.method, claims).SignedString([]byte(t.settings.Secret))
+}
+
+// Parse verifies the signature, expiration, issuer and type of the token.
+func (t *TokenIssuer) Parse(token string, tokenType string) (*Principal, error) {
+	claims := &TokenClaims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, func(parsed *jwt.Token) (any, error) {
+		if parsed.Method.Alg() != t.method.Alg() {
+			return nil, fmt.Errorf("algoritmo de firma inesperado: %s", parsed.Method.Alg())
+		}
+
+		return []byte(t.settings.Secret), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(t.settings.Issuer, true) {
+		return nil, errors.New("el token no fue emitido por esta api")
+	}
+
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("se esperaba un token de tipo %s", tokenType)
+	}
+
+	userId, err := primitive.ObjectIDFromHex(claims.Subject)
+
+	if err != nil {
+		return nil, fmt.Errorf("el token no identifica a un usuario: %w", err)
+	}
+
+	principal := &Principal{UserID: userId, Username: claims.Username, Roles: claims.Roles}
+
+	// tokens issued before organizations existed carry none
+	if claims.Organization != "" {
+		principal.OrganizationID, err = primitive.ObjectIDFromHex(claims.Organization)
+
+		if err != nil {
+			return nil, fmt.Errorf("el token no identifica a una organizacion: %w", err)
+		}
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import "sort"
+
+// Permissions follow the action:resource form, which API key scopes reuse.
+const (
+	PermissionReadBudgets      = "read:budgets"
+	PermissionWriteBudgets     = "write:budgets"
+	PermissionReadMaterials    = "read:materials"
+	PermissionWriteMaterials   = "write:materials"
+	PermissionWritePrices      = "write:prices"
+	PermissionReadDimensions   = "read:dimensions"
+	PermissionWriteDimensions  = "write:dimensions"
+	PermissionDeleteDimensions = "delete:dimensions"
+	PermissionManageUsers      = "admin:users"
+	PermissionManageApiKeys    = "admin:apikeys"
+	PermissionReadAudit        = "read:audit"
+	// PermissionManageOrganizations spans every organization of the
+	// instance, unlike the rest, which apply within the principal's own.
+	PermissionManageOrganizations = "admin:organizations"
+)
+
+const (
+	RoleAdmin      = "admin"
+	RolePurchasing = "purchasing"
+	RoleEstimator  = "estimator"
+	RoleViewer     = "viewer"
+	RoleSuperAdmin = "superadmin"
+)
+
+var readPermissions = []string{PermissionReadBudgets, PermissionReadMaterials, PermissionReadDimensions}
+
+// RolePermissions grants every role its permissions. Purchasing admins own the
+// material catalog and its prices, estimators edit budgets and viewers only
+// read. Superadmins create organizations and hold no permission within them.
+var RolePermissions = map[string][]string{
+	RoleAdmin: append([]string{
+		PermissionWriteBudgets,
+		PermissionWriteMaterials,
+		PermissionWritePrices,
+		PermissionWriteDimensions,
+		PermissionDeleteDimensions,
+		PermissionManageUsers,
+		PermissionManageApiKeys,
+		PermissionReadAudit,
+	}, readPermissions...),
+	RolePurchasing: append([]string{
+		PermissionWriteMaterials,
+		PermissionWritePrices,
+		PermissionWriteDimensions,
+		PermissionDeleteDimensions,
+	}, readPermissions...),
+	RoleEstimator:  append([]string{PermissionWriteBudgets}, readPermissions...),
+	RoleViewer:     readPermissions,
+	RoleSuperAdmin: {PermissionManageOrganizations},
+}
+
+// Roles lists the role names, sorted.
+func Roles() []string {
+	roles := make([]string, 0, len(RolePermissions))
+
+	for role := range RolePermissions {
+		roles = append(roles, role)
+	}
+
+	sort.Strings(roles)
+
+	return roles
+}
+
+// HasPermission reports whether any role or scope of the principal grants the
+// permission.
+func (p *Principal) HasPermission(permission string) bool {
+	if containsString(p.Scopes, permission) {
+		return true
+	}
+
+	for _, role := range p.Roles {
+		if containsString(RolePermissions[role], permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return containsString(p.Roles, role)
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+// VersionField holds the version of budgets, materials and dimensions. It
+// starts at 1 and every write increments it, so a write conditioned on the
+// version read fails if the entity changed in between.
+const VersionField = "version"
+
+// ErrVersionConflict is returned by the conditional writes of the
+// repositories when the entity no longer has the expected version.
+var ErrVersionConflict = errors.New("la entidad cambio desde que fue leida")
+
+// ETag returns the entity tag of a version.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag sends the entity tag of the version returned in the body.
+func SetETag(w http.ResponseWriter, version int64) {
+	w.Header().Set(ETagHeader, ETag(version))
+}
+
+// CheckIfMatch compares the If-Match header, when sent, with the current
+// version of the entity. Tags are compared strongly, so weak tags never match.
+func CheckIfMatch(r *http.Request, version int64, entity string) *ApiError {
+	header := r.Header.Get(IfMatchHeader)
+
+	if header == "" {
+		return nil
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" || tag == ETag(version) {
+			return nil
+		}
+	}
+
+	return NewPreconditionFailedError(entity)
+}
+
+// AsWriteError is AsApiError for conditional writes, turning a version
+// conflict into a 412 for the entity.
+func AsWriteError(err error, entity string) *ApiError {
+	if errors.Is(err, ErrVersionConflict) {
+		return NewPreconditionFailedError(entity)
+	}
+
+	return AsApiError(err)
+}
+
+// WithVersion conditions a Mongo filter on the version of the entity.
+func WithVersion(filter bson.M, version int64) bson.M {
+	filter[VersionField] = version
+	return filter
+}
+
+// IncrementVersion is the $inc of every Mongo update of a versioned entity.
+func IncrementVersion() bson.M {
+	return bson.M{VersionField: 1}
+}
+
+// BackfillVersions sets the first version on the documents written before
+// versions existed.
+func BackfillVersions(collections ...string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, collection := range collections {
+			_, err := db.Collection(collection).UpdateMany(ctx,
+				bson.M{VersionField: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{VersionField: int64(1)}})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
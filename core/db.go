@@ -2,35 +2,66 @@ package core
 
 import (
 	"context"
-	"log"
+	"fmt"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var databaseConnection *mongo.Database
+func ConnectDatabase(settings DatabaseSettings, monitors ...*event.CommandMonitor) (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), settings.ConnectTimeout)
+	defer cancel()
 
-func GetDatabaseConnection() *mongo.Database {
-	if databaseConnection == nil {
-		conn, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://localhost:27017"))
+	conn, err := mongo.Connect(ctx, options.Client().ApplyURI(settings.URI).SetMonitor(combineCommandMonitors(monitors)))
 
-		if err != nil {
-			log.Fatal("La conexion a la base de datos no pudo realizarse")
-		}
-
-		err = conn.Ping(context.TODO(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("la conexion a la base de datos no pudo realizarse: %w", err)
+	}
 
-		if err != nil {
-			log.Fatal("La conexion a la base de datos no responde")
-		}
+	err = conn.Ping(ctx, nil)
 
-		databaseConnection = conn.Database("arquitectura")
+	if err != nil {
+		return nil, fmt.Errorf("la conexion a la base de datos no responde: %w", err)
 	}
 
-	return databaseConnection
+	return conn.Database(settings.Name), nil
+}
+
+func CloseDatabaseConnection(ctx context.Context, db *mongo.Database) error {
+	return db.Client().Disconnect(ctx)
 }
 
-func CheckDatabaseHealth() error {
-	log.Println("Checking database health")
-	return GetDatabaseConnection().Client().Ping(context.TODO(), nil)
+func DatabaseHealthCheck(db *mongo.Database) HealthCheck {
+	return func(ctx context.Context) error {
+		return db.Client().Ping(ctx, nil)
+	}
+}
+
+// combineCommandMonitors fans the driver events out to every monitor, since
+// the client accepts only one.
+func combineCommandMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
 }
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	ErrorCodeInvalidBody  = "INVALID_BODY"
+	ErrorCodeBodyTooLarge = "BODY_TOO_LARGE"
+	ErrorCodeValidation   = "VALIDATION_ERROR"
+	ErrorCodeInvalidId    = "INVALID_ID"
+	ErrorCodeInvalidQuery = "INVALID_QUERY"
+	ErrorCodeNotFound     = "NOT_FOUND"
+	ErrorCodeUnauthorized = "UNAUTHORIZED"
+	ErrorCodeForbidden    = "FORBIDDEN"
+	ErrorCodeConflict     = "CONFLICT"
+	ErrorCodeRateLimited  = "RATE_LIMITED"
+	ErrorCodeInternal     = "INTERNAL_ERROR"
+	ErrorCodeUnavailable  = "SERVICE_UNAVAILABLE"
+	// a conditional write was sent with a stale If-Match, or without one
+	// when server.requireIfMatch is set
+	ErrorCodePreconditionFailed   = "PRECONDITION_FAILED"
+	ErrorCodePreconditionRequired = "PRECONDITION_REQUIRED"
+	// an Idempotency-Key was reused for another request, or while the first
+	// request with it still runs
+	ErrorCodeInvalidIdempotencyKey  = "INVALID_IDEMPOTENCY_KEY"
+	ErrorCodeIdempotencyKeyMismatch = "IDEMPOTENCY_KEY_MISMATCH"
+	ErrorCodeIdempotencyKeyInUse    = "IDEMPOTENCY_KEY_IN_USE"
+)
+
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type ApiError struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Status  int              `json:"status"`
+	Fields  []FieldViolation `json:"fields,omitempty"`
+	cause   error
+}
+
+func (e *ApiError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Code, e.Message, e.cause.Error())
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *ApiError) Unwrap() error {
+	return e.cause
+}
+
+func NewApiError(status int, code string, message string) *ApiError {
+	return &ApiError{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+func NewInvalidBodyError(cause error) *ApiError {
+	apiError := NewApiError(http.StatusBadRequest, ErrorCodeInvalidBody, "El cuerpo de la peticion no es un JSON valido")
+	apiError.cause = cause
+	return apiError
+}
+
+func NewBodyTooLargeError(limit int) *ApiError {
+	return NewApiError(http.StatusRequestEntityTooLarge, ErrorCodeBodyTooLarge, fmt.Sprintf("El cuerpo de la peticion no puede superar los %d bytes", limit))
+}
+
+func NewInvalidIdError(param string) *ApiError {
+	return NewApiError(http.StatusBadRequest, ErrorCodeInvalidId, fmt.Sprintf("El parametro %s no es un identificador valido", param))
+}
+
+func NewInvalidQueryError(param string, message string) *ApiError {
+	apiError := NewApiError(http.StatusBadRequest, ErrorCodeInvalidQuery, fmt.Sprintf("El parametro de consulta %s no es valido", param))
+	apiError.Fields = []FieldViolation{{Field: param, Rule: "query", Message: message}}
+	return apiError
+}
+
+func NewNotFoundError(entity string) *ApiError {
+	return NewApiError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("No se encontro el recurso %s", entity))
+}
+
+func NewUnauthorizedError(message string) *ApiError {
+	return NewApiError(http.StatusUnauthorized, ErrorCodeUnauthorized, message)
+}
+
+func NewForbiddenError(permission string) *ApiError {
+	return NewApiError(http.StatusForbidden, ErrorCodeForbidden, fmt.Sprintf("No tiene permiso para realizar la operacion, se requiere %s", permission))
+}
+
+func NewConflictError(message string) *ApiError {
+	return NewApiError(http.StatusConflict, ErrorCodeConflict, message)
+}
+
+func NewPreconditionFailedError(entity string) *ApiError {
+	return NewApiError(http.StatusPreconditionFailed, ErrorCodePreconditionFailed, fmt.Sprintf("El recurso %s fue modificado por otra peticion, vuelva a leerlo", entity))
+}
+
+func NewPreconditionRequiredError() *ApiError {
+	return NewApiError(http.StatusPreconditionRequired, ErrorCodePreconditionRequired, fmt.Sprintf("La peticion debe incluir el encabezado %s", IfMatchHeader))
+}
+
+func NewInvalidIdempotencyKeyError() *ApiError {
+	return NewApiError(http.StatusBadRequest, ErrorCodeInvalidIdempotencyKey,
+		fmt.Sprintf("El encabezado %s no puede superar los %d caracteres", IdempotencyKeyHeader, MaxIdempotencyKeyLength))
+}
+
+func NewIdempotencyKeyMismatchError() *ApiError {
+	return NewApiError(http.StatusUnprocessableEntity, ErrorCodeIdempotencyKeyMismatch,
+		fmt.Sprintf("El encabezado %s ya se uso con otra peticion", IdempotencyKeyHeader))
+}
+
+func NewIdempotencyKeyInUseError() *ApiError {
+	return NewApiError(http.StatusConflict, ErrorCodeIdempotencyKeyInUse,
+		fmt.Sprintf("Otra peticion con el mismo %s sigue en curso, reintente en unos segundos", IdempotencyKeyHeader))
+}
+
+func NewRateLimitedError(retryAfter int) *ApiError {
+	return NewApiError(http.StatusTooManyRequests, ErrorCodeRateLimited, fmt.Sprintf("Demasiadas peticiones, reintente en %d segundos", retryAfter))
+}
+
+func NewInternalError(cause error) *ApiError {
+	apiError := NewApiError(http.StatusInternalServerError, ErrorCodeInternal, "Ocurrio un error al realizar la operacion")
+	apiError.cause = cause
+	return apiError
+}
+
+func NewUnavailableError(component string) *ApiError {
+	return NewApiError(http.StatusServiceUnavailable, ErrorCodeUnavailable, fmt.Sprintf("El componente %s no esta disponible", component))
+}
+
+func NewValidationError(cause error) *ApiError {
+	apiError := NewApiError(http.StatusBadRequest, ErrorCodeValidation, "La peticion contiene campos invalidos")
+	apiError.cause = cause
+
+	var validationErrors validator.ValidationErrors
+
+	if errors.As(cause, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			apiError.Fields = append(apiError.Fields, newFieldViolation(fieldError))
+		}
+	}
+
+	return apiError
+}
+
+// WithMessage returns a copy of the error with a more specific human message,
+// keeping its code and status.
+func (e *ApiError) WithMessage(message string) *ApiError {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// AsApiError converts any error into an ApiError, treating unknown errors as
+// internal failures.
+func AsApiError(err error) *ApiError {
+	var apiError *ApiError
+
+	if errors.As(err, &apiError) {
+		return apiError
+	}
+
+	return NewInternalError(err)
+}
+
+func newFieldViolation(fieldError validator.FieldError) FieldViolation {
+	return FieldViolation{
+		Field:   fieldPath(fieldError),
+		Rule:    fieldError.Tag(),
+		Param:   fieldError.Param(),
+		Message: fieldMessage(fieldError),
+	}
+}
+
+// fieldPath strips the root struct name from the namespace so the frontend
+// receives the same path it sent, e.g. "dimension.metric".
+func fieldPath(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+
+	if index := strings.Index(namespace, "."); index >= 0 {
+		return namespace[index+1:]
+	}
+
+	return fieldError.Field()
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "El campo es obligatorio"
+	case "min", "gte":
+		return fmt.Sprintf("El valor debe ser mayor o igual a %s", fieldError.Param())
+	case "max", "lte":
+		return fmt.Sprintf("El valor debe ser menor o igual a %s", fieldError.Param())
+	case "gt":
+		return fmt.Sprintf("El valor debe ser mayor a %s", fieldError.Param())
+	case "lt":
+		return fmt.Sprintf("El valor debe ser menor a %s", fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("El valor debe ser uno de: %s", fieldError.Param())
+	default:
+		return fmt.Sprintf("El valor no cumple la regla %s", fieldError.Tag())
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
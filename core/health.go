@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusUp   = "UP"
+	HealthStatusDown = "DOWN"
+
+	DatabaseComponent = "database"
+)
+
+type HealthCheck func(ctx context.Context) error
+
+type ComponentHealth struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Latency   string    `json:"latency"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// HealthMonitor runs the registered checks in the background and caches their
+// last result, so request paths can ask for the status without extra round
+// trips to the dependencies.
+type HealthMonitor struct {
+	mutex    sync.RWMutex
+	checks   map[string]HealthCheck
+	statuses map[string]ComponentHealth
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewHealthMonitor(settings HealthSettings) *HealthMonitor {
+	return &HealthMonitor{
+		checks:   map[string]HealthCheck{},
+		statuses: map[string]ComponentHealth{},
+		interval: settings.CheckInterval,
+		timeout:  settings.CheckTimeout,
+	}
+}
+
+func (m *HealthMonitor) Register(component string, check HealthCheck) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.checks[component] = check
+}
+
+// Start runs every check once synchronously and then keeps refreshing them
+// until the context is cancelled.
+func (m *HealthMonitor) Start(ctx context.Context) {
+	m.runChecks(ctx)
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.runChecks(ctx)
+			}
+		}
+	}()
+}
+
+func (m *HealthMonitor) IsUp(component string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status, ok := m.statuses[component]
+
+	return ok && status.Status == HealthStatusUp
+}
+
+func (m *HealthMonitor) Report() HealthReport {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	report := HealthReport{
+		Status:     HealthStatusUp,
+		Components: map[string]ComponentHealth{},
+	}
+
+	for component := range m.checks {
+		status, ok := m.statuses[component]
+
+		if !ok {
+			status = ComponentHealth{Status: HealthStatusDown, Error: "pendiente de verificacion"}
+		}
+
+		if status.Status != HealthStatusUp {
+			report.Status = HealthStatusDown
+		}
+
+		report.Components[component] = status
+	}
+
+	return report
+}
+
+func (m *HealthMonitor) runChecks(ctx context.Context) {
+	m.mutex.RLock()
+	checks := make(map[string]HealthCheck, len(m.checks))
+	for component, check := range m.checks {
+		checks[component] = check
+	}
+	m.mutex.RUnlock()
+
+	for component, check := range checks {
+		status := m.runCheck(ctx, check)
+
+		m.mutex.Lock()
+		previous, known := m.statuses[component]
+		m.statuses[component] = status
+		m.mutex.Unlock()
+
+		if !known || previous.Status != status.Status {
+			log.Printf("Componente %s: %s %s\n", component, status.Status, status.Error)
+		}
+	}
+}
+
+func (m *HealthMonitor) runCheck(ctx context.Context, check HealthCheck) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+
+	status := ComponentHealth{
+		Status:    HealthStatusUp,
+		Latency:   time.Since(start).String(),
+		CheckedAt: start.UTC(),
+	}
+
+	if err != nil {
+		status.Status = HealthStatusDown
+		status.Error = err.Error()
+	}
+
+	return status
+}
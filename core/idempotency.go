@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the responses replayed from an earlier
+	// request with the same key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength  = 255
+)
+
+type IdempotencySettings struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTL is how long the first response of a key is replayed to retries.
+	TTL time.Duration `yaml:"ttl" json:"ttl" validate:"gt=0"`
+	// MaxBodyBytes limits the bodies read to fingerprint the requests; larger
+	// ones get a 413.
+	MaxBodyBytes int `yaml:"maxBodyBytes" json:"maxBodyBytes" validate:"gt=0"`
+}
+
+// IdempotentResponse is the response stored for a key, replayed as it was
+// written.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyRecord is the request that first used a key. Fingerprint
+// identifies its method, path and body; Response is nil while it runs.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Response    *IdempotentResponse
+}
+
+// IdempotencyStore keeps the records. The in-process one suits a single
+// instance; clusters plug in a shared implementation, like RateLimitStore.
+type IdempotencyStore interface {
+	// Start reserves the key for the request with the fingerprint, for ttl.
+	// When the key is already reserved it returns the earlier record instead,
+	// and the request must not run.
+	Start(ctx context.Context, key string, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+	// Finish stores the response of the request that reserved the key.
+	Finish(ctx context.Context, key string, response IdempotentResponse) error
+	// Release forgets the key, so a retry runs the request again.
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+type memoryIdempotencyStore struct {
+	mutex     sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries:   map[string]*idempotencyEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryIdempotencyStore) Start(ctx context.Context, key string, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		found := entry.record
+		return &found, nil
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		record:  IdempotencyRecord{Fingerprint: fingerprint},
+		expires: now.Add(ttl),
+	}
+
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Finish(ctx context.Context, key string, response IdempotentResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		entry.record.Response = &response
+	}
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastSweep = now
+}
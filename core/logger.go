@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+type LogFields map[string]any
+
+type requestIdKey struct{}
+
+var logOutput = struct {
+	sync.Mutex
+	writer io.Writer
+}{writer: os.Stderr}
+
+func SetLogOutput(writer io.Writer) {
+	logOutput.Lock()
+	defer logOutput.Unlock()
+
+	logOutput.writer = writer
+}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// Log writes one JSON line with the given fields, tagged with the request and
+// trace IDs found in the context, if any.
+func Log(ctx context.Context, level string, message string, fields LogFields) {
+	entry := LogFields{}
+
+	for key, value := range fields {
+		entry[key] = value
+	}
+
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = message
+
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		entry["request_id"] = requestId
+	}
+
+	if traceId := TraceIdFromContext(ctx); traceId != "" {
+		entry["trace_id"] = traceId
+	}
+
+	line, err := json.Marshal(entry)
+
+	if err != nil {
+		line, _ = json.Marshal(LogFields{"level": LogLevelError, "msg": "log entry could not be encoded: " + err.Error()})
+	}
+
+	logOutput.Lock()
+	defer logOutput.Unlock()
+
+	logOutput.writer.Write(append(line, '\n'))
+}
+
+func LogInfo(ctx context.Context, message string) {
+	Log(ctx, LogLevelInfo, message, nil)
+}
+
+func LogError(ctx context.Context, err error) {
+	Log(ctx, LogLevelError, err.Error(), nil)
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Metrics groups every Prometheus collector of the API in its own registry,
+// so several instances can live in the same process.
+type Metrics struct {
+	registry               *prometheus.Registry
+	httpRequests           *prometheus.CounterVec
+	httpDuration           *prometheus.HistogramVec
+	httpInFlight           prometheus.Gauge
+	databaseDuration       *prometheus.HistogramVec
+	databaseErrors         *prometheus.CounterVec
+	budgetsCreated         prometheus.Counter
+	materialPriceChanges   prometheus.Counter
+	budgetPricesPropagated prometheus.Counter
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests processed, by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency, by route template and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+		databaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongo_operation_duration_seconds",
+			Help:    "Mongo command latency, by repository and repository method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"repository", "operation"}),
+		databaseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongo_operation_errors_total",
+			Help: "Failed Mongo commands, by repository and repository method.",
+		}, []string{"repository", "operation"}),
+		budgetsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "budgets_created_total",
+			Help: "Budgets created.",
+		}),
+		materialPriceChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "material_price_changes_total",
+			Help: "Material dimension price changes.",
+		}),
+		budgetPricesPropagated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "budget_price_propagations_total",
+			Help: "Budgets repriced because of a material price change.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.databaseDuration,
+		m.databaseErrors,
+		m.budgetsCreated,
+		m.materialPriceChanges,
+		m.budgetPricesPropagated,
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// TrackRequest marks a request as in flight and returns the function that
+// records its outcome.
+func (m *Metrics) TrackRequest(route string, method string) func(status int) {
+	start := time.Now()
+	m.httpInFlight.Inc()
+
+	return func(status int) {
+		m.httpInFlight.Dec()
+		m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) BudgetCreated() {
+	m.budgetsCreated.Inc()
+}
+
+func (m *Metrics) MaterialPriceChanged() {
+	m.materialPriceChanges.Inc()
+}
+
+func (m *Metrics) BudgetPricesPropagated(budgets int) {
+	m.budgetPricesPropagated.Add(float64(budgets))
+}
+
+// CommandMonitor measures every Mongo command issued by a repository method,
+// labelled with the operation stored in the context by WithQueryTimeout.
+func (m *Metrics) CommandMonitor() *event.CommandMonitor {
+	observe := func(ctx context.Context, durationNanos int64, failed bool) {
+		repository, operation, found := strings.Cut(OperationFromContext(ctx), ".")
+
+		if !found {
+			return
+		}
+
+		m.databaseDuration.WithLabelValues(repository, operation).Observe(time.Duration(durationNanos).Seconds())
+
+		if failed {
+			m.databaseErrors.WithLabelValues(repository, operation).Inc()
+		}
+	}
+
+	return &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			observe(ctx, e.DurationNanos, false)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			observe(ctx, e.DurationNanos, true)
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationsCollection records the applied migrations, one document per
+// version.
+const MigrationsCollection = "schema_migrations"
+
+// Migration changes the schema or the data of a storage once. Migrations run
+// in version order and are never edited after a release; a later change is a
+// new migration. A migration interrupted before being recorded runs again on
+// the next start, so Up must be idempotent.
+type Migration[DB any] struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db DB) error
+}
+
+type AppliedMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// MigrationHistory stores the migrations applied to a storage.
+type MigrationHistory interface {
+	AppliedMigrations(ctx context.Context) ([]AppliedMigration, error)
+	RecordMigration(ctx context.Context, migration AppliedMigration) error
+}
+
+// PendingMigrations returns the migrations missing from the history, in
+// version order.
+func PendingMigrations[DB any](ctx context.Context, history MigrationHistory, migrations []Migration[DB]) ([]Migration[DB], error) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("las migraciones deben tener versiones crecientes: %d despues de %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+
+	applied, err := history.AppliedMigrations(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int]bool{}
+
+	for _, migration := range applied {
+		done[migration.Version] = true
+
+		if len(migrations) > 0 && migration.Version > migrations[len(migrations)-1].Version {
+			Log(ctx, LogLevelWarn, "La base de datos tiene una migracion desconocida por esta version", LogFields{
+				"version": migration.Version,
+				"name":    migration.Name,
+			})
+		}
+	}
+
+	pending := []Migration[DB]{}
+
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// RunMigrations applies the pending migrations in order, recording each one
+// after it succeeds, and returns how many it applied.
+func RunMigrations[DB any](ctx context.Context, db DB, history MigrationHistory, migrations []Migration[DB]) (int, error) {
+	pending, err := PendingMigrations(ctx, history, migrations)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, migration := range pending {
+		start := time.Now()
+
+		err = migration.Up(ctx, db)
+
+		if err != nil {
+			return i, fmt.Errorf("la migracion %d (%s) fallo: %w", migration.Version, migration.Name, err)
+		}
+
+		err = history.RecordMigration(ctx, AppliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC().Truncate(time.Millisecond),
+		})
+
+		if err != nil {
+			return i, fmt.Errorf("no se pudo registrar la migracion %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		Log(ctx, LogLevelInfo, "Migracion aplicada", LogFields{
+			"version":  migration.Version,
+			"name":     migration.Name,
+			"duration": time.Since(start).String(),
+		})
+	}
+
+	return len(pending), nil
+}
+
+type mongoMigrationHistory struct {
+	db *mongo.Collection
+}
+
+func NewMongoMigrationHistory(db *mongo.Database) MigrationHistory {
+	return &mongoMigrationHistory{
+		db: db.Collection(MigrationsCollection),
+	}
+}
+
+func (h *mongoMigrationHistory) AppliedMigrations(ctx context.Context) ([]AppliedMigration, error) {
+	cursor, err := h.db.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []AppliedMigration = []AppliedMigration{}
+
+	err = cursor.All(ctx, &applied)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func (h *mongoMigrationHistory) RecordMigration(ctx context.Context, migration AppliedMigration) error {
+	_, err := h.db.InsertOne(ctx, migration)
+
+	// another instance starting at the same time applied it too
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
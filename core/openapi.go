@@ -0,0 +1,469 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const openApiVersion = "3.0.3"
+
+const objectIdPattern = "^[0-9a-fA-F]{24}$"
+
+const (
+	bearerSecurityScheme = "bearerAuth"
+	apiKeySecurityScheme = "apiKeyAuth"
+)
+
+type OpenApiDocument struct {
+	OpenApi    string                                  `json:"openapi"`
+	Info       OpenApiInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenApiOperation `json:"paths"`
+	Components OpenApiComponents                       `json:"components"`
+}
+
+type OpenApiInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenApiComponents struct {
+	Schemas         map[string]*OpenApiSchema         `json:"schemas"`
+	SecuritySchemes map[string]*OpenApiSecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenApiSecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+type OpenApiOperation struct {
+	OperationId string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []OpenApiParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenApiRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenApiResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type OpenApiParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+	Schema      *OpenApiSchema `json:"schema"`
+}
+
+type OpenApiRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenApiMediaType `json:"content"`
+}
+
+type OpenApiResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenApiMediaType `json:"content,omitempty"`
+}
+
+type OpenApiMediaType struct {
+	Schema *OpenApiSchema `json:"schema"`
+}
+
+type OpenApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool                      `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool                      `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Items                *OpenApiSchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenApiSchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenApiSchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+var (
+	objectIdType = reflect.TypeOf(primitive.ObjectID{})
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// NewOpenApiDocument describes the given routes. Schemas are derived from the
+// Request and Response types of each route through their json and validate
+// tags, and every body is wrapped in the Response envelope the API writes.
+func NewOpenApiDocument(title string, version string, groups ...Routes) *OpenApiDocument {
+	document := &OpenApiDocument{
+		OpenApi: openApiVersion,
+		Info: OpenApiInfo{
+			Title:   title,
+			Version: version,
+		},
+		Paths: map[string]map[string]*OpenApiOperation{},
+		Components: OpenApiComponents{
+			Schemas: map[string]*OpenApiSchema{},
+			SecuritySchemes: map[string]*OpenApiSecurityScheme{
+				bearerSecurityScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				apiKeySecurityScheme: {Type: "apiKey", In: "header", Name: ApiKeyHeader},
+			},
+		},
+	}
+
+	errorSchema := &OpenApiSchema{
+		Type: "object",
+		Properties: map[string]*OpenApiSchema{
+			"error": document.schemaFor(reflect.TypeOf(ApiError{})),
+		},
+		Required: []string{"error"},
+	}
+
+	document.Components.Schemas["Error"] = errorSchema
+
+	for _, routes := range groups {
+		for _, route := range routes {
+			document.addRoute(route)
+		}
+	}
+
+	return document
+}
+
+func (d *OpenApiDocument) addRoute(route Route) {
+	path := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = map[string]*OpenApiOperation{}
+	}
+
+	operation := &OpenApiOperation{
+		OperationId: handlerName(route.HandlerFunc),
+		Summary:     route.Summary,
+		Tags:        []string{pathTag(route.Path)},
+		Responses:   map[string]OpenApiResponse{},
+	}
+
+	if !route.Public {
+		// either scheme is enough
+		operation.Security = []map[string][]string{{bearerSecurityScheme: {}}, {apiKeySecurityScheme: {}}}
+	}
+
+	if route.Permission != "" {
+		operation.Description = "Requiere el permiso " + route.Permission
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		operation.Parameters = append(operation.Parameters, OpenApiParameter{
+			Name:        match[1],
+			In:          "path",
+			Description: route.PathParams[match[1]],
+			Required:    true,
+			Schema:      &OpenApiSchema{Type: "string", Pattern: objectIdPattern},
+		})
+	}
+
+	for _, name := range sortedKeys(route.QueryParams) {
+		operation.Parameters = append(operation.Parameters, OpenApiParameter{
+			Name:        name,
+			In:          "query",
+			Description: route.QueryParams[name],
+			Schema:      &OpenApiSchema{Type: "string"},
+		})
+	}
+
+	if route.Conditional {
+		operation.Parameters = append(operation.Parameters, OpenApiParameter{
+			Name:        IfMatchHeader,
+			In:          "header",
+			Description: "ETag leido del recurso; si cambio desde entonces la peticion responde 412",
+			Schema:      &OpenApiSchema{Type: "string"},
+		})
+	}
+
+	if route.Idempotent {
+		operation.Parameters = append(operation.Parameters, OpenApiParameter{
+			Name:        IdempotencyKeyHeader,
+			In:          "header",
+			Description: "Clave unica de la operacion; los reintentos con la misma clave y el mismo cuerpo devuelven la primera respuesta",
+			Schema:      &OpenApiSchema{Type: "string"},
+		})
+	}
+
+	if route.Request != nil {
+		operation.RequestBody = &OpenApiRequestBody{
+			Required: true,
+			Content: map[string]OpenApiMediaType{
+				"application/json": {Schema: d.schemaFor(reflect.TypeOf(route.Request))},
+			},
+		}
+	}
+
+	status := route.Status
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := OpenApiResponse{Description: http.StatusText(status)}
+
+	if route.Response != nil {
+		success.Content = map[string]OpenApiMediaType{
+			"application/json": {Schema: &OpenApiSchema{
+				Type: "object",
+				Properties: map[string]*OpenApiSchema{
+					"body": d.schemaFor(reflect.TypeOf(route.Response)),
+				},
+			}},
+		}
+	}
+
+	operation.Responses[strconv.Itoa(status)] = success
+	operation.Responses["default"] = OpenApiResponse{
+		Description: "Error",
+		Content: map[string]OpenApiMediaType{
+			"application/json": {Schema: &OpenApiSchema{Ref: "#/components/schemas/Error"}},
+		},
+	}
+
+	d.Paths[path][strings.ToLower(route.Method)] = operation
+}
+
+// schemaFor returns the schema of a type. Named structs are registered once in
+// the components and referenced from every place they appear.
+func (d *OpenApiDocument) schemaFor(t reflect.Type) *OpenApiSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case objectIdType:
+		return &OpenApiSchema{Type: "string", Pattern: objectIdPattern}
+	case timeType:
+		return &OpenApiSchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenApiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenApiSchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenApiSchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenApiSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenApiSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenApiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &OpenApiSchema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &OpenApiSchema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+
+		name := schemaName(t)
+
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// registered before walking the fields so recursive types terminate
+			d.Components.Schemas[name] = &OpenApiSchema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+
+		return &OpenApiSchema{Ref: "#/components/schemas/" + name}
+	default:
+		return &OpenApiSchema{}
+	}
+}
+
+func (d *OpenApiDocument) structSchema(t reflect.Type) *OpenApiSchema {
+	schema := &OpenApiSchema{
+		Type:       "object",
+		Properties: map[string]*OpenApiSchema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := jsonFieldName(field)
+
+		if name == "" {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := d.structSchema(field.Type)
+
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		fieldSchema := d.schemaFor(field.Type)
+
+		if applyValidateTag(fieldSchema, field.Type, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = fieldSchema
+	}
+
+	sort.Strings(schema.Required)
+
+	return schema
+}
+
+// applyValidateTag translates the validator rules that have an OpenAPI
+// equivalent and reports whether the field is required.
+func applyValidateTag(schema *OpenApiSchema, t reflect.Type, tag string) bool {
+	required := false
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			// the remaining rules apply to the elements
+			return required
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "email":
+			schema.Format = "email"
+		case "uri", "url":
+			schema.Format = "uri"
+		case "min", "gte", "gt", "max", "lte", "lt", "len":
+			if schema.Ref == "" {
+				applyBoundRule(schema, t.Kind(), name, param)
+			}
+		}
+	}
+
+	return required
+}
+
+func applyBoundRule(schema *OpenApiSchema, kind reflect.Kind, rule string, param string) {
+	value, err := strconv.ParseFloat(param, 64)
+
+	if err != nil {
+		return
+	}
+
+	lower := rule == "min" || rule == "gte" || rule == "gt" || rule == "len"
+	upper := rule == "max" || rule == "lte" || rule == "lt" || rule == "len"
+
+	switch kind {
+	case reflect.String:
+		length := int(value)
+
+		if lower {
+			schema.MinLength = &length
+		}
+
+		if upper {
+			schema.MaxLength = &length
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		count := int(value)
+
+		if lower {
+			schema.MinItems = &count
+		}
+
+		if upper {
+			schema.MaxItems = &count
+		}
+	default:
+		if lower {
+			schema.Minimum = &value
+			schema.ExclusiveMinimum = rule == "gt"
+		}
+
+		if upper {
+			schema.Maximum = &value
+			schema.ExclusiveMaximum = rule == "lt"
+		}
+	}
+}
+
+// schemaName qualifies the type with its package, since several packages
+// declare DTOs with the same name, e.g. budget.DimensionDTO and
+// material.DimensionDTO. Instances of generic types are named after their
+// arguments, e.g. core.Page_budget.BudgetDTO.
+func schemaName(t reflect.Type) string {
+	packagePath := t.PkgPath()
+	name := t.Name()
+
+	if start := strings.Index(name, "["); start >= 0 {
+		arguments := strings.Split(strings.TrimSuffix(name[start+1:], "]"), ",")
+
+		for i, argument := range arguments {
+			arguments[i] = argument[strings.LastIndex(argument, "/")+1:]
+		}
+
+		name = name[:start] + "_" + strings.Join(arguments, "_")
+	}
+
+	return packagePath[strings.LastIndex(packagePath, "/")+1:] + "." + name
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// handlerName returns the method name of a handler method value, which the
+// runtime reports as ".../budget.(*handler).GetAllBudgets-fm".
+func handlerName(handlerFunc func(w http.ResponseWriter, r *http.Request)) string {
+	function := runtime.FuncForPC(reflect.ValueOf(handlerFunc).Pointer())
+
+	if function == nil {
+		return ""
+	}
+
+	name := function.Name()
+
+	return strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "-fm")
+}
+
+func pathTag(path string) string {
+	return strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+}
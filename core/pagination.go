@@ -0,0 +1,154 @@
+package core
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+
+	// SortCreatedAt orders by creation date, which every storage derives from
+	// the ObjectID, so it is also the natural order of the collections.
+	SortCreatedAt = "createdAt"
+)
+
+// PageRequest is the storage-agnostic description of one page of a list:
+// offset pagination plus a single sort field.
+type PageRequest struct {
+	Limit          int
+	Offset         int
+	SortField      string
+	SortDescending bool
+}
+
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	Total         int64  `json:"total"`
+	Limit         int    `json:"limit"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// ParsePageRequest reads the limit, pageToken and sort query parameters. Sort
+// takes one of the allowed fields, prefixed with "-" for descending order, and
+// defaults to the creation date.
+func ParsePageRequest(r *http.Request, sortFields ...string) (*PageRequest, *ApiError) {
+	query := r.URL.Query()
+
+	var page *PageRequest = &PageRequest{
+		Limit:     DefaultPageLimit,
+		SortField: SortCreatedAt,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+
+		if err != nil || parsed < 1 || parsed > MaxPageLimit {
+			return nil, NewInvalidQueryError("limit", "El valor debe estar entre 1 y "+strconv.Itoa(MaxPageLimit))
+		}
+
+		page.Limit = parsed
+	}
+
+	if token := query.Get("pageToken"); token != "" {
+		offset, err := decodePageToken(token)
+
+		if err != nil {
+			return nil, NewInvalidQueryError("pageToken", "El token de pagina no es valido")
+		}
+
+		page.Offset = offset
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		page.SortDescending = strings.HasPrefix(sort, "-")
+		page.SortField = strings.TrimPrefix(sort, "-")
+
+		if !containsString(append(sortFields, SortCreatedAt), page.SortField) {
+			return nil, NewInvalidQueryError("sort", "El valor debe ser uno de: "+strings.Join(append(sortFields, SortCreatedAt), ", "))
+		}
+	}
+
+	return page, nil
+}
+
+// NewPage wraps the items of the requested page, adding the token of the next
+// one when there are more items left.
+func NewPage[T any](items []T, total int64, request PageRequest) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	page := &Page[T]{
+		Items: items,
+		Total: total,
+		Limit: request.Limit,
+	}
+
+	next := request.Offset + len(items)
+
+	if len(items) > 0 && int64(next) < total {
+		page.NextPageToken = encodePageToken(next)
+	}
+
+	return page
+}
+
+// The token only carries the offset, but clients must treat it as opaque so
+// the pagination can move to cursors without breaking them.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), "offset:"))
+
+	if err != nil || offset < 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	return offset, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindOptions translates the page into Mongo options. sortFields maps the API
+// sort fields to document fields; the _id tiebreaker keeps pages stable when
+// several documents share the sort value.
+func (p PageRequest) FindOptions(sortFields map[string]string) *options.FindOptions {
+	direction := 1
+
+	if p.SortDescending {
+		direction = -1
+	}
+
+	sort := bson.D{}
+
+	if field, ok := sortFields[p.SortField]; ok && field != "_id" {
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+
+	sort = append(sort, bson.E{Key: "_id", Value: direction})
+
+	return options.Find().SetSort(sort).SetSkip(int64(p.Offset)).SetLimit(int64(p.Limit))
+}
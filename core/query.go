@@ -0,0 +1,42 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+type QueryTimeouts struct {
+	Default    time.Duration            `yaml:"default" json:"default" validate:"gt=0"`
+	Operations map[string]time.Duration `yaml:"operations" json:"operations"`
+}
+
+// For returns the deadline configured for the operation, e.g.
+// "budgets.FindAllBudgets", falling back to the default one.
+func (t QueryTimeouts) For(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok && timeout > 0 {
+		return timeout
+	}
+	return t.Default
+}
+
+type operationKey struct{}
+
+// WithQueryTimeout derives the context of a single repository operation from
+// the request context, so the query is cancelled when the client goes away.
+// The operation name travels in the context for the database instrumentation,
+// and the operation span ends when the returned function is called.
+func WithQueryTimeout(ctx context.Context, timeouts QueryTimeouts, operation string) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, operationKey{}, operation)
+	ctx, span := StartSpan(ctx, operation)
+	ctx, cancel := context.WithTimeout(ctx, timeouts.For(operation))
+
+	return ctx, func() {
+		cancel()
+		span.End()
+	}
+}
+
+func OperationFromContext(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey{}).(string)
+	return operation
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type RateLimitSettings struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	Default RateLimitRule `yaml:"default" json:"default"`
+	// Groups overrides the default rule per route group, e.g. "auth" or
+	// "budgetLines". A route belongs to the group named in its route table,
+	// or else to the first segment of its path.
+	Groups map[string]RateLimitRule `yaml:"groups" json:"groups" validate:"dive"`
+	// Address limits every client address before authentication, so requests
+	// with invalid credentials are limited too. It should allow what the
+	// clients sharing an address need together.
+	Address RateLimitRule `yaml:"address" json:"address"`
+	// TrustForwardedFor identifies anonymous clients by the first address of
+	// X-Forwarded-For, which only a trusted proxy in front of the API makes
+	// safe.
+	TrustForwardedFor bool `yaml:"trustForwardedFor" json:"trustForwardedFor"`
+}
+
+// RateLimitRule is a token bucket that holds Burst requests and refills
+// Requests tokens every Period. Burst defaults to Requests.
+type RateLimitRule struct {
+	Requests int           `yaml:"requests" json:"requests" validate:"gte=1"`
+	Period   time.Duration `yaml:"period" json:"period" validate:"gt=0"`
+	Burst    int           `yaml:"burst" json:"burst" validate:"gte=0"`
+}
+
+// For returns the rule of the route group, falling back to the default one.
+func (s RateLimitSettings) For(group string) RateLimitRule {
+	if rule, ok := s.Groups[group]; ok {
+		return rule
+	}
+	return s.Default
+}
+
+func (r RateLimitRule) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// Limit is the size of the bucket, reported in X-RateLimit-Limit.
+func (r RateLimitRule) Limit() int {
+	return int(r.capacity())
+}
+
+func (r RateLimitRule) refillRate() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+type RateLimitDecision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the wait until the next token, zero when allowed.
+	RetryAfter time.Duration
+	// Reset is the wait until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the buckets. The in-process one suits a single
+// instance; clusters plug in a shared implementation, e.g. over Redis, so
+// every instance draws from the same buckets.
+type RateLimitStore interface {
+	// Take draws a token from the bucket of the key, if there is one.
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitDecision, error)
+}
+
+// sweepInterval is how often the memory store forgets the full buckets, which
+// behave the same as missing ones.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+type memoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitDecision, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	capacity := rule.capacity()
+	rate := rule.refillRate()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	decision := RateLimitDecision{}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = secondsToDuration((capacity - b.tokens) / rate)
+	b.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -2,16 +2,79 @@ package core
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func DecodeBody(r *http.Request, storeVar any) bool {
-	err := json.NewDecoder(r.Body).Decode(&storeVar)
+func DecodeBody(r *http.Request, storeVar any) *ApiError {
+	err := json.NewDecoder(r.Body).Decode(storeVar)
+	if err != nil {
+		LogError(r.Context(), err)
+		return NewInvalidBodyError(err)
+	}
+
+	apiErr := Validate(storeVar)
+
+	if apiErr != nil {
+		Log(r.Context(), LogLevelWarn, "Error de validacion: "+apiErr.Error(), nil)
+	}
+
+	return apiErr
+}
+
+// QueryFloat reads an optional numeric query parameter, returning nil when it
+// is missing.
+func QueryFloat(r *http.Request, param string) (*float64, *ApiError) {
+	value := r.URL.Query().Get(param)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return nil, NewInvalidQueryError(param, "El valor debe ser numerico")
+	}
+
+	return &parsed, nil
+}
+
+// QueryObjectId reads an optional ObjectID query parameter, returning nil when
+// it is missing.
+func QueryObjectId(r *http.Request, param string) (*primitive.ObjectID, *ApiError) {
+	value := r.URL.Query().Get(param)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	oid := ConvertHexToObjectId(value)
+
+	if oid == nil {
+		return nil, NewInvalidIdError(param)
+	}
+
+	return oid, nil
+}
+
+// QueryTime reads an optional RFC 3339 time query parameter, returning nil
+// when it is missing.
+func QueryTime(r *http.Request, param string) (*time.Time, *ApiError) {
+	value := r.URL.Query().Get(param)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+
 	if err != nil {
-		log.Println(err.Error())
-		return true
+		return nil, NewInvalidQueryError(param, "El valor debe ser una fecha RFC 3339, por ejemplo 2024-01-31T15:04:05Z")
 	}
 
-	return Validate(storeVar)
+	return &parsed, nil
 }
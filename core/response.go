@@ -1,32 +1,45 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 )
 
 type Response struct {
-	Error string      `json:"error"`
+	Error *ApiError   `json:"error"`
 	Body  interface{} `json:"body"`
 }
 
-func EncodeJsonResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+// EncodeJsonResponse writes the body with the success status of the route, or
+// the error with its own status. Services return only the body and the error,
+// so the status of an error cannot disagree with it.
+func EncodeJsonResponse(w http.ResponseWriter, statusCode int, body interface{}, err *ApiError) {
+	if err != nil {
+		EncodeErrorResponse(w, err)
+		return
+	}
+
 	w.Header().Add("Content-type", "application/json")
 	w.WriteHeader(statusCode)
-	var response *Response = &Response{}
-
-	if statusCode == http.StatusInternalServerError ||
-		statusCode == http.StatusBadRequest ||
-		statusCode == http.StatusNotFound {
-		response.Error = "Ocurrio un error al realizar la operacion"
-	}
 
 	if statusCode == http.StatusOK && body == nil {
 		body = "OK"
 	}
 
 	if body != nil {
-		response.Body = body
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(&Response{Body: body})
 	}
 }
+
+func EncodeErrorResponse(w http.ResponseWriter, err *ApiError) {
+	if err.Status >= http.StatusInternalServerError {
+		// the request logger sets the header before calling the handler
+		ctx := WithRequestId(context.Background(), w.Header().Get(RequestIdHeader))
+		LogError(ctx, err)
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(&Response{Error: err})
+}
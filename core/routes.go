@@ -1,11 +1,43 @@
 package core
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 type Route struct {
 	Path        string
 	HandlerFunc func(w http.ResponseWriter, r *http.Request)
 	Method      string
+	// Public routes skip the authentication middleware. Otherwise, when
+	// Permission is set, the principal needs a role that grants it.
+	Public     bool
+	Permission string
+	// RateLimitGroup selects the rate limit rule; it defaults to the first
+	// segment of the path.
+	RateLimitGroup string
+	// Conditional routes write a versioned entity and honour If-Match.
+	Conditional bool
+	// Idempotent routes replay their first response to the retries sent with
+	// the same Idempotency-Key.
+	Idempotent bool
+	// Documentation used to build the OpenAPI specification. Request and
+	// Response hold a zero value of the body types, e.g. BudgetNameDTO{}.
+	Summary     string
+	Request     any
+	Response    any
+	Status      int
+	PathParams  map[string]string
+	QueryParams map[string]string
+}
+
+// Group returns the rate limit group of the route.
+func (r Route) Group() string {
+	if r.RateLimitGroup != "" {
+		return r.RateLimitGroup
+	}
+
+	return strings.SplitN(strings.TrimPrefix(r.Path, "/"), "/", 2)[0]
 }
 
 type Routes []Route
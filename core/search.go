@@ -0,0 +1,119 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText lowercases the text and strips its diacritics, so
+// "Ladrillo Huéco" and "ladrillo hueco" compare equal.
+func NormalizeText(text string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	normalized, _, err := transform.String(stripAccents, text)
+
+	if err != nil {
+		normalized = text
+	}
+
+	return strings.ToLower(normalized)
+}
+
+// SearchTerms splits a search query into its distinct normalized words.
+func SearchTerms(query string) []string {
+	terms := []string{}
+
+	for _, word := range searchWords(query) {
+		if !containsString(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// SearchScore ranks how well a text matches the terms, between 0 and 1. A
+// word equal to a term counts fully, and a word starting with it, like
+// "ladrillos" for "ladrillo", or with its same stem, like "ladrillo" for
+// "ladrillos", counts partially; texts containing the whole query as
+// consecutive words get a bonus. Every storage finds its candidates with the
+// stem prefixes of the terms and the score decides which of them are hits, so
+// the drivers return the same results.
+func SearchScore(terms []string, text string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	words := searchWords(text)
+
+	var matched float64 = 0
+
+	for _, term := range terms {
+		var best float64 = 0
+		stem := SearchStem(term)
+
+		for _, word := range words {
+			if word == term {
+				best = 1
+				break
+			}
+
+			if strings.HasPrefix(word, term) || SearchStem(word) == stem {
+				best = 0.75
+			}
+		}
+
+		matched += best
+	}
+
+	if matched == 0 {
+		return 0
+	}
+
+	score := matched / float64(len(terms)) * 0.9
+
+	if strings.Contains(" "+strings.Join(words, " ")+" ", " "+strings.Join(terms, " ")+" ") {
+		score += 0.1
+	}
+
+	return score
+}
+
+// SearchStem strips the plural and gender endings of a normalized Spanish
+// word, "ladrillos" and "ladrillo" giving "ladrill", approximating the stems
+// of the Mongo text indexes for nouns and adjectives. Stems keep at least
+// minStemLength letters.
+func SearchStem(word string) string {
+	stem := []rune(word)
+
+	if len(stem) > minStemLength && stem[len(stem)-1] == 's' {
+		stem = stem[:len(stem)-1]
+
+		// "paredes" is the plural of "pared"
+		if len(stem) > minStemLength && stem[len(stem)-1] == 'e' && !isVowel(stem[len(stem)-2]) {
+			return string(stem[:len(stem)-1])
+		}
+	}
+
+	if len(stem) > minStemLength && (stem[len(stem)-1] == 'a' || stem[len(stem)-1] == 'e' || stem[len(stem)-1] == 'o') {
+		stem = stem[:len(stem)-1]
+	}
+
+	return string(stem)
+}
+
+const minStemLength = 3
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
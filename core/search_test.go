@@ -0,0 +1,50 @@
+package core
+
+import "testing"
+
+func TestSearchStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "ladrillo", want: "ladrill"},
+		{word: "ladrillos", want: "ladrill"},
+		{word: "hueca", want: "huec"},
+		{word: "paredes", want: "pared"},
+		{word: "pared", want: "pared"},
+		{word: "cales", want: "cal"},
+		{word: "tres", want: "tre"},
+		{word: "cal", want: "cal"},
+	}
+
+	for _, tt := range tests {
+		if got := SearchStem(tt.word); got != tt.want {
+			t.Errorf("SearchStem(%q) = %q, se esperaba %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestSearchScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		text  string
+		want  float64
+	}{
+		{name: "same word", query: "ladrillo", text: "Ladrillo hueco", want: 1},
+		{name: "accents", query: "hueco", text: "Ladrillo Huéco", want: 1},
+		{name: "partial word", query: "ladri", text: "Ladrillo", want: 0.675},
+		{name: "plural of the word", query: "ladrillos", text: "Ladrillo", want: 0.675},
+		{name: "other gender", query: "hueco", text: "Teja hueca", want: 0.675},
+		{name: "same stem prefix only", query: "ladrillos", text: "Ladrillera", want: 0},
+		{name: "no match", query: "arena", text: "Ladrillo", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchScore(SearchTerms(tt.query), tt.text); got != tt.want {
+				t.Errorf("SearchScore(%q, %q) = %v, se esperaba %v", tt.query, tt.text, got, tt.want)
+			}
+		})
+	}
+}
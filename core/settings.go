@@ -0,0 +1,464 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigFileEnv = "APP_CONFIG_FILE"
+
+type Settings struct {
+	Server      ServerSettings      `yaml:"server" json:"server"`
+	Storage     StorageSettings     `yaml:"storage" json:"storage"`
+	Database    DatabaseSettings    `yaml:"database" json:"database"`
+	Cors        CorsSettings        `yaml:"cors" json:"cors"`
+	Health      HealthSettings      `yaml:"health" json:"health"`
+	Logging     LoggingSettings     `yaml:"logging" json:"logging"`
+	Metrics     MetricsSettings     `yaml:"metrics" json:"metrics"`
+	Tracing     TracingSettings     `yaml:"tracing" json:"tracing"`
+	Docs        DocsSettings        `yaml:"docs" json:"docs"`
+	Auth        AuthSettings        `yaml:"auth" json:"auth"`
+	RateLimit   RateLimitSettings   `yaml:"rateLimit" json:"rateLimit"`
+	Idempotency IdempotencySettings `yaml:"idempotency" json:"idempotency"`
+}
+
+type ServerSettings struct {
+	Address           string        `yaml:"address" json:"address" validate:"required"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" json:"readTimeout" validate:"gte=0"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" json:"readHeaderTimeout" validate:"gte=0"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" json:"writeTimeout" validate:"gte=0"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" json:"idleTimeout" validate:"gte=0"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" json:"shutdownTimeout" validate:"gt=0"`
+	// RequireIfMatch rejects with 428 the conditional writes sent without
+	// If-Match; otherwise those writes overwrite whatever version is stored.
+	RequireIfMatch bool `yaml:"requireIfMatch" json:"requireIfMatch"`
+}
+
+const (
+	StorageDriverMongo  = "mongo"
+	StorageDriverMemory = "memory"
+	StorageDriverSQLite = "sqlite"
+)
+
+type StorageSettings struct {
+	Driver string         `yaml:"driver" json:"driver" validate:"required,oneof=mongo memory sqlite"`
+	SQLite SQLiteSettings `yaml:"sqlite" json:"sqlite"`
+}
+
+type SQLiteSettings struct {
+	Path string `yaml:"path" json:"path"`
+}
+
+type DatabaseSettings struct {
+	URI            string        `yaml:"uri" json:"uri" validate:"required,uri"`
+	Name           string        `yaml:"name" json:"name" validate:"required"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout" json:"connectTimeout" validate:"gt=0"`
+	QueryTimeouts  QueryTimeouts `yaml:"queryTimeouts" json:"queryTimeouts"`
+	// MigrateOnStartup applies the pending migrations before serving; when
+	// disabled they run with the migrate command.
+	MigrateOnStartup bool          `yaml:"migrateOnStartup" json:"migrateOnStartup"`
+	MigrationTimeout time.Duration `yaml:"migrationTimeout" json:"migrationTimeout" validate:"gt=0"`
+}
+
+type CorsSettings struct {
+	AllowedOrigins   []string `yaml:"allowedOrigins" json:"allowedOrigins" validate:"required,min=1"`
+	AllowedMethods   []string `yaml:"allowedMethods" json:"allowedMethods" validate:"required,min=1"`
+	AllowedHeaders   []string `yaml:"allowedHeaders" json:"allowedHeaders"`
+	ExposedHeaders   []string `yaml:"exposedHeaders" json:"exposedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials" json:"allowCredentials"`
+	MaxAge           int      `yaml:"maxAge" json:"maxAge" validate:"gte=0"`
+}
+
+type HealthSettings struct {
+	CheckInterval time.Duration `yaml:"checkInterval" json:"checkInterval" validate:"gt=0"`
+	CheckTimeout  time.Duration `yaml:"checkTimeout" json:"checkTimeout" validate:"gt=0"`
+}
+
+type LoggingSettings struct {
+	LogBodies    bool     `yaml:"logBodies" json:"logBodies"`
+	MaxBodyBytes int      `yaml:"maxBodyBytes" json:"maxBodyBytes" validate:"gte=0"`
+	RedactFields []string `yaml:"redactFields" json:"redactFields"`
+}
+
+type MetricsSettings struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Path    string `yaml:"path" json:"path" validate:"required,startswith=/"`
+}
+
+type TracingSettings struct {
+	Enabled     bool         `yaml:"enabled" json:"enabled"`
+	Exporter    string       `yaml:"exporter" json:"exporter" validate:"required,oneof=stdout otlp"`
+	ServiceName string       `yaml:"serviceName" json:"serviceName" validate:"required"`
+	SampleRatio float64      `yaml:"sampleRatio" json:"sampleRatio" validate:"gte=0,lte=1"`
+	OTLP        OTLPSettings `yaml:"otlp" json:"otlp"`
+}
+
+type OTLPSettings struct {
+	Endpoint string `yaml:"endpoint" json:"endpoint" validate:"required"`
+	Insecure bool   `yaml:"insecure" json:"insecure"`
+}
+
+type DocsSettings struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+type AuthSettings struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Secret signs the tokens with HS256, so it must be shared by every
+	// instance and kept out of version control.
+	Secret          string        `yaml:"secret" json:"secret" validate:"required_if=Enabled true,omitempty,min=32"`
+	Issuer          string        `yaml:"issuer" json:"issuer" validate:"required"`
+	AccessTokenTTL  time.Duration `yaml:"accessTokenTTL" json:"accessTokenTTL" validate:"gt=0"`
+	RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL" json:"refreshTokenTTL" validate:"gtfield=AccessTokenTTL"`
+	BcryptCost      int           `yaml:"bcryptCost" json:"bcryptCost" validate:"gte=4,lte=31"`
+	Admin           AdminSettings `yaml:"admin" json:"admin"`
+}
+
+// AdminSettings describes the account created on startup when no user has its
+// username, so a new deployment has someone able to log in.
+type AdminSettings struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password" validate:"required_with=Username,omitempty,min=8"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		Server: ServerSettings{
+			Address:           ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Storage: StorageSettings{
+			Driver: StorageDriverMongo,
+			SQLite: SQLiteSettings{
+				Path: "arquitectura.db",
+			},
+		},
+		Database: DatabaseSettings{
+			URI:            "mongodb://localhost:27017",
+			Name:           "arquitectura",
+			ConnectTimeout: 10 * time.Second,
+			QueryTimeouts: QueryTimeouts{
+				Default:    15 * time.Second,
+				Operations: map[string]time.Duration{},
+			},
+			MigrateOnStartup: true,
+			MigrationTimeout: 5 * time.Minute,
+		},
+		Cors: CorsSettings{
+			AllowedOrigins:   []string{"*"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders:   []string{"Authorization", ApiKeyHeader, "Content-Type", RequestIdHeader, IfMatchHeader, IdempotencyKeyHeader},
+			ExposedHeaders:   []string{ETagHeader, IdempotentReplayedHeader},
+			AllowCredentials: true,
+			MaxAge:           3600,
+		},
+		Health: HealthSettings{
+			CheckInterval: 10 * time.Second,
+			CheckTimeout:  2 * time.Second,
+		},
+		Logging: LoggingSettings{
+			LogBodies:    true,
+			MaxBodyBytes: 4096,
+			RedactFields: []string{"password", "token", "refreshToken", "accessToken", "apiKey", "secret"},
+		},
+		Metrics: MetricsSettings{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingSettings{
+			Enabled:     false,
+			Exporter:    TracingExporterOTLP,
+			ServiceName: "arquitectura-api",
+			SampleRatio: 1,
+			OTLP: OTLPSettings{
+				Endpoint: "localhost:4318",
+				Insecure: true,
+			},
+		},
+		Docs: DocsSettings{
+			Enabled: true,
+		},
+		Auth: AuthSettings{
+			Enabled:         true,
+			Issuer:          "arquitectura-api",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			BcryptCost:      12,
+		},
+		RateLimit: RateLimitSettings{
+			Enabled: true,
+			Default: RateLimitRule{Requests: 300, Period: time.Minute},
+			Address: RateLimitRule{Requests: 1200, Period: time.Minute},
+			Groups: map[string]RateLimitRule{
+				// login and refresh, limited per address against password guessing
+				"auth":        {Requests: 10, Period: time.Minute},
+				"budgetLines": {Requests: 60, Period: time.Minute, Burst: 20},
+			},
+		},
+		Idempotency: IdempotencySettings{
+			Enabled:      true,
+			TTL:          24 * time.Hour,
+			MaxBodyBytes: 1 << 20,
+		},
+	}
+}
+
+// LoadSettings builds the settings from the defaults, the optional file pointed
+// by APP_CONFIG_FILE (YAML or JSON) and the APP_* environment variables, in that
+// order of precedence.
+func LoadSettings() (*Settings, error) {
+	settings := DefaultSettings()
+
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		err := loadSettingsFile(path, settings)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := loadSettingsEnv(settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = validator.New().Struct(settings)
+
+	if err != nil {
+		return nil, fmt.Errorf("configuracion invalida: %w", err)
+	}
+
+	return settings, nil
+}
+
+func loadSettingsFile(path string, settings *Settings) error {
+	extension := strings.ToLower(filepath.Ext(path))
+
+	if extension != ".yaml" && extension != ".yml" && extension != ".json" {
+		return fmt.Errorf("formato de archivo de configuracion no soportado: %s", path)
+	}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo de configuracion: %w", err)
+	}
+
+	// JSON is a subset of YAML, so the same decoder handles both formats
+	err = yaml.Unmarshal(content, settings)
+
+	if err != nil {
+		return fmt.Errorf("no se pudo interpretar el archivo de configuracion %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func loadSettingsEnv(settings *Settings) error {
+	setString("APP_SERVER_ADDRESS", &settings.Server.Address)
+	setString("APP_STORAGE_DRIVER", &settings.Storage.Driver)
+	setString("APP_STORAGE_SQLITE_PATH", &settings.Storage.SQLite.Path)
+	setString("APP_DATABASE_URI", &settings.Database.URI)
+	setString("APP_DATABASE_NAME", &settings.Database.Name)
+	setList("APP_CORS_ALLOWED_ORIGINS", &settings.Cors.AllowedOrigins)
+	setList("APP_CORS_ALLOWED_METHODS", &settings.Cors.AllowedMethods)
+	setList("APP_CORS_ALLOWED_HEADERS", &settings.Cors.AllowedHeaders)
+	setList("APP_CORS_EXPOSED_HEADERS", &settings.Cors.ExposedHeaders)
+	setList("APP_LOGGING_REDACT_FIELDS", &settings.Logging.RedactFields)
+	setString("APP_METRICS_PATH", &settings.Metrics.Path)
+	setString("APP_TRACING_EXPORTER", &settings.Tracing.Exporter)
+	setString("APP_TRACING_SERVICE_NAME", &settings.Tracing.ServiceName)
+	setString("APP_TRACING_OTLP_ENDPOINT", &settings.Tracing.OTLP.Endpoint)
+	setString("APP_AUTH_SECRET", &settings.Auth.Secret)
+	setString("APP_AUTH_ISSUER", &settings.Auth.Issuer)
+	setString("APP_AUTH_ADMIN_USERNAME", &settings.Auth.Admin.Username)
+	setString("APP_AUTH_ADMIN_PASSWORD", &settings.Auth.Admin.Password)
+
+	return firstError(
+		setDuration("APP_SERVER_READ_TIMEOUT", &settings.Server.ReadTimeout),
+		setDuration("APP_SERVER_READ_HEADER_TIMEOUT", &settings.Server.ReadHeaderTimeout),
+		setDuration("APP_SERVER_WRITE_TIMEOUT", &settings.Server.WriteTimeout),
+		setDuration("APP_SERVER_IDLE_TIMEOUT", &settings.Server.IdleTimeout),
+		setDuration("APP_SERVER_SHUTDOWN_TIMEOUT", &settings.Server.ShutdownTimeout),
+		setBool("APP_SERVER_REQUIRE_IF_MATCH", &settings.Server.RequireIfMatch),
+		setDuration("APP_DATABASE_CONNECT_TIMEOUT", &settings.Database.ConnectTimeout),
+		setDuration("APP_DATABASE_QUERY_TIMEOUT", &settings.Database.QueryTimeouts.Default),
+		setDurationMap("APP_DATABASE_OPERATION_TIMEOUTS", &settings.Database.QueryTimeouts.Operations),
+		setBool("APP_DATABASE_MIGRATE_ON_STARTUP", &settings.Database.MigrateOnStartup),
+		setDuration("APP_DATABASE_MIGRATION_TIMEOUT", &settings.Database.MigrationTimeout),
+		setBool("APP_CORS_ALLOW_CREDENTIALS", &settings.Cors.AllowCredentials),
+		setInt("APP_CORS_MAX_AGE", &settings.Cors.MaxAge),
+		setDuration("APP_HEALTH_CHECK_INTERVAL", &settings.Health.CheckInterval),
+		setDuration("APP_HEALTH_CHECK_TIMEOUT", &settings.Health.CheckTimeout),
+		setBool("APP_LOGGING_LOG_BODIES", &settings.Logging.LogBodies),
+		setInt("APP_LOGGING_MAX_BODY_BYTES", &settings.Logging.MaxBodyBytes),
+		setBool("APP_METRICS_ENABLED", &settings.Metrics.Enabled),
+		setBool("APP_TRACING_ENABLED", &settings.Tracing.Enabled),
+		setFloat("APP_TRACING_SAMPLE_RATIO", &settings.Tracing.SampleRatio),
+		setBool("APP_TRACING_OTLP_INSECURE", &settings.Tracing.OTLP.Insecure),
+		setBool("APP_DOCS_ENABLED", &settings.Docs.Enabled),
+		setBool("APP_AUTH_ENABLED", &settings.Auth.Enabled),
+		setDuration("APP_AUTH_ACCESS_TOKEN_TTL", &settings.Auth.AccessTokenTTL),
+		setDuration("APP_AUTH_REFRESH_TOKEN_TTL", &settings.Auth.RefreshTokenTTL),
+		setInt("APP_AUTH_BCRYPT_COST", &settings.Auth.BcryptCost),
+		setBool("APP_RATE_LIMIT_ENABLED", &settings.RateLimit.Enabled),
+		setInt("APP_RATE_LIMIT_REQUESTS", &settings.RateLimit.Default.Requests),
+		setDuration("APP_RATE_LIMIT_PERIOD", &settings.RateLimit.Default.Period),
+		setInt("APP_RATE_LIMIT_BURST", &settings.RateLimit.Default.Burst),
+		setInt("APP_RATE_LIMIT_ADDRESS_REQUESTS", &settings.RateLimit.Address.Requests),
+		setDuration("APP_RATE_LIMIT_ADDRESS_PERIOD", &settings.RateLimit.Address.Period),
+		setBool("APP_RATE_LIMIT_TRUST_FORWARDED_FOR", &settings.RateLimit.TrustForwardedFor),
+		setBool("APP_IDEMPOTENCY_ENABLED", &settings.Idempotency.Enabled),
+		setDuration("APP_IDEMPOTENCY_TTL", &settings.Idempotency.TTL),
+		setInt("APP_IDEMPOTENCY_MAX_BODY_BYTES", &settings.Idempotency.MaxBodyBytes),
+	)
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setString(key string, target *string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = value
+	}
+}
+
+func setList(key string, target *[]string) {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return
+	}
+
+	list := []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	*target = list
+}
+
+func setInt(key string, target *int) error {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+func setFloat(key string, target *float64) error {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+func setBool(key string, target *bool) error {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+func setDuration(key string, target *time.Duration) error {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*target = parsed
+	return nil
+}
+
+// setDurationMap parses values like "budgets.FindAllBudgets=5s,materials.ChangeMaterialPrice=30s"
+// and merges them into the target map.
+func setDurationMap(key string, target *map[string]time.Duration) error {
+	value, ok := os.LookupEnv(key)
+
+	if !ok {
+		return nil
+	}
+
+	if *target == nil {
+		*target = map[string]time.Duration{}
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		name, rawDuration, found := strings.Cut(item, "=")
+
+		if !found {
+			return fmt.Errorf("%s: se esperaba nombre=duracion en %q", key, item)
+		}
+
+		parsed, err := time.ParseDuration(strings.TrimSpace(rawDuration))
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		(*target)[strings.TrimSpace(name)] = parsed
+	}
+
+	return nil
+}
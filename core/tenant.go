@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TenantField holds the organization that owns a budget, material or
+// dimension document.
+const TenantField = "organizationId"
+
+// DefaultOrganizationID owns the documents written before organizations
+// existed, and every request while authentication is disabled.
+var DefaultOrganizationID = primitive.ObjectID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+
+const DefaultOrganizationName = "Organizacion principal"
+
+// TenantFromContext returns the organization of the authenticated principal.
+// Anonymous requests and tokens issued before organizations existed belong to
+// the default organization.
+func TenantFromContext(ctx context.Context) primitive.ObjectID {
+	principal := PrincipalFromContext(ctx)
+
+	if principal == nil || principal.OrganizationID.IsZero() {
+		return DefaultOrganizationID
+	}
+
+	return principal.OrganizationID
+}
+
+// TenantFilter restricts the filter to the documents of the request
+// organization. Every query builder passes its filter through it.
+func TenantFilter(ctx context.Context, filter bson.M) bson.M {
+	filter[TenantField] = TenantFromContext(ctx)
+	return filter
+}
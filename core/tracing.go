@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+const tracerName = "github.com/lucasbravi2019/arquitectura"
+
+// StartTracing installs the global tracer provider and the W3C trace-context
+// propagator. When tracing is disabled spans are still created by the no-op
+// provider, so the instrumented code does not need to check it. The returned
+// function flushes the pending spans.
+func StartTracing(settings TracingSettings) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !settings.Enabled {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newSpanExporter(settings)
+
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear el exportador de trazas: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(settings.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(settings.ServiceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newSpanExporter(settings TracingSettings) (sdktrace.SpanExporter, error) {
+	switch settings.Exporter {
+	case TracingExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TracingExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(settings.OTLP.Endpoint)}
+
+		if settings.OTLP.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		return otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("exportador desconocido: %s", settings.Exporter)
+	}
+}
+
+// StartSpan opens a span of the application tracer as a child of the span in
+// the context, if any.
+func StartSpan(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// TraceIdFromContext returns the trace ID of the current span, or an empty
+// string when the request is not sampled.
+func TraceIdFromContext(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
+// TracingCommandMonitor opens a client span for every Mongo command, child of
+// the repository span started by WithQueryTimeout.
+func TracingCommandMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	finish := func(requestId int64, failure string) {
+		value, ok := spans.LoadAndDelete(requestId)
+
+		if !ok {
+			return
+		}
+
+		span := value.(trace.Span)
+
+		if failure != "" {
+			span.SetStatus(codes.Error, failure)
+		}
+
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			_, span := StartSpan(ctx, "mongo."+e.CommandName,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemMongoDB,
+					semconv.DBName(e.DatabaseName),
+					semconv.DBOperation(e.CommandName),
+					semconv.DBMongoDBCollection(commandCollection(e)),
+				),
+			)
+
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.Failure)
+		},
+	}
+}
+
+// commandCollection reads the collection name, which Mongo sends as the value
+// of the command's first element, e.g. {"find": "budgets", ...}.
+func commandCollection(e *event.CommandStartedEvent) string {
+	element, err := e.Command.IndexErr(0)
+
+	if err != nil {
+		return ""
+	}
+
+	collection, _ := element.Value().StringValueOK()
+	return collection
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork runs the writes of a cascade, like deleting a dimension from
+// every material and budget, so that they all apply or none does. The
+// repositories join the unit through the context fn receives, so fn must
+// pass it to every call. Units nested in another one join the outer unit.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type mongoUnitOfWork struct {
+	client *mongo.Client
+}
+
+// NewMongoUnitOfWork runs every unit in a multi-document transaction.
+// Transactions need a replica set or a sharded cluster; on a standalone
+// server, see SupportsTransactions, use NewDirectUnitOfWork instead.
+func NewMongoUnitOfWork(db *mongo.Database) UnitOfWork {
+	return &mongoUnitOfWork{
+		client: db.Client(),
+	}
+}
+
+func (u *mongoUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := u.client.StartSession()
+
+	if err != nil {
+		LogError(ctx, err)
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	// WithTransaction retries fn on transient errors, so fn must not keep
+	// state from an earlier attempt
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (any, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	return err
+}
+
+// SupportsTransactions reports whether the server is a replica set member or
+// a mongos router, the deployments that accept transactions.
+func SupportsTransactions(ctx context.Context, db *mongo.Database) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+
+	if err != nil {
+		return false, err
+	}
+
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+type directUnitOfWork struct{}
+
+// NewDirectUnitOfWork runs fn without a transaction, for storages that cannot
+// roll the writes back.
+func NewDirectUnitOfWork() UnitOfWork {
+	return directUnitOfWork{}
+}
+
+func (directUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
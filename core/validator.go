@@ -1,16 +1,21 @@
 package core
 
 import (
-	"log"
-
 	"github.com/go-playground/validator/v10"
 )
 
-func Validate(obj interface{}) bool {
-	validationErrors := validator.New().Struct(obj)
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v
+}
+
+func Validate(obj interface{}) *ApiError {
+	validationErrors := validate.Struct(obj)
 	if validationErrors != nil {
-		log.Println("Error de validacion\n" + validationErrors.Error())
-		return true
+		return NewValidationError(validationErrors)
 	}
-	return false
+	return nil
 }
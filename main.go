@@ -1,9 +1,82 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+
 	"github.com/lucasbravi2019/arquitectura/config"
+	"github.com/lucasbravi2019/arquitectura/core"
 )
 
 func main() {
-	config.StartApi()
+	settings, err := core.LoadSettings()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// "migrate" applies the pending migrations and exits, for deployments
+	// that disable database.migrateOnStartup
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			log.Fatalf("comando desconocido: %s", os.Args[1])
+		}
+
+		err = config.Migrate(settings)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	stopTracing, err := core.StartTracing(settings.Tracing)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	monitor := core.NewHealthMonitor(settings.Health)
+	metrics := core.NewMetrics()
+
+	repositories, closeStorage, err := config.OpenStorage(settings, monitor, metrics)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	container := config.NewContainer(settings, monitor, metrics, repositories)
+	container.OnClose(stopTracing)
+	container.OnClose(closeStorage)
+
+	err = config.CreateDefaultOrganization(container)
+
+	if err == nil {
+		err = config.CreateAdminUser(container)
+	}
+
+	if err != nil {
+		abort(container, err)
+	}
+
+	os.Exit(config.StartApi(container))
+}
+
+// abort exits like StartApi does when the server fails, releasing the
+// container resources that log.Fatal would leave open.
+func abort(container *config.Container, err error) {
+	log.Println(err.Error())
+
+	ctx, cancel := context.WithTimeout(context.Background(), container.Settings.Database.ConnectTimeout)
+
+	err = container.Close(ctx)
+	cancel()
+
+	if err != nil {
+		log.Println("No se pudieron liberar los recursos: " + err.Error())
+	}
+
+	os.Exit(config.ExitServerError)
 }
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuthenticationMiddleware requires a valid access token in the Authorization
+// header, or an API key in the X-API-Key header, and stores its principal in
+// the request context. A nil apiKeys rejects API keys.
+func AuthenticationMiddleware(tokens *core.TokenIssuer, apiKeys core.ApiKeyVerifier) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var principal *core.Principal
+
+			if key := r.Header.Get(core.ApiKeyHeader); key != "" && apiKeys != nil {
+				verified, err := apiKeys.VerifyApiKey(r.Context(), strings.TrimSpace(key))
+
+				if err != nil {
+					core.Log(r.Context(), core.LogLevelWarn, "Clave de API rechazada: "+err.Error(), nil)
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+					core.EncodeErrorResponse(w, core.NewUnauthorizedError("La clave de API no es valida o fue revocada"))
+					return
+				}
+
+				principal = verified
+			} else {
+				scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+
+				if !found || !strings.EqualFold(scheme, "Bearer") {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+					core.EncodeErrorResponse(w, core.NewUnauthorizedError("Se requiere un token de acceso"))
+					return
+				}
+
+				parsed, err := tokens.Parse(strings.TrimSpace(token), core.TokenTypeAccess)
+
+				if err != nil {
+					core.Log(r.Context(), core.LogLevelWarn, "Token de acceso rechazado: "+err.Error(), nil)
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+					core.EncodeErrorResponse(w, core.NewUnauthorizedError("El token de acceso no es valido o expiro"))
+					return
+				}
+
+				principal = parsed
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.EnduserID(principal.Username))
+
+			f(w, r.WithContext(core.ContextWithPrincipal(r.Context(), principal)))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// AuthorizationMiddleware rejects with 403 the principals without the
+// permission. It runs after the authentication middleware.
+func AuthorizationMiddleware(permission string) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := core.PrincipalFromContext(r.Context())
+
+			if principal == nil {
+				core.EncodeErrorResponse(w, core.NewUnauthorizedError("Se requiere un token de acceso"))
+				return
+			}
+
+			if !principal.HasPermission(permission) {
+				core.Log(r.Context(), core.LogLevelWarn, "El usuario "+principal.Username+" no tiene el permiso "+permission, nil)
+				core.EncodeErrorResponse(w, core.NewForbiddenError(permission))
+				return
+			}
+
+			f(w, r)
+		}
+	}
+}
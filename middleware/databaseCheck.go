@@ -1,19 +1,21 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/lucasbravi2019/arquitectura/core"
 )
 
-func DatabaseCheckMiddleware(f func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		err := core.CheckDatabaseHealth()
-		if err != nil {
-			log.Println(err)
-			return
+// DatabaseCheckMiddleware rejects requests with 503 while the health monitor
+// reports the database as down, without pinging it on every call.
+func DatabaseCheckMiddleware(monitor *core.HealthMonitor) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !monitor.IsUp(core.DatabaseComponent) {
+				core.EncodeErrorResponse(w, core.NewUnavailableError(core.DatabaseComponent))
+				return
+			}
+			f(w, r)
 		}
-		f(w, r)
 	}
 }
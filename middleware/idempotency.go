@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// replayedHeaders are the response headers stored with the body; the rest
+// belong to the request that gets the replay, e.g. X-Request-ID.
+var replayedHeaders = []string{"Content-Type", core.ETagHeader}
+
+// IdempotencyMiddleware runs the requests sent with Idempotency-Key once per
+// key, client and route: retries with the same method, path and body get the
+// first response again, and other requests reusing the key a 422. Server
+// errors and panics are not stored, so their retries run again. Bodies over
+// settings.MaxBodyBytes get a 413. Requests without the header, or arriving
+// while the store fails, go through as usual.
+func IdempotencyMiddleware(settings core.IdempotencySettings, store core.IdempotencyStore, route string, trustForwardedFor bool) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(core.IdempotencyKeyHeader)
+
+			if idempotencyKey == "" {
+				f(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > core.MaxIdempotencyKeyLength {
+				core.EncodeErrorResponse(w, core.NewInvalidIdempotencyKeyError())
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(settings.MaxBodyBytes)))
+
+			var tooLarge *http.MaxBytesError
+
+			if errors.As(err, &tooLarge) {
+				core.EncodeErrorResponse(w, core.NewBodyTooLargeError(settings.MaxBodyBytes))
+				return
+			}
+
+			if err != nil {
+				core.EncodeErrorResponse(w, core.NewInvalidBodyError(err))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := route + "|" + clientIdentity(r, trustForwardedFor) + "|" + idempotencyKey
+			fingerprint := requestFingerprint(r, body)
+
+			record, err := store.Start(r.Context(), key, fingerprint, settings.TTL)
+
+			if err != nil {
+				core.LogError(r.Context(), err)
+				f(w, r)
+				return
+			}
+
+			if record != nil {
+				replay(w, record, fingerprint)
+				return
+			}
+
+			response := core.IdempotentResponse{Status: http.StatusOK}
+			var written bytes.Buffer
+
+			// a panic leaves no response to store; the retries run again
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					if err := store.Release(r.Context(), key); err != nil {
+						core.LogError(r.Context(), err)
+					}
+
+					panic(recovered)
+				}
+			}()
+
+			f(httpsnoop.Wrap(w, httpsnoop.Hooks{
+				WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+					return func(code int) {
+						response.Status = code
+						next(code)
+					}
+				},
+				Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+					return func(b []byte) (int, error) {
+						written.Write(b)
+						return next(b)
+					}
+				},
+			}), r)
+
+			if response.Status >= http.StatusInternalServerError {
+				err = store.Release(r.Context(), key)
+			} else {
+				response.Header = http.Header{}
+				response.Body = written.Bytes()
+
+				for _, name := range replayedHeaders {
+					if values := w.Header().Values(name); len(values) > 0 {
+						response.Header[http.CanonicalHeaderKey(name)] = values
+					}
+				}
+
+				err = store.Finish(r.Context(), key, response)
+			}
+
+			if err != nil {
+				core.LogError(r.Context(), err)
+			}
+		}
+	}
+}
+
+func replay(w http.ResponseWriter, record *core.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		core.EncodeErrorResponse(w, core.NewIdempotencyKeyMismatchError())
+		return
+	}
+
+	if record.Response == nil {
+		core.EncodeErrorResponse(w, core.NewIdempotencyKeyInUseError())
+		return
+	}
+
+	for name, values := range record.Response.Header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set(core.IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.Response.Status)
+	w.Write(record.Response.Body)
+}
+
+// requestFingerprint identifies the method, path and body of the request,
+// which retries repeat byte for byte.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
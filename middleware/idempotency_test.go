@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/lucasbravi2019/arquitectura/middleware"
+)
+
+func idempotentRequest(body string) *http.Request {
+	r := httptest.NewRequest("POST", "/budgets", strings.NewReader(body))
+	r.Header.Set(core.IdempotencyKeyHeader, "clave")
+
+	return r
+}
+
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	settings := core.IdempotencySettings{Enabled: true, TTL: time.Hour, MaxBodyBytes: 8}
+	called := false
+
+	handler := middleware.IdempotencyMiddleware(settings, core.NewMemoryIdempotencyStore(), "budgets", false)(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest(`{"name":"casa"}`))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body.String())
+	}
+
+	if called {
+		t.Error("el handler se ejecuto con un cuerpo demasiado grande")
+	}
+}
+
+func TestIdempotencyPanicReleasesKey(t *testing.T) {
+	settings := core.IdempotencySettings{Enabled: true, TTL: time.Hour, MaxBodyBytes: 1024}
+	store := core.NewMemoryIdempotencyStore()
+	panicking := true
+
+	handler := middleware.IdempotencyMiddleware(settings, store, "budgets", false)(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("fallo")
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "fallo" {
+				t.Errorf("recover() = %v, se esperaba el panic del handler", recovered)
+			}
+		}()
+
+		handler(httptest.NewRecorder(), idempotentRequest(`{"name":"casa"}`))
+	}()
+
+	panicking = false
+
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest(`{"name":"casa"}`))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d, se esperaba %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+}
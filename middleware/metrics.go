@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// MetricsMiddleware records request count, latency and in-flight requests
+// labelled by the route template, not the raw path, to keep cardinality low.
+func MetricsMiddleware(metrics *core.Metrics) Middleware {
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			done := metrics.TrackRequest(routeTemplate(r), r.Method)
+			captured := httpsnoop.CaptureMetrics(fn, w, r)
+			done(captured.Code)
+		}
+	}
+}
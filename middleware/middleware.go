@@ -0,0 +1,13 @@
+package middleware
+
+import "net/http"
+
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Chain wraps the handler so the first middleware is the outermost one.
+func Chain(handler http.HandlerFunc, middlewares ...Middleware) http.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
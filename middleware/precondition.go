@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// PreconditionMiddleware rejects with 428 the conditional writes sent without
+// If-Match, so clients cannot overwrite a version they never read. The
+// services compare the header with the stored version.
+func PreconditionMiddleware() Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(core.IfMatchHeader) == "" {
+				core.EncodeErrorResponse(w, core.NewPreconditionRequiredError())
+				return
+			}
+
+			f(w, r)
+		}
+	}
+}
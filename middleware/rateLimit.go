@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// RateLimitMiddleware draws a token per request from the bucket of the client
+// in the route group, and rejects the request with 429 once it is empty. It
+// runs after authentication, so API keys and users get their own buckets and
+// only anonymous clients are told apart by address. When the store fails the
+// request goes through, since a limiter outage should not take the API down.
+func RateLimitMiddleware(settings core.RateLimitSettings, store core.RateLimitStore, group string) Middleware {
+	rule := settings.For(group)
+
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			limit(w, r, f, store, group+"|"+clientIdentity(r, settings.TrustForwardedFor), rule)
+		}
+	}
+}
+
+// AddressRateLimitMiddleware is the limit of the client address, which runs
+// before authentication so that guessing tokens or API keys is limited too.
+func AddressRateLimitMiddleware(settings core.RateLimitSettings, store core.RateLimitStore) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			limit(w, r, f, store, "address|"+addressIdentity(r, settings.TrustForwardedFor), settings.Address)
+		}
+	}
+}
+
+func limit(w http.ResponseWriter, r *http.Request, f http.HandlerFunc, store core.RateLimitStore, key string, rule core.RateLimitRule) {
+	decision, err := store.Take(r.Context(), key, rule)
+
+	if err != nil {
+		core.LogError(r.Context(), err)
+		f(w, r)
+		return
+	}
+
+	// the address and the group limits both run; the headers describe the
+	// one closer to rejecting the request
+	if remaining, err := strconv.Atoi(w.Header().Get("X-RateLimit-Remaining")); err != nil || !decision.Allowed || decision.Remaining < remaining {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Limit()))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	}
+
+	if !decision.Allowed {
+		retryAfter := ceilSeconds(decision.RetryAfter)
+
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		core.EncodeErrorResponse(w, core.NewRateLimitedError(retryAfter))
+		return
+	}
+
+	f(w, r)
+}
+
+func clientIdentity(r *http.Request, trustForwardedFor bool) string {
+	if principal := core.PrincipalFromContext(r.Context()); principal != nil {
+		if !principal.ApiKeyID.IsZero() {
+			return "apikey:" + principal.ApiKeyID.Hex()
+		}
+
+		return "user:" + principal.UserID.Hex()
+	}
+
+	return addressIdentity(r, trustForwardedFor)
+}
+
+func addressIdentity(r *http.Request, trustForwardedFor bool) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); trustForwardedFor && forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return "ip:" + strings.TrimSpace(first)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
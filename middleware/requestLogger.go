@@ -2,34 +2,155 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+	"github.com/lucasbravi2019/arquitectura/core"
 )
 
-func RequestLoggerMiddleware(fn func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var jsonRequest *json.RawMessage = &json.RawMessage{}
+const redactedValue = "[REDACTED]"
 
-		err := json.NewDecoder(r.Body).Decode(jsonRequest)
-		if err != nil && err.Error() != "EOF" {
-			log.Println(err.Error())
-		}
-		r.Body.Close()
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestLoggerMiddleware writes one JSON access log line per request and
+// makes the request ID available to the lower layers through the context.
+func RequestLoggerMiddleware(settings core.LoggingSettings) Middleware {
+	redact := map[string]bool{}
+
+	for _, field := range settings.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			requestId := r.Header.Get(core.RequestIdHeader)
+
+			if !validRequestId.MatchString(requestId) {
+				requestId = newRequestId()
+			}
+
+			w.Header().Set(core.RequestIdHeader, requestId)
+			ctx := core.WithRequestId(r.Context(), requestId)
+			r = r.WithContext(ctx)
+
+			fields := core.LogFields{
+				"method":      r.Method,
+				"route":       routeTemplate(r),
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+				"user_agent":  r.UserAgent(),
+			}
+
+			if settings.LogBodies {
+				addBodyFields(r, settings.MaxBodyBytes, redact, fields)
+			}
+
+			metrics := httpsnoop.CaptureMetrics(fn, w, r)
 
-		body, err := jsonRequest.MarshalJSON()
-		if err != nil {
-			log.Println(err)
+			fields["status"] = metrics.Code
+			fields["bytes"] = metrics.Written
+			fields["latency_ms"] = float64(metrics.Duration) / float64(time.Millisecond)
+
+			core.Log(ctx, accessLogLevel(metrics.Code), "request", fields)
 		}
+	}
+}
 
-		r.Body = io.NopCloser(bytes.NewBuffer(body))
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
 
-		log.Printf("URL Request: %s: %s%s\n", r.Method, r.Host, r.URL.Path)
-		stringBody := string(body)
-		if stringBody != "" {
-			log.Println(stringBody)
+	if route == nil {
+		return ""
+	}
+
+	template, err := route.GetPathTemplate()
+
+	if err != nil {
+		return ""
+	}
+
+	return template
+}
+
+func accessLogLevel(status int) string {
+	if status >= http.StatusInternalServerError {
+		return core.LogLevelError
+	}
+	if status >= http.StatusBadRequest {
+		return core.LogLevelWarn
+	}
+	return core.LogLevelInfo
+}
+
+// addBodyFields reads at most maxBytes of the body for the log and puts the
+// full body back for the handler.
+func addBodyFields(r *http.Request, maxBytes int, redact map[string]bool, fields core.LogFields) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+
+	if err != nil {
+		core.LogError(r.Context(), err)
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+
+	if len(head) == 0 {
+		return
+	}
+
+	// a cut JSON document can not be redacted safely, so it is not logged
+	if len(head) > maxBytes {
+		fields["body_truncated"] = true
+		return
+	}
+
+	var body any
+
+	if json.Unmarshal(head, &body) != nil {
+		fields["body"] = "[non-json body]"
+		return
+	}
+
+	fields["body"] = redactValue(body, redact)
+}
+
+func redactValue(value any, redact map[string]bool) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, nested := range typed {
+			if redact[strings.ToLower(key)] {
+				typed[key] = redactedValue
+			} else {
+				typed[key] = redactValue(nested, redact)
+			}
+		}
+		return typed
+	case []any:
+		for i, nested := range typed {
+			typed[i] = redactValue(nested, redact)
 		}
-		fn(w, r)
+		return typed
+	default:
+		return value
 	}
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware continues the trace received in the traceparent header, or
+// starts a new one, with a server span named after the route template.
+func TracingMiddleware() Middleware {
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := core.StartSpan(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.HTTPTarget(r.URL.RequestURI()),
+				),
+			)
+			defer span.End()
+
+			captured := httpsnoop.CaptureMetrics(fn, w, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPStatusCode(captured.Code))
+
+			if captured.Code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(captured.Code))
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/apikey"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type apiKeyRepository struct {
+	store *Store
+}
+
+func NewApiKeyRepository(store *Store) apikey.ApiKeyRepository {
+	return &apiKeyRepository{
+		store: store,
+	}
+}
+
+func (r *apiKeyRepository) FindApiKeys(ctx context.Context, page core.PageRequest) ([]apikey.ApiKey, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	keys := []apikey.ApiKey{}
+	organizationId := core.TenantFromContext(ctx)
+
+	for _, k := range r.store.apiKeys {
+		if k.OrganizationID == organizationId {
+			keys = append(keys, copyApiKey(k))
+		}
+	}
+
+	found, total := sortAndPage(keys, page, func(k apikey.ApiKey) primitive.ObjectID {
+		return k.ID
+	}, compareApiKeys)
+
+	return found, total, nil
+}
+
+func (r *apiKeyRepository) FindApiKeyByOID(ctx context.Context, oid *primitive.ObjectID) *apikey.ApiKey {
+	organizationId := core.TenantFromContext(ctx)
+
+	return r.findFirst(ctx, func(k apikey.ApiKey) bool {
+		return k.ID == *oid && k.OrganizationID == organizationId
+	})
+}
+
+func (r *apiKeyRepository) FindApiKeyToVerify(ctx context.Context, oid *primitive.ObjectID) *apikey.ApiKey {
+	return r.findFirst(ctx, func(k apikey.ApiKey) bool {
+		return k.ID == *oid
+	})
+}
+
+func (r *apiKeyRepository) CreateApiKey(ctx context.Context, created *apikey.ApiKey) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	r.store.apiKeys = append(r.store.apiKeys, copyApiKey(*created))
+
+	return nil
+}
+
+func (r *apiKeyRepository) UpdateApiKeySecret(ctx context.Context, oid *primitive.ObjectID, secretHash string) error {
+	organizationId := core.TenantFromContext(ctx)
+
+	return r.update(ctx, func(k *apikey.ApiKey) {
+		if k.ID == *oid && k.OrganizationID == organizationId {
+			k.SecretHash = secretHash
+		}
+	})
+}
+
+func (r *apiKeyRepository) RevokeApiKey(ctx context.Context, oid *primitive.ObjectID, revokedAt time.Time) error {
+	organizationId := core.TenantFromContext(ctx)
+
+	return r.update(ctx, func(k *apikey.ApiKey) {
+		if k.ID == *oid && k.OrganizationID == organizationId {
+			k.RevokedAt = copyTime(&revokedAt)
+		}
+	})
+}
+
+func (r *apiKeyRepository) UpdateApiKeyLastUsed(ctx context.Context, oid *primitive.ObjectID, usedAt time.Time) error {
+	return r.update(ctx, func(k *apikey.ApiKey) {
+		if k.ID == *oid {
+			k.LastUsedAt = copyTime(&usedAt)
+		}
+	})
+}
+
+func (r *apiKeyRepository) findFirst(ctx context.Context, matches func(k apikey.ApiKey) bool) *apikey.ApiKey {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	for _, k := range r.store.apiKeys {
+		if matches(k) {
+			found := copyApiKey(k)
+			return &found
+		}
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) update(ctx context.Context, apply func(k *apikey.ApiKey)) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for i := range r.store.apiKeys {
+		apply(&r.store.apiKeys[i])
+	}
+
+	return nil
+}
+
+func compareApiKeys(a apikey.ApiKey, b apikey.ApiKey, field string) int {
+	if field == "name" {
+		return compareStrings(a.Name, b.Name)
+	}
+
+	return 0
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type auditRepository struct {
+	store *Store
+}
+
+func NewAuditRepository(store *Store) audit.AuditRepository {
+	return &auditRepository{
+		store: store,
+	}
+}
+
+func (r *auditRepository) FindEntries(ctx context.Context, filter audit.EntryFilter, page core.PageRequest) ([]audit.Entry, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	entries := []audit.Entry{}
+	organizationId := core.TenantFromContext(ctx)
+
+	for _, e := range r.store.auditLog {
+		if e.OrganizationID == organizationId && matchesEntryFilter(e, filter) {
+			entries = append(entries, e)
+		}
+	}
+
+	found, total := sortAndPage(entries, page, func(e audit.Entry) primitive.ObjectID {
+		return e.ID
+	}, func(a audit.Entry, b audit.Entry, field string) int {
+		return 0
+	})
+
+	return found, total, nil
+}
+
+// CreateEntries keeps the snapshots as they are: entries are never updated
+// and snapshots are built fresh for every entry.
+func (r *auditRepository) CreateEntries(ctx context.Context, entries []audit.Entry) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	for i := range entries {
+		if entries[i].ID.IsZero() {
+			entries[i].ID = primitive.NewObjectID()
+		}
+
+		r.store.auditLog = append(r.store.auditLog, entries[i])
+	}
+
+	return nil
+}
+
+// matchesEntryFilter mirrors the audit.FilterEntries query.
+func matchesEntryFilter(e audit.Entry, filter audit.EntryFilter) bool {
+	if filter.EntityType != "" && e.EntityType != filter.EntityType {
+		return false
+	}
+
+	if filter.EntityID != nil && e.EntityID != *filter.EntityID {
+		return false
+	}
+
+	if filter.Actor != "" && e.Actor != filter.Actor {
+		return false
+	}
+
+	if filter.Operation != "" && e.Operation != filter.Operation {
+		return false
+	}
+
+	if filter.RequestID != "" && e.RequestID != filter.RequestID {
+		return false
+	}
+
+	if filter.From != nil && e.Timestamp.Before(*filter.From) {
+		return false
+	}
+
+	if filter.To != nil && !e.Timestamp.Before(*filter.To) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,393 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type budgetRepository struct {
+	store *Store
+}
+
+func NewBudgetRepository(store *Store) budget.BudgetRepository {
+	return &budgetRepository{
+		store: store,
+	}
+}
+
+func (r *budgetRepository) FindBudgets(ctx context.Context, filter budget.BudgetFilter, page core.PageRequest) ([]budget.BudgetDTO, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	budgets := []budget.BudgetDTO{}
+
+	for _, b := range tenant.budgets {
+		if matchesBudgetFilter(b, filter) {
+			budgets = append(budgets, copyBudget(b))
+		}
+	}
+
+	found, total := sortAndPage(budgets, page, func(b budget.BudgetDTO) primitive.ObjectID {
+		return b.ID
+	}, compareBudgets)
+
+	return found, total, nil
+}
+
+func (r *budgetRepository) FindBudgetByOID(ctx context.Context, oid *primitive.ObjectID) *budget.BudgetDTO {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	index := r.indexOf(tenant, *oid)
+
+	if index < 0 {
+		return nil
+	}
+
+	found := copyBudget(tenant.budgets[index])
+
+	return &found
+}
+
+func (r *budgetRepository) FindBudgetsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []budget.BudgetDTO {
+	budgets := []budget.BudgetDTO{}
+
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return budgets
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	for _, b := range tenant.budgets {
+		if hasDimension(b, *dimensionId) {
+			budgets = append(budgets, copyBudget(b))
+		}
+	}
+
+	return budgets
+}
+
+func (r *budgetRepository) FindBudgetsByMaterialId(ctx context.Context, materialId *primitive.ObjectID) []budget.BudgetDTO {
+	budgets := []budget.BudgetDTO{}
+
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return budgets
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	for _, b := range tenant.budgets {
+		if matchesBudgetFilter(b, budget.BudgetFilter{MaterialId: materialId}) {
+			budgets = append(budgets, copyBudget(b))
+		}
+	}
+
+	return budgets
+}
+
+func (r *budgetRepository) CreateBudget(ctx context.Context, budgetName *budget.BudgetNameDTO) *primitive.ObjectID {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	tenant := r.store.writableTenant(ctx)
+
+	id := primitive.NewObjectID()
+
+	tenant.budgets = append(tenant.budgets, budget.BudgetDTO{
+		ID:      id,
+		Name:    budgetName.Name,
+		Version: 1,
+	})
+
+	return &id
+}
+
+func (r *budgetRepository) UpdateBudgetName(ctx context.Context, oid *primitive.ObjectID, version int64, budgetName *budget.BudgetNameDTO) error {
+	return r.updateVersion(ctx, *oid, version, func(tenant *collections, index int) {
+		tenant.budgets[index].Name = budgetName.Name
+	})
+}
+
+func (r *budgetRepository) AddMaterialToBudget(ctx context.Context, oid *primitive.ObjectID, version int64, budgetMaterial *budget.BudgetMaterial) error {
+	return r.updateVersion(ctx, *oid, version, func(tenant *collections, index int) {
+		added := toMaterialsDTO(*budgetMaterial)
+
+		// $addToSet only adds the element when an identical one is not present
+		for _, existing := range tenant.budgets[index].Materials {
+			if existing == added {
+				return
+			}
+		}
+
+		tenant.budgets[index].Materials = append(tenant.budgets[index].Materials, added)
+	})
+}
+
+func (r *budgetRepository) RemoveMaterialFromBudget(ctx context.Context, oid *primitive.ObjectID, budgetMaterial *budget.BudgetMaterial) error {
+	return r.update(ctx, func(tenant *collections) {
+		index := r.indexOf(tenant, *oid)
+
+		if index < 0 {
+			return
+		}
+
+		tenant.budgets[index].Version++
+		tenant.budgets[index].Materials = pullMaterials(tenant.budgets[index].Materials, func(m budget.MaterialsDTO) bool {
+			return m.ID == budgetMaterial.ID
+		})
+	})
+}
+
+func (r *budgetRepository) DeleteBudget(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	return r.updateVersion(ctx, *oid, version, func(tenant *collections, index int) {
+		tenant.budgets = append(tenant.budgets[:index], tenant.budgets[index+1:]...)
+	})
+}
+
+func (r *budgetRepository) RemoveMaterialByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) error {
+	return r.update(ctx, func(tenant *collections) {
+		for i := range tenant.budgets {
+			if !hasDimension(tenant.budgets[i], *dimensionId) {
+				continue
+			}
+
+			tenant.budgets[i].Version++
+			tenant.budgets[i].Materials = pullMaterials(tenant.budgets[i].Materials, func(m budget.MaterialsDTO) bool {
+				return m.Dimension.ID == *dimensionId
+			})
+		}
+	})
+}
+
+func (r *budgetRepository) UpdateBudgetByIdPrice(ctx context.Context, budgetId *primitive.ObjectID) error {
+	return r.update(ctx, func(tenant *collections) {
+		if index := r.indexOf(tenant, *budgetId); index >= 0 {
+			tenant.budgets[index].Version++
+			tenant.budgets[index].Price = sumPrices(tenant.budgets[index].Materials)
+		}
+	})
+}
+
+func (r *budgetRepository) UpdateMaterialDimensionPrice(ctx context.Context, dimensionId *primitive.ObjectID, price float64) error {
+	return r.update(ctx, func(tenant *collections) {
+		for i := range tenant.budgets {
+			if !hasDimension(tenant.budgets[i], *dimensionId) {
+				continue
+			}
+
+			tenant.budgets[i].Version++
+
+			for j := range tenant.budgets[i].Materials {
+				if tenant.budgets[i].Materials[j].Dimension.ID == *dimensionId {
+					tenant.budgets[i].Materials[j].Dimension.Price = price
+				}
+			}
+		}
+	})
+}
+
+func (r *budgetRepository) UpdateMaterialsPrice(ctx context.Context, dimensionId *primitive.ObjectID, updated budget.BudgetDTO) error {
+	var conflict bool
+
+	err := r.update(ctx, func(tenant *collections) {
+		index := r.indexOf(tenant, updated.ID)
+
+		if index < 0 || tenant.budgets[index].Version != updated.Version || !hasDimension(tenant.budgets[index], *dimensionId) {
+			conflict = true
+			return
+		}
+
+		tenant.budgets[index].Materials = copyBudget(updated).Materials
+		tenant.budgets[index].Price = updated.Price
+		tenant.budgets[index].Version++
+	})
+
+	if err == nil && conflict {
+		return core.ErrVersionConflict
+	}
+
+	return err
+}
+
+func (r *budgetRepository) ReassignMaterial(ctx context.Context, from *primitive.ObjectID, to *primitive.ObjectID, name string) error {
+	return r.update(ctx, func(tenant *collections) {
+		for i := range tenant.budgets {
+			if !matchesBudgetFilter(tenant.budgets[i], budget.BudgetFilter{MaterialId: from}) {
+				continue
+			}
+
+			tenant.budgets[i].Version++
+
+			for j := range tenant.budgets[i].Materials {
+				if tenant.budgets[i].Materials[j].MaterialID == *from {
+					tenant.budgets[i].Materials[j].MaterialID = *to
+					tenant.budgets[i].Materials[j].Name = name
+				}
+			}
+		}
+	})
+}
+
+func (r *budgetRepository) update(ctx context.Context, apply func(tenant *collections)) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	apply(r.store.writableTenant(ctx))
+
+	return nil
+}
+
+// updateVersion applies the change to the budget while it has the version,
+// incrementing it, like the conditional updates of the Mongo repository.
+func (r *budgetRepository) updateVersion(ctx context.Context, oid primitive.ObjectID, version int64, apply func(tenant *collections, index int)) error {
+	var conflict bool
+
+	err := r.update(ctx, func(tenant *collections) {
+		index := r.indexOf(tenant, oid)
+
+		if index < 0 || tenant.budgets[index].Version != version {
+			conflict = true
+			return
+		}
+
+		tenant.budgets[index].Version++
+		apply(tenant, index)
+	})
+
+	if err == nil && conflict {
+		return core.ErrVersionConflict
+	}
+
+	return err
+}
+
+func (r *budgetRepository) indexOf(tenant *collections, oid primitive.ObjectID) int {
+	for i, b := range tenant.budgets {
+		if b.ID == oid {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchesBudgetFilter mirrors the budget.FilterBudgets query.
+func matchesBudgetFilter(b budget.BudgetDTO, filter budget.BudgetFilter) bool {
+	if filter.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(b.Name), strings.ToLower(filter.NamePrefix)) {
+		return false
+	}
+
+	if filter.MinPrice != nil && b.Price < *filter.MinPrice {
+		return false
+	}
+
+	if filter.MaxPrice != nil && b.Price > *filter.MaxPrice {
+		return false
+	}
+
+	if filter.MaterialId != nil {
+		for _, m := range b.Materials {
+			if m.MaterialID == *filter.MaterialId {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func compareBudgets(a budget.BudgetDTO, b budget.BudgetDTO, field string) int {
+	switch field {
+	case "name":
+		return compareStrings(a.Name, b.Name)
+	case "price":
+		return compareFloats(a.Price, b.Price)
+	default:
+		return 0
+	}
+}
+
+func hasDimension(b budget.BudgetDTO, dimensionId primitive.ObjectID) bool {
+	for _, m := range b.Materials {
+		if m.Dimension.ID == dimensionId {
+			return true
+		}
+	}
+	return false
+}
+
+func pullMaterials(materials []budget.MaterialsDTO, matches func(budget.MaterialsDTO) bool) []budget.MaterialsDTO {
+	kept := []budget.MaterialsDTO{}
+
+	for _, m := range materials {
+		if !matches(m) {
+			kept = append(kept, m)
+		}
+	}
+
+	return kept
+}
+
+// sumPrices mirrors the {$sum: "$materials.price"} aggregation stage.
+func sumPrices(materials []budget.MaterialsDTO) float64 {
+	var total float64 = 0
+
+	for _, m := range materials {
+		total += m.Price
+	}
+
+	return total
+}
+
+func toMaterialsDTO(m budget.BudgetMaterial) budget.MaterialsDTO {
+	return budget.MaterialsDTO{
+		ID:         m.ID,
+		MaterialID: m.MaterialID,
+		Name:       m.Name,
+		Price:      m.Price,
+		Dimension: budget.DimensionDTO{
+			ID:       m.Dimension.ID,
+			Metric:   m.Dimension.Metric,
+			Quantity: m.Dimension.Quantity,
+			Price:    m.Dimension.Price,
+		},
+		Quantity: float64(m.Quantity),
+	}
+}
@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type dimensionRepository struct {
+	store *Store
+}
+
+func NewDimensionRepository(store *Store) dimension.DimensionRepository {
+	return &dimensionRepository{
+		store: store,
+	}
+}
+
+func (r *dimensionRepository) FindDimensions(ctx context.Context, filter dimension.DimensionFilter, page core.PageRequest) ([]dimension.Dimension, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	dimensions := []dimension.Dimension{}
+
+	for _, d := range tenant.dimensions {
+		if filter.Metric == "" || d.Metric == filter.Metric {
+			dimensions = append(dimensions, d)
+		}
+	}
+
+	found, total := sortAndPage(dimensions, page, func(d dimension.Dimension) primitive.ObjectID {
+		return d.ID
+	}, compareDimensions)
+
+	return found, total, nil
+}
+
+func (r *dimensionRepository) GetDimensionById(ctx context.Context, oid *primitive.ObjectID) *dimension.Dimension {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	index := r.indexOf(tenant, *oid)
+
+	if index < 0 {
+		return nil
+	}
+
+	found := tenant.dimensions[index]
+
+	return &found
+}
+
+func (r *dimensionRepository) CreateDimension(ctx context.Context, body *dimension.Dimension) *primitive.ObjectID {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	tenant := r.store.writableTenant(ctx)
+
+	created := *body
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	created.Version = 1
+
+	tenant.dimensions = append(tenant.dimensions, created)
+
+	return &created.ID
+}
+
+func (r *dimensionRepository) UpdateDimension(ctx context.Context, oid *primitive.ObjectID, version int64, body *dimension.Dimension) error {
+	return r.updateVersion(ctx, *oid, version, func(tenant *collections, index int) {
+		tenant.dimensions[index].Metric = body.Metric
+		tenant.dimensions[index].Quantity = body.Quantity
+	})
+}
+
+func (r *dimensionRepository) DeleteDimension(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	return r.updateVersion(ctx, *oid, version, func(tenant *collections, index int) {
+		tenant.dimensions = append(tenant.dimensions[:index], tenant.dimensions[index+1:]...)
+	})
+}
+
+func (r *dimensionRepository) update(ctx context.Context, apply func(tenant *collections)) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	apply(r.store.writableTenant(ctx))
+
+	return nil
+}
+
+// updateVersion is budgetRepository.updateVersion for dimensions.
+func (r *dimensionRepository) updateVersion(ctx context.Context, oid primitive.ObjectID, version int64, apply func(tenant *collections, index int)) error {
+	var conflict bool
+
+	err := r.update(ctx, func(tenant *collections) {
+		index := r.indexOf(tenant, oid)
+
+		if index < 0 || tenant.dimensions[index].Version != version {
+			conflict = true
+			return
+		}
+
+		tenant.dimensions[index].Version++
+		apply(tenant, index)
+	})
+
+	if err == nil && conflict {
+		return core.ErrVersionConflict
+	}
+
+	return err
+}
+
+func (r *dimensionRepository) indexOf(tenant *collections, oid primitive.ObjectID) int {
+	for i, d := range tenant.dimensions {
+		if d.ID == oid {
+			return i
+		}
+	}
+	return -1
+}
+
+func compareDimensions(a dimension.Dimension, b dimension.Dimension, field string) int {
+	switch field {
+	case "metric":
+		return compareStrings(a.Metric, b.Metric)
+	case "quantity":
+		return compareFloats(a.Quantity, b.Quantity)
+	default:
+		return 0
+	}
+}
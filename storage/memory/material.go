@@ -0,0 +1,339 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type materialRepository struct {
+	store *Store
+}
+
+func NewMaterialRepository(store *Store) material.MaterialRepository {
+	return &materialRepository{
+		store: store,
+	}
+}
+
+func (r *materialRepository) FindMaterials(ctx context.Context, filter material.MaterialFilter, page core.PageRequest) ([]material.MaterialDTO, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	materials := []material.MaterialDTO{}
+
+	for _, m := range tenant.materials {
+		if matchesMaterialFilter(m, filter) {
+			materials = append(materials, copyMaterial(m))
+		}
+	}
+
+	found, total := sortAndPage(materials, page, func(m material.MaterialDTO) primitive.ObjectID {
+		return m.ID
+	}, compareMaterials)
+
+	return found, total, nil
+}
+
+func (r *materialRepository) FindMaterialByOID(ctx context.Context, oid *primitive.ObjectID) *material.MaterialDTO {
+	return r.findFirst(ctx, func(m material.MaterialDTO) bool {
+		return m.ID == *oid
+	})
+}
+
+func (r *materialRepository) FindMaterialByPackageId(ctx context.Context, dimensionId *primitive.ObjectID) *material.MaterialDTO {
+	return r.findFirst(ctx, func(m material.MaterialDTO) bool {
+		return dimensionIndex(m, *dimensionId) >= 0
+	})
+}
+
+func (r *materialRepository) FindMaterialsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []material.MaterialDTO {
+	materials := []material.MaterialDTO{}
+
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return materials
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	for _, m := range tenant.materials {
+		if dimensionIndex(m, *dimensionId) >= 0 {
+			materials = append(materials, copyMaterial(m))
+		}
+	}
+
+	return materials
+}
+
+func (r *materialRepository) ValidateExistingMaterial(ctx context.Context, materialName *material.MaterialNameDTO, excludedId *primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	return nameTaken(r.store.tenant(ctx), materialName.Name, excludedId)
+}
+
+func (r *materialRepository) CreateMaterial(ctx context.Context, created *material.Material) (*primitive.ObjectID, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	tenant := r.store.writableTenant(ctx)
+
+	// the unique name index of the Mongo repository
+	err := nameTaken(tenant, created.Name, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id := created.ID
+
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+
+	dimensions := []material.DimensionDTO{}
+
+	for _, d := range created.Dimensions {
+		dimensions = append(dimensions, toDimensionDTO(d))
+	}
+
+	tenant.materials = append(tenant.materials, material.MaterialDTO{
+		ID:         id,
+		Name:       created.Name,
+		Dimensions: dimensions,
+		Version:    1,
+	})
+
+	return &id, nil
+}
+
+func (r *materialRepository) UpdateMaterial(ctx context.Context, oid *primitive.ObjectID, version int64, dto *material.MaterialNameDTO) error {
+	var rejected error
+
+	err := r.update(ctx, func(tenant *collections) {
+		index := r.indexOf(tenant, *oid)
+
+		if index < 0 || tenant.materials[index].Version != version {
+			rejected = core.ErrVersionConflict
+			return
+		}
+
+		// the unique name index of the Mongo repository
+		rejected = nameTaken(tenant, dto.Name, oid)
+
+		if rejected != nil {
+			return
+		}
+
+		tenant.materials[index].Version++
+		tenant.materials[index].Name = dto.Name
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return rejected
+}
+
+func (r *materialRepository) DeleteMaterial(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	return r.updateVersion(ctx, func(m material.MaterialDTO) bool {
+		return m.ID == *oid && m.Version == version
+	}, func(tenant *collections, index int) {
+		tenant.materials = append(tenant.materials[:index], tenant.materials[index+1:]...)
+	})
+}
+
+func (r *materialRepository) AddDimensionToMaterial(ctx context.Context, materialOid *primitive.ObjectID, version int64, dimensionOid *primitive.ObjectID, dimension *material.MaterialDimension) error {
+	// the Mongo filter excludes materials that already have the dimension
+	return r.updateVersion(ctx, func(m material.MaterialDTO) bool {
+		return m.ID == *materialOid && m.Version == version && dimensionIndex(m, *dimensionOid) < 0
+	}, func(tenant *collections, index int) {
+		tenant.materials[index].Dimensions = append(tenant.materials[index].Dimensions, toDimensionDTO(*dimension))
+	})
+}
+
+func (r *materialRepository) RemoveDimensionFromMaterials(ctx context.Context, dto material.MaterialDimensionDTO) error {
+	return r.update(ctx, func(tenant *collections) {
+		for i := range tenant.materials {
+			if dimensionIndex(tenant.materials[i], dto.DimensionOid) < 0 {
+				continue
+			}
+
+			tenant.materials[i].Version++
+
+			kept := []material.DimensionDTO{}
+
+			for _, d := range tenant.materials[i].Dimensions {
+				if d.ID != dto.DimensionOid {
+					kept = append(kept, d)
+				}
+			}
+
+			tenant.materials[i].Dimensions = kept
+		}
+	})
+}
+
+func (r *materialRepository) ChangeMaterialPrice(ctx context.Context, dimensionId *primitive.ObjectID, version int64, priceDTO *material.MaterialDimensionPriceDTO) error {
+	// UpdateOne: only the first material holding the dimension is changed
+	return r.updateVersion(ctx, func(m material.MaterialDTO) bool {
+		return m.Version == version && dimensionIndex(m, *dimensionId) >= 0
+	}, func(tenant *collections, index int) {
+		tenant.materials[index].Dimensions[dimensionIndex(tenant.materials[index], *dimensionId)].Price = priceDTO.Price
+	})
+}
+
+func (r *materialRepository) findFirst(ctx context.Context, matches func(material.MaterialDTO) bool) *material.MaterialDTO {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	for _, m := range tenant.materials {
+		if matches(m) {
+			found := copyMaterial(m)
+			return &found
+		}
+	}
+
+	return nil
+}
+
+func (r *materialRepository) update(ctx context.Context, apply func(tenant *collections)) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	apply(r.store.writableTenant(ctx))
+
+	return nil
+}
+
+// updateVersion applies the change to the first material that matches,
+// incrementing its version, or returns core.ErrVersionConflict when none
+// does, like the conditional updates of the Mongo repository.
+func (r *materialRepository) updateVersion(ctx context.Context, matches func(material.MaterialDTO) bool, apply func(tenant *collections, index int)) error {
+	var conflict bool
+
+	err := r.update(ctx, func(tenant *collections) {
+		for i := range tenant.materials {
+			if matches(tenant.materials[i]) {
+				tenant.materials[i].Version++
+				apply(tenant, i)
+				return
+			}
+		}
+
+		conflict = true
+	})
+
+	if err == nil && conflict {
+		return core.ErrVersionConflict
+	}
+
+	return err
+}
+
+func (r *materialRepository) indexOf(tenant *collections, oid primitive.ObjectID) int {
+	for i, m := range tenant.materials {
+		if m.ID == oid {
+			return i
+		}
+	}
+	return -1
+}
+
+// nameTaken returns material.ErrDuplicateMaterialName when a material other
+// than excludedId has the name, compared like the Mongo collation does.
+func nameTaken(tenant *collections, name string, excludedId *primitive.ObjectID) error {
+	for _, m := range tenant.materials {
+		if excludedId != nil && m.ID == *excludedId {
+			continue
+		}
+
+		if core.NormalizeText(m.Name) == core.NormalizeText(name) {
+			return material.ErrDuplicateMaterialName
+		}
+	}
+
+	return nil
+}
+
+func dimensionIndex(m material.MaterialDTO, dimensionId primitive.ObjectID) int {
+	for i, d := range m.Dimensions {
+		if d.ID == dimensionId {
+			return i
+		}
+	}
+	return -1
+}
+
+func toDimensionDTO(d material.MaterialDimension) material.DimensionDTO {
+	return material.DimensionDTO{
+		ID:       d.ID,
+		Metric:   d.Metric,
+		Quantity: d.Quantity,
+		Price:    d.Price,
+	}
+}
+
+// matchesMaterialFilter mirrors the material.FilterMaterials query.
+func matchesMaterialFilter(m material.MaterialDTO, filter material.MaterialFilter) bool {
+	if filter.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(m.Name), strings.ToLower(filter.NamePrefix)) {
+		return false
+	}
+
+	if filter.Metric != "" {
+		for _, d := range m.Dimensions {
+			if d.Metric == filter.Metric {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func compareMaterials(a material.MaterialDTO, b material.MaterialDTO, field string) int {
+	if field == "name" {
+		return compareStrings(a.Name, b.Name)
+	}
+
+	return 0
+}
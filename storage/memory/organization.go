@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type organizationRepository struct {
+	store *Store
+}
+
+func NewOrganizationRepository(store *Store) organization.OrganizationRepository {
+	return &organizationRepository{
+		store: store,
+	}
+}
+
+func (r *organizationRepository) FindOrganizations(ctx context.Context, page core.PageRequest) ([]organization.Organization, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	organizations := append([]organization.Organization{}, r.store.organizations...)
+
+	found, total := sortAndPage(organizations, page, func(o organization.Organization) primitive.ObjectID {
+		return o.ID
+	}, compareOrganizations)
+
+	return found, total, nil
+}
+
+func (r *organizationRepository) FindOrganizationByOID(ctx context.Context, oid *primitive.ObjectID) *organization.Organization {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	for _, o := range r.store.organizations {
+		if o.ID == *oid {
+			found := o
+			return &found
+		}
+	}
+
+	return nil
+}
+
+func (r *organizationRepository) CreateOrganization(ctx context.Context, created *organization.Organization) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	r.store.organizations = append(r.store.organizations, *created)
+
+	return nil
+}
+
+func compareOrganizations(a organization.Organization, b organization.Organization, field string) int {
+	if field == "name" {
+		return compareStrings(a.Name, b.Name)
+	}
+
+	return 0
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sortAndPage orders the matching documents like the Mongo sort of the page,
+// with the same _id tiebreaker, and returns the requested window along with
+// the number of matches.
+func sortAndPage[T any](items []T, page core.PageRequest, id func(T) primitive.ObjectID, compare func(a T, b T, field string) int) ([]T, int64) {
+	sort.SliceStable(items, func(i, j int) bool {
+		result := compare(items[i], items[j], page.SortField)
+
+		if result == 0 {
+			left, right := id(items[i]), id(items[j])
+			result = bytes.Compare(left[:], right[:])
+		}
+
+		if page.SortDescending {
+			result = -result
+		}
+
+		return result < 0
+	})
+
+	total := int64(len(items))
+
+	if page.Offset >= len(items) {
+		return items[:0], total
+	}
+
+	end := page.Offset + page.Limit
+
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return items[page.Offset:end], total
+}
+
+func compareStrings(a string, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/search"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type searchRepository struct {
+	store *Store
+}
+
+func NewSearchRepository(store *Store) search.SearchRepository {
+	return &searchRepository{
+		store: store,
+	}
+}
+
+func (r *searchRepository) SearchMaterials(ctx context.Context, terms []string, limit int) ([]search.SearchHitDTO, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	hits := []search.SearchHitDTO{}
+
+	for _, m := range tenant.materials {
+		if len(hits) == limit {
+			break
+		}
+
+		if core.SearchScore(terms, m.Name) > 0 {
+			hits = append(hits, search.SearchHitDTO{Type: search.HitTypeMaterial, ID: m.ID, Name: m.Name})
+		}
+	}
+
+	return hits, nil
+}
+
+func (r *searchRepository) SearchBudgets(ctx context.Context, terms []string, limit int) ([]search.SearchHitDTO, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	tenant := r.store.tenant(ctx)
+
+	hits := []search.SearchHitDTO{}
+	found := 0
+
+	for _, b := range tenant.budgets {
+		if found == limit {
+			break
+		}
+
+		document := search.BudgetDocument{ID: b.ID, Name: b.Name}
+		matches := core.SearchScore(terms, b.Name) > 0
+
+		for _, line := range b.Materials {
+			document.Materials = append(document.Materials, search.BudgetLineDocument{ID: line.ID, MaterialID: line.MaterialID, Name: line.Name})
+			matches = matches || core.SearchScore(terms, line.Name) > 0
+		}
+
+		if matches {
+			hits = append(hits, document.Hits()...)
+			found++
+		}
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/apikey"
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store keeps every collection in process memory. Documents are kept in
+// insertion order, like Mongo's natural order, and every read returns copies
+// so callers never share state with the store. Budgets, materials and
+// dimensions are partitioned by organization, which stands in for the tenant
+// filter of the Mongo queries.
+type Store struct {
+	mutex sync.RWMutex
+	// units is held by the running unit of work, see lock.
+	units         sync.Mutex
+	tenants       map[primitive.ObjectID]*collections
+	users         []user.User
+	organizations []organization.Organization
+	apiKeys       []apikey.ApiKey
+	auditLog      []audit.Entry
+}
+
+type collections struct {
+	budgets    []budget.BudgetDTO
+	materials  []material.MaterialDTO
+	dimensions []dimension.Dimension
+}
+
+func NewStore() *Store {
+	return &Store{
+		tenants:       map[primitive.ObjectID]*collections{},
+		users:         []user.User{},
+		organizations: []organization.Organization{},
+		apiKeys:       []apikey.ApiKey{},
+		auditLog:      []audit.Entry{},
+	}
+}
+
+func newCollections() *collections {
+	return &collections{
+		budgets:    []budget.BudgetDTO{},
+		materials:  []material.MaterialDTO{},
+		dimensions: []dimension.Dimension{},
+	}
+}
+
+// tenant returns the collections of the request organization, or empty ones
+// when it has no documents yet. The caller holds the mutex.
+func (s *Store) tenant(ctx context.Context) *collections {
+	if tenant, ok := s.tenants[core.TenantFromContext(ctx)]; ok {
+		return tenant
+	}
+
+	return newCollections()
+}
+
+// writableTenant is like tenant but keeps the collections it creates, so the
+// caller must hold the write lock.
+func (s *Store) writableTenant(ctx context.Context) *collections {
+	oid := core.TenantFromContext(ctx)
+
+	if _, ok := s.tenants[oid]; !ok {
+		s.tenants[oid] = newCollections()
+	}
+
+	return s.tenants[oid]
+}
+
+// lock takes the write lock over the collections a unit of work restores.
+// Writes outside a unit of work first wait for the running unit, so rolling
+// it back never discards them. It returns the function that unlocks.
+func (s *Store) lock(ctx context.Context) func() {
+	inUnit := ctx.Value(unitKey{}) == s
+
+	if !inUnit {
+		s.units.Lock()
+	}
+
+	s.mutex.Lock()
+
+	return func() {
+		s.mutex.Unlock()
+
+		if !inUnit {
+			s.units.Unlock()
+		}
+	}
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func copyBudget(b budget.BudgetDTO) budget.BudgetDTO {
+	materials := make([]budget.MaterialsDTO, len(b.Materials))
+	copy(materials, b.Materials)
+	b.Materials = materials
+	return b
+}
+
+func copyMaterial(m material.MaterialDTO) material.MaterialDTO {
+	dimensions := make([]material.DimensionDTO, len(m.Dimensions))
+	copy(dimensions, m.Dimensions)
+	m.Dimensions = dimensions
+	return m
+}
+
+func copyUser(u user.User) user.User {
+	u.Roles = append([]string{}, u.Roles...)
+	return u
+}
+
+func copyApiKey(k apikey.ApiKey) apikey.ApiKey {
+	k.Scopes = append([]string{}, k.Scopes...)
+	k.LastUsedAt = copyTime(k.LastUsedAt)
+	k.RevokedAt = copyTime(k.RevokedAt)
+	return k
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	copied := *t
+	return &copied
+}
@@ -0,0 +1,419 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/lucasbravi2019/arquitectura/storage/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixture struct {
+	ctx       context.Context
+	budgets   budget.BudgetRepository
+	materials material.MaterialRepository
+}
+
+func newFixture() fixture {
+	store := memory.NewStore()
+
+	return fixture{
+		ctx:       context.Background(),
+		budgets:   memory.NewBudgetRepository(store),
+		materials: memory.NewMaterialRepository(store),
+	}
+}
+
+func organizationContext(organizationId primitive.ObjectID) context.Context {
+	return core.ContextWithPrincipal(context.Background(), &core.Principal{OrganizationID: organizationId})
+}
+
+// createMaterial stores a material with one dimension per price and returns
+// it with the ids generated for them.
+func (f fixture) createMaterial(t *testing.T, name string, prices ...float64) material.MaterialDTO {
+	t.Helper()
+
+	dimensions := []material.MaterialDimension{}
+
+	for _, price := range prices {
+		dimensions = append(dimensions, material.MaterialDimension{
+			ID:       primitive.NewObjectID(),
+			Metric:   "KG",
+			Quantity: 1,
+			Price:    price,
+		})
+	}
+
+	oid, err := f.materials.CreateMaterial(f.ctx, &material.Material{Name: name, Dimensions: dimensions})
+
+	if err != nil {
+		t.Fatalf("CreateMaterial(%q): %v", name, err)
+	}
+
+	return *f.materials.FindMaterialByOID(f.ctx, oid)
+}
+
+// createBudget stores a budget with one line per dimension of the material.
+func (f fixture) createBudget(t *testing.T, name string, m material.MaterialDTO) budget.BudgetDTO {
+	t.Helper()
+
+	oid := f.budgets.CreateBudget(f.ctx, &budget.BudgetNameDTO{Name: name})
+
+	for _, d := range m.Dimensions {
+		found := f.budgets.FindBudgetByOID(f.ctx, oid)
+
+		err := f.budgets.AddMaterialToBudget(f.ctx, oid, found.Version, &budget.BudgetMaterial{
+			ID:         primitive.NewObjectID(),
+			MaterialID: m.ID,
+			Name:       m.Name,
+			Dimension: budget.BudgetMaterialDimension{
+				ID:       d.ID,
+				Metric:   d.Metric,
+				Quantity: d.Quantity,
+				Price:    d.Price,
+			},
+			Quantity: 2,
+			Price:    d.Price * 2,
+		})
+
+		if err != nil {
+			t.Fatalf("AddMaterialToBudget: %v", err)
+		}
+	}
+
+	return *f.budgets.FindBudgetByOID(f.ctx, oid)
+}
+
+func TestChangeMaterialPrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   func(m material.MaterialDTO) int64
+		wantErr   error
+		wantPrice float64
+	}{
+		{
+			name:      "current version",
+			version:   func(m material.MaterialDTO) int64 { return m.Version },
+			wantPrice: 25,
+		},
+		{
+			name:      "stale version",
+			version:   func(m material.MaterialDTO) int64 { return m.Version - 1 },
+			wantErr:   core.ErrVersionConflict,
+			wantPrice: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			m := f.createMaterial(t, "arena", 10, 20)
+			changed := m.Dimensions[0].ID
+
+			err := f.materials.ChangeMaterialPrice(f.ctx, &changed, tt.version(m), &material.MaterialDimensionPriceDTO{Price: 25})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, se esperaba %v", err, tt.wantErr)
+			}
+
+			found := f.materials.FindMaterialByOID(f.ctx, &m.ID)
+
+			if found.Dimensions[0].Price != tt.wantPrice {
+				t.Errorf("precio de la dimension cambiada = %v, se esperaba %v", found.Dimensions[0].Price, tt.wantPrice)
+			}
+
+			// the array filter only matches the changed dimension
+			if found.Dimensions[1].Price != 20 {
+				t.Errorf("precio de la otra dimension = %v, se esperaba 20", found.Dimensions[1].Price)
+			}
+		})
+	}
+}
+
+func TestUpdateMaterialDimensionPrice(t *testing.T) {
+	f := newFixture()
+	m := f.createMaterial(t, "cemento", 10, 20)
+	b := f.createBudget(t, "casa", m)
+	changed := m.Dimensions[0].ID
+
+	err := f.budgets.UpdateMaterialDimensionPrice(f.ctx, &changed, 30)
+
+	if err != nil {
+		t.Fatalf("UpdateMaterialDimensionPrice: %v", err)
+	}
+
+	found := f.budgets.FindBudgetByOID(f.ctx, &b.ID)
+
+	tests := []struct {
+		dimensionId primitive.ObjectID
+		want        float64
+	}{
+		{dimensionId: m.Dimensions[0].ID, want: 30},
+		{dimensionId: m.Dimensions[1].ID, want: 20},
+	}
+
+	for _, tt := range tests {
+		for _, line := range found.Materials {
+			if line.Dimension.ID == tt.dimensionId && line.Dimension.Price != tt.want {
+				t.Errorf("precio de la dimension %s = %v, se esperaba %v", tt.dimensionId.Hex(), line.Dimension.Price, tt.want)
+			}
+		}
+	}
+
+	if found.Version != b.Version+1 {
+		t.Errorf("version = %d, se esperaba %d", found.Version, b.Version+1)
+	}
+}
+
+func TestPullByDimension(t *testing.T) {
+	f := newFixture()
+	m := f.createMaterial(t, "ladrillo", 10, 20)
+	other := f.createMaterial(t, "hierro", 5)
+	b := f.createBudget(t, "casa", m)
+	untouched := f.createBudget(t, "galpon", other)
+	pulled := m.Dimensions[0].ID
+
+	err := f.materials.RemoveDimensionFromMaterials(f.ctx, material.MaterialDimensionDTO{DimensionOid: pulled})
+
+	if err != nil {
+		t.Fatalf("RemoveDimensionFromMaterials: %v", err)
+	}
+
+	err = f.budgets.RemoveMaterialByDimensionId(f.ctx, &pulled)
+
+	if err != nil {
+		t.Fatalf("RemoveMaterialByDimensionId: %v", err)
+	}
+
+	foundMaterial := f.materials.FindMaterialByOID(f.ctx, &m.ID)
+	foundBudget := f.budgets.FindBudgetByOID(f.ctx, &b.ID)
+	foundUntouched := f.budgets.FindBudgetByOID(f.ctx, &untouched.ID)
+
+	tests := []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{name: "material dimensions", got: int64(len(foundMaterial.Dimensions)), want: 1},
+		{name: "material version", got: foundMaterial.Version, want: m.Version + 1},
+		{name: "budget lines", got: int64(len(foundBudget.Materials)), want: 1},
+		{name: "budget version", got: foundBudget.Version, want: b.Version + 1},
+		{name: "lines of the budget without the dimension", got: int64(len(foundUntouched.Materials)), want: 1},
+		{name: "version of the budget without the dimension", got: foundUntouched.Version, want: untouched.Version},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, se esperaba %d", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if foundBudget.Materials[0].Dimension.ID == pulled {
+		t.Errorf("quedo la linea de la dimension %s", pulled.Hex())
+	}
+}
+
+func TestUpdateBudgetByIdPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   float64
+	}{
+		{name: "without lines", want: 0},
+		{name: "one line", prices: []float64{10}, want: 20},
+		{name: "several lines", prices: []float64{10, 2.5, 7}, want: 39},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			m := f.createMaterial(t, "pintura", tt.prices...)
+			b := f.createBudget(t, "casa", m)
+
+			err := f.budgets.UpdateBudgetByIdPrice(f.ctx, &b.ID)
+
+			if err != nil {
+				t.Fatalf("UpdateBudgetByIdPrice: %v", err)
+			}
+
+			found := f.budgets.FindBudgetByOID(f.ctx, &b.ID)
+
+			if found.Price != tt.want {
+				t.Errorf("precio = %v, se esperaba %v", found.Price, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	store := memory.NewStore()
+	budgets := memory.NewBudgetRepository(store)
+	materials := memory.NewMaterialRepository(store)
+
+	owner := organizationContext(primitive.NewObjectID())
+	other := organizationContext(primitive.NewObjectID())
+
+	budgetId := budgets.CreateBudget(owner, &budget.BudgetNameDTO{Name: "casa"})
+	materialId, err := materials.CreateMaterial(owner, &material.Material{Name: "arena", Dimensions: []material.MaterialDimension{}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantFound bool
+	}{
+		{name: "owner organization", ctx: owner, wantFound: true},
+		{name: "other organization", ctx: other, wantFound: false},
+		{name: "default organization", ctx: context.Background(), wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if found := budgets.FindBudgetByOID(tt.ctx, budgetId) != nil; found != tt.wantFound {
+				t.Errorf("presupuesto encontrado = %v, se esperaba %v", found, tt.wantFound)
+			}
+
+			if found := materials.FindMaterialByOID(tt.ctx, materialId) != nil; found != tt.wantFound {
+				t.Errorf("material encontrado = %v, se esperaba %v", found, tt.wantFound)
+			}
+
+			found, total, err := budgets.FindBudgets(tt.ctx, budget.BudgetFilter{}, core.PageRequest{Limit: 10})
+
+			if err != nil {
+				t.Fatalf("FindBudgets: %v", err)
+			}
+
+			if (total == 1) != tt.wantFound || (len(found) == 1) != tt.wantFound {
+				t.Errorf("FindBudgets devolvio %d de %d presupuestos", len(found), total)
+			}
+
+			if tt.wantFound {
+				return
+			}
+
+			err = budgets.UpdateBudgetName(tt.ctx, budgetId, 1, &budget.BudgetNameDTO{Name: "ajeno"})
+
+			if !errors.Is(err, core.ErrVersionConflict) {
+				t.Errorf("UpdateBudgetName de otra organizacion: err = %v, se esperaba %v", err, core.ErrVersionConflict)
+			}
+
+			if name := budgets.FindBudgetByOID(owner, budgetId).Name; name != "casa" {
+				t.Errorf("nombre = %q, se esperaba %q", name, "casa")
+			}
+		})
+	}
+}
+
+func TestVersionConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error
+	}{
+		{
+			name: "stale budget name",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				return f.budgets.UpdateBudgetName(f.ctx, &b.ID, b.Version-1, &budget.BudgetNameDTO{Name: "otro"})
+			},
+		},
+		{
+			name: "stale budget deletion",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				return f.budgets.DeleteBudget(f.ctx, &b.ID, b.Version+1)
+			},
+		},
+		{
+			name: "missing budget",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				missing := primitive.NewObjectID()
+				return f.budgets.UpdateBudgetName(f.ctx, &missing, 1, &budget.BudgetNameDTO{Name: "otro"})
+			},
+		},
+		{
+			name: "stale budget lines",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				b.Version--
+				return f.budgets.UpdateMaterialsPrice(f.ctx, &m.Dimensions[0].ID, b)
+			},
+		},
+		{
+			name: "stale material name",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				return f.materials.UpdateMaterial(f.ctx, &m.ID, m.Version+1, &material.MaterialNameDTO{Name: "otro"})
+			},
+		},
+		{
+			name: "dimension already in the material",
+			write: func(f fixture, b budget.BudgetDTO, m material.MaterialDTO) error {
+				existing := m.Dimensions[0].ID
+				return f.materials.AddDimensionToMaterial(f.ctx, &m.ID, m.Version, &existing, &material.MaterialDimension{ID: existing, Metric: "KG", Quantity: 1, Price: 1})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			m := f.createMaterial(t, "arena", 10)
+			b := f.createBudget(t, "casa", m)
+
+			err := tt.write(f, b, m)
+
+			if !errors.Is(err, core.ErrVersionConflict) {
+				t.Fatalf("err = %v, se esperaba %v", err, core.ErrVersionConflict)
+			}
+
+			if found := f.budgets.FindBudgetByOID(f.ctx, &b.ID); found == nil || found.Version != b.Version || found.Name != b.Name {
+				t.Errorf("el presupuesto cambio: %+v", found)
+			}
+
+			if found := f.materials.FindMaterialByOID(f.ctx, &m.ID); found.Version != m.Version || found.Name != m.Name || len(found.Dimensions) != 1 {
+				t.Errorf("el material cambio: %+v", found)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRolesTenant(t *testing.T) {
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	owner := primitive.NewObjectID()
+
+	created := &user.User{Username: "lucia", OrganizationID: owner, Roles: []string{core.RoleAdmin}}
+
+	if err := users.CreateUser(organizationContext(owner), created); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{name: "other organization", ctx: organizationContext(primitive.NewObjectID()), want: []string{core.RoleAdmin}},
+		{name: "default organization", ctx: context.Background(), want: []string{core.RoleAdmin}},
+		{name: "user organization", ctx: organizationContext(owner), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := users.UpdateUserRoles(tt.ctx, &created.ID, []string{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if roles := users.FindUserByOID(tt.ctx, &created.ID).Roles; !reflect.DeepEqual(roles, tt.want) {
+				t.Errorf("roles = %v, se esperaba %v", roles, tt.want)
+			}
+		})
+	}
+}
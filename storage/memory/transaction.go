@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type unitKey struct{}
+
+type unitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork runs one unit at a time and, when it fails, restores the
+// budgets, materials and dimensions of the organization, the users and the
+// organizations as they were before it. Reads made by other requests
+// meanwhile see the unit's writes.
+func NewUnitOfWork(store *Store) core.UnitOfWork {
+	return &unitOfWork{
+		store: store,
+	}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(unitKey{}) == u.store {
+		return fn(ctx)
+	}
+
+	u.store.units.Lock()
+	defer u.store.units.Unlock()
+
+	organizationId := core.TenantFromContext(ctx)
+
+	u.store.mutex.RLock()
+	saved, existed := u.store.tenants[organizationId]
+
+	if existed {
+		saved = copyCollections(saved)
+	}
+
+	users := copyUsers(u.store.users)
+	organizations := append([]organization.Organization{}, u.store.organizations...)
+
+	u.store.mutex.RUnlock()
+
+	err := fn(context.WithValue(ctx, unitKey{}, u.store))
+
+	if err != nil {
+		u.store.mutex.Lock()
+
+		if existed {
+			u.store.tenants[organizationId] = saved
+		} else {
+			delete(u.store.tenants, organizationId)
+		}
+
+		u.store.users = users
+		u.store.organizations = organizations
+
+		u.store.mutex.Unlock()
+	}
+
+	return err
+}
+
+func copyCollections(c *collections) *collections {
+	copied := newCollections()
+
+	for _, b := range c.budgets {
+		copied.budgets = append(copied.budgets, copyBudget(b))
+	}
+
+	for _, m := range c.materials {
+		copied.materials = append(copied.materials, copyMaterial(m))
+	}
+
+	copied.dimensions = append([]dimension.Dimension{}, c.dimensions...)
+
+	return copied
+}
+
+func copyUsers(users []user.User) []user.User {
+	copied := []user.User{}
+
+	for _, u := range users {
+		copied = append(copied, copyUser(u))
+	}
+
+	return copied
+}
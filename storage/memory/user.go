@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) user.UserRepository {
+	return &userRepository{
+		store: store,
+	}
+}
+
+func (r *userRepository) FindUsers(ctx context.Context, page core.PageRequest) ([]user.User, int64, error) {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	users := []user.User{}
+	organizationId := core.TenantFromContext(ctx)
+
+	for _, u := range r.store.users {
+		if u.OrganizationID == organizationId {
+			users = append(users, copyUser(u))
+		}
+	}
+
+	found, total := sortAndPage(users, page, func(u user.User) primitive.ObjectID {
+		return u.ID
+	}, compareUsers)
+
+	return found, total, nil
+}
+
+func (r *userRepository) FindUserByOID(ctx context.Context, oid *primitive.ObjectID) *user.User {
+	return r.findFirst(ctx, func(u user.User) bool {
+		return u.ID == *oid
+	})
+}
+
+func (r *userRepository) FindUserByUsername(ctx context.Context, username string) *user.User {
+	username = user.NormalizeUsername(username)
+
+	return r.findFirst(ctx, func(u user.User) bool {
+		return u.Username == username
+	})
+}
+
+func (r *userRepository) CreateUser(ctx context.Context, created *user.User) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	created.Username = user.NormalizeUsername(created.Username)
+
+	for _, u := range r.store.users {
+		if u.Username == created.Username {
+			return user.ErrDuplicateUsername
+		}
+	}
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	r.store.users = append(r.store.users, copyUser(*created))
+
+	return nil
+}
+
+func (r *userRepository) UpdateUserRoles(ctx context.Context, oid *primitive.ObjectID, roles []string) error {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	organizationId := core.TenantFromContext(ctx)
+
+	for i := range r.store.users {
+		if r.store.users[i].ID == *oid && r.store.users[i].OrganizationID == organizationId {
+			r.store.users[i].Roles = append([]string{}, roles...)
+		}
+	}
+
+	return nil
+}
+
+func (r *userRepository) findFirst(ctx context.Context, matches func(u user.User) bool) *user.User {
+	if err := ctx.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	r.store.mutex.RLock()
+	defer r.store.mutex.RUnlock()
+
+	for _, u := range r.store.users {
+		if matches(u) {
+			found := copyUser(u)
+			return &found
+		}
+	}
+
+	return nil
+}
+
+func compareUsers(a user.User, b user.User, field string) int {
+	if field == "username" {
+		return compareStrings(a.Username, b.Username)
+	}
+
+	return 0
+}
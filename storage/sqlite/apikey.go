@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/apikey"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const selectApiKeys = `SELECT id, organization_id, name, scopes, secret_hash, created_by, created_at, last_used_at, revoked_at FROM api_keys`
+
+var apiKeySortColumns = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "id",
+}
+
+type apiKeyRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewApiKeyRepository(db *sql.DB, timeouts core.QueryTimeouts) apikey.ApiKeyRepository {
+	return &apiKeyRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *apiKeyRepository) FindApiKeys(ctx context.Context, page core.PageRequest) ([]apikey.ApiKey, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeys")
+	defer cancel()
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys WHERE organization_id = ?`, tenant(ctx)).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, args := pageClause(page, apiKeySortColumns)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectApiKeys+` WHERE organization_id = ?`+order, append([]any{tenant(ctx)}, args...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	keys := []apikey.ApiKey{}
+
+	for rows.Next() {
+		found, err := scanApiKey(rows)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, 0, err
+		}
+
+		keys = append(keys, *found)
+	}
+
+	return keys, total, rows.Err()
+}
+
+func (r *apiKeyRepository) FindApiKeyByOID(ctx context.Context, oid *primitive.ObjectID) *apikey.ApiKey {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeyByOID")
+	defer cancel()
+
+	return r.findApiKey(ctx, selectApiKeys+` WHERE id = ? AND organization_id = ?`, oid.Hex(), tenant(ctx))
+}
+
+func (r *apiKeyRepository) FindApiKeyToVerify(ctx context.Context, oid *primitive.ObjectID) *apikey.ApiKey {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.FindApiKeyToVerify")
+	defer cancel()
+
+	return r.findApiKey(ctx, selectApiKeys+` WHERE id = ?`, oid.Hex())
+}
+
+func (r *apiKeyRepository) CreateApiKey(ctx context.Context, created *apikey.ApiKey) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.CreateApiKey")
+	defer cancel()
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	return exec(ctx, r.db, `INSERT INTO api_keys (id, organization_id, name, scopes, secret_hash, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		created.ID.Hex(), created.OrganizationID.Hex(), created.Name, strings.Join(created.Scopes, ","), created.SecretHash,
+		created.CreatedBy, created.CreatedAt.Format(time.RFC3339Nano))
+}
+
+func (r *apiKeyRepository) UpdateApiKeySecret(ctx context.Context, oid *primitive.ObjectID, secretHash string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.UpdateApiKeySecret")
+	defer cancel()
+
+	return exec(ctx, r.db, `UPDATE api_keys SET secret_hash = ? WHERE id = ? AND organization_id = ?`, secretHash, oid.Hex(), tenant(ctx))
+}
+
+func (r *apiKeyRepository) RevokeApiKey(ctx context.Context, oid *primitive.ObjectID, revokedAt time.Time) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.RevokeApiKey")
+	defer cancel()
+
+	return exec(ctx, r.db, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND organization_id = ?`,
+		revokedAt.Format(time.RFC3339Nano), oid.Hex(), tenant(ctx))
+}
+
+func (r *apiKeyRepository) UpdateApiKeyLastUsed(ctx context.Context, oid *primitive.ObjectID, usedAt time.Time) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "apiKeys.UpdateApiKeyLastUsed")
+	defer cancel()
+
+	return exec(ctx, r.db, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, usedAt.Format(time.RFC3339Nano), oid.Hex())
+}
+
+func (r *apiKeyRepository) findApiKey(ctx context.Context, query string, args ...any) *apikey.ApiKey {
+	found, err := scanApiKey(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError(ctx, err)
+		}
+		return nil
+	}
+
+	return found
+}
+
+// scanApiKey reads the optional timestamps, stored empty when unset.
+func scanApiKey(row scanner) (*apikey.ApiKey, error) {
+	var id, organizationId, scopes, createdAt, lastUsedAt, revokedAt string
+	var found apikey.ApiKey
+
+	err := row.Scan(&id, &organizationId, &found.Name, &scopes, &found.SecretHash, &found.CreatedBy, &createdAt, &lastUsedAt, &revokedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found.ID = parseObjectId(id)
+	found.OrganizationID = parseObjectId(organizationId)
+	found.Scopes = []string{}
+
+	if scopes != "" {
+		found.Scopes = strings.Split(scopes, ",")
+	}
+
+	found.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+
+	if err == nil {
+		found.LastUsedAt, err = parseOptionalTime(lastUsedAt)
+	}
+
+	if err == nil {
+		found.RevokedAt, err = parseOptionalTime(revokedAt)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
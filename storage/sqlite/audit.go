@@ -0,0 +1,226 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/audit"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditTimeLayout has a fixed width, unlike RFC3339Nano, so the stored
+// times compare in order as text.
+const auditTimeLayout = "2006-01-02T15:04:05.000Z"
+
+const selectAuditEntries = `SELECT id, organization_id, actor, actor_id, created_at, entity_type, entity_id, operation, cause, request_id,
+	before_snapshot, after_snapshot FROM audit_log`
+
+var auditSortColumns = map[string]string{
+	core.SortCreatedAt: "id",
+}
+
+type auditRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewAuditRepository(db *sql.DB, timeouts core.QueryTimeouts) audit.AuditRepository {
+	return &auditRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *auditRepository) FindEntries(ctx context.Context, filter audit.EntryFilter, page core.PageRequest) ([]audit.Entry, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "auditLog.FindEntries")
+	defer cancel()
+
+	where, args := entryFilterClause(ctx, filter)
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, pageArgs := pageClause(page, auditSortColumns)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectAuditEntries+where+order, append(args, pageArgs...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	entries := []audit.Entry{}
+
+	for rows.Next() {
+		found, err := scanAuditEntry(rows)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, 0, err
+		}
+
+		entries = append(entries, *found)
+	}
+
+	return entries, total, rows.Err()
+}
+
+func (r *auditRepository) CreateEntries(ctx context.Context, entries []audit.Entry) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "auditLog.CreateEntries")
+	defer cancel()
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		for i := range entries {
+			if entries[i].ID.IsZero() {
+				entries[i].ID = primitive.NewObjectID()
+			}
+
+			before, err := encodeSnapshot(entries[i].Before)
+
+			if err != nil {
+				return err
+			}
+
+			after, err := encodeSnapshot(entries[i].After)
+
+			if err != nil {
+				return err
+			}
+
+			var actorId string
+
+			if !entries[i].ActorID.IsZero() {
+				actorId = entries[i].ActorID.Hex()
+			}
+
+			_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (id, organization_id, actor, actor_id, created_at, entity_type, entity_id,
+				operation, cause, request_id, before_snapshot, after_snapshot) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				entries[i].ID.Hex(), entries[i].OrganizationID.Hex(), entries[i].Actor, actorId,
+				entries[i].Timestamp.UTC().Format(auditTimeLayout), entries[i].EntityType, entries[i].EntityID.Hex(),
+				entries[i].Operation, entries[i].Cause, entries[i].RequestID, before, after)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func scanAuditEntry(row scanner) (*audit.Entry, error) {
+	var id, organizationId, actorId, createdAt, entityId, before, after string
+	var found audit.Entry
+
+	err := row.Scan(&id, &organizationId, &found.Actor, &actorId, &createdAt, &found.EntityType, &entityId,
+		&found.Operation, &found.Cause, &found.RequestID, &before, &after)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found.ID = parseObjectId(id)
+	found.OrganizationID = parseObjectId(organizationId)
+	found.ActorID = parseOptionalObjectId(actorId)
+	found.EntityID = parseObjectId(entityId)
+
+	found.Timestamp, err = time.Parse(auditTimeLayout, createdAt)
+
+	if err == nil {
+		found.Before, err = decodeSnapshot(before)
+	}
+
+	if err == nil {
+		found.After, err = decodeSnapshot(after)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+// snapshots are stored as JSON, empty when the entry has none
+func encodeSnapshot(snapshot audit.Snapshot) (string, error) {
+	if snapshot == nil {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(snapshot)
+
+	return string(encoded), err
+}
+
+func decodeSnapshot(value string) (audit.Snapshot, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var snapshot audit.Snapshot
+
+	err := json.Unmarshal([]byte(value), &snapshot)
+
+	return snapshot, err
+}
+
+// entryFilterClause mirrors the audit.FilterEntries query.
+func entryFilterClause(ctx context.Context, filter audit.EntryFilter) (string, []any) {
+	conditions := []string{`organization_id = ?`}
+	args := []any{tenant(ctx)}
+
+	if filter.EntityType != "" {
+		conditions = append(conditions, `entity_type = ?`)
+		args = append(args, filter.EntityType)
+	}
+
+	if filter.EntityID != nil {
+		conditions = append(conditions, `entity_id = ?`)
+		args = append(args, filter.EntityID.Hex())
+	}
+
+	if filter.Actor != "" {
+		conditions = append(conditions, `actor = ?`)
+		args = append(args, filter.Actor)
+	}
+
+	if filter.Operation != "" {
+		conditions = append(conditions, `operation = ?`)
+		args = append(args, filter.Operation)
+	}
+
+	if filter.RequestID != "" {
+		conditions = append(conditions, `request_id = ?`)
+		args = append(args, filter.RequestID)
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, filter.From.UTC().Format(auditTimeLayout))
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, filter.To.UTC().Format(auditTimeLayout))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
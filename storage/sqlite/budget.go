@@ -0,0 +1,388 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/api/budget"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	selectBudgets = `SELECT id, name, price, version FROM budgets`
+
+	selectBudgetLines = `SELECT id, material_id, name, price, quantity, dimension_id, dimension_metric, dimension_quantity, dimension_price
+		FROM budget_lines WHERE budget_id = ? ORDER BY rowid`
+
+	insertBudgetLine = `INSERT INTO budget_lines
+		(id, budget_id, material_id, name, price, quantity, dimension_id, dimension_metric, dimension_quantity, dimension_price)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	// scopes budget lines, which have no organization of their own
+	tenantBudgets = `SELECT id FROM budgets WHERE organization_id = ?`
+
+	// increments the version of the budgets with a line matching the
+	// condition that follows, before a cascade changes those lines
+	bumpBudgetsWithLines = `UPDATE budgets SET version = version + 1 WHERE organization_id = ? AND id IN (
+		SELECT budget_id FROM budget_lines WHERE `
+
+	// mirrors the {$sum: "$materials.price"} update pipeline
+	updateBudgetPrice = `UPDATE budgets SET price = (
+		SELECT COALESCE(SUM(price), 0) FROM budget_lines WHERE budget_id = budgets.id), version = version + 1`
+)
+
+var budgetSortColumns = map[string]string{
+	"name":             "name",
+	"price":            "price",
+	core.SortCreatedAt: "id",
+}
+
+type budgetRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewBudgetRepository(db *sql.DB, timeouts core.QueryTimeouts) budget.BudgetRepository {
+	return &budgetRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *budgetRepository) FindBudgets(ctx context.Context, filter budget.BudgetFilter, page core.PageRequest) ([]budget.BudgetDTO, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgets")
+	defer cancel()
+
+	where, args := budgetFilterClause(ctx, filter)
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM budgets`+where, args...).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, pageArgs := pageClause(page, budgetSortColumns)
+
+	budgets, err := r.findBudgets(ctx, selectBudgets+where+order, append(args, pageArgs...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return budgets, total, nil
+}
+
+func (r *budgetRepository) FindBudgetByOID(ctx context.Context, oid *primitive.ObjectID) *budget.BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetByOID")
+	defer cancel()
+
+	budgets, err := r.findBudgets(ctx, selectBudgets+` WHERE id = ? AND organization_id = ?`, oid.Hex(), tenant(ctx))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	return &budgets[0]
+}
+
+func (r *budgetRepository) FindBudgetsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []budget.BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetsByDimensionId")
+	defer cancel()
+
+	budgets, err := r.findBudgets(ctx, selectBudgets+` WHERE id IN (
+		SELECT budget_id FROM budget_lines WHERE dimension_id = ?) AND organization_id = ? ORDER BY rowid`, dimensionId.Hex(), tenant(ctx))
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return budgets
+}
+
+func (r *budgetRepository) FindBudgetsByMaterialId(ctx context.Context, materialId *primitive.ObjectID) []budget.BudgetDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.FindBudgetsByMaterialId")
+	defer cancel()
+
+	budgets, err := r.findBudgets(ctx, selectBudgets+` WHERE id IN (
+		SELECT budget_id FROM budget_lines WHERE material_id = ?) AND organization_id = ? ORDER BY rowid`, materialId.Hex(), tenant(ctx))
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return budgets
+}
+
+func (r *budgetRepository) CreateBudget(ctx context.Context, budgetName *budget.BudgetNameDTO) *primitive.ObjectID {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.CreateBudget")
+	defer cancel()
+
+	id := primitive.NewObjectID()
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO budgets (id, name, organization_id) VALUES (?, ?, ?)`, id.Hex(), budgetName.Name, tenant(ctx))
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	return &id
+}
+
+func (r *budgetRepository) UpdateBudgetName(ctx context.Context, oid *primitive.ObjectID, version int64, budgetName *budget.BudgetNameDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateBudgetName")
+	defer cancel()
+
+	return execVersioned(ctx, conn(ctx, r.db), `UPDATE budgets SET name = ?, version = version + 1 WHERE id = ? AND organization_id = ? AND version = ?`,
+		budgetName.Name, oid.Hex(), tenant(ctx), version)
+}
+
+func (r *budgetRepository) AddMaterialToBudget(ctx context.Context, oid *primitive.ObjectID, version int64, line *budget.BudgetMaterial) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.AddMaterialToBudget")
+	defer cancel()
+
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := execVersioned(ctx, tx, `UPDATE budgets SET version = version + 1 WHERE id = ? AND organization_id = ? AND version = ?`,
+			oid.Hex(), tenant(ctx), version)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, insertBudgetLine, line.ID.Hex(), oid.Hex(), materialIdColumn(line.MaterialID), line.Name, line.Price, float64(line.Quantity),
+			line.Dimension.ID.Hex(), line.Dimension.Metric, line.Dimension.Quantity, line.Dimension.Price)
+
+		if err != nil {
+			core.LogError(ctx, err)
+		}
+
+		return err
+	})
+}
+
+func (r *budgetRepository) RemoveMaterialFromBudget(ctx context.Context, oid *primitive.ObjectID, line *budget.BudgetMaterial) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.RemoveMaterialFromBudget")
+	defer cancel()
+
+	return r.cascade(ctx, []any{oid.Hex(), tenant(ctx)},
+		`UPDATE budgets SET version = version + 1 WHERE id = ? AND organization_id = ?`,
+		`DELETE FROM budget_lines WHERE budget_id = ? AND id = ? AND budget_id IN (`+tenantBudgets+`)`, oid.Hex(), line.ID.Hex(), tenant(ctx))
+}
+
+func (r *budgetRepository) DeleteBudget(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.DeleteBudget")
+	defer cancel()
+
+	return execVersioned(ctx, conn(ctx, r.db), `DELETE FROM budgets WHERE id = ? AND organization_id = ? AND version = ?`, oid.Hex(), tenant(ctx), version)
+}
+
+func (r *budgetRepository) RemoveMaterialByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.RemoveMaterialByDimensionId")
+	defer cancel()
+
+	return r.cascade(ctx, []any{tenant(ctx), dimensionId.Hex()}, bumpBudgetsWithLines+`dimension_id = ?)`,
+		`DELETE FROM budget_lines WHERE dimension_id = ? AND budget_id IN (`+tenantBudgets+`)`, dimensionId.Hex(), tenant(ctx))
+}
+
+func (r *budgetRepository) UpdateBudgetByIdPrice(ctx context.Context, budgetId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateBudgetByIdPrice")
+	defer cancel()
+
+	return exec(ctx, r.db, updateBudgetPrice+` WHERE id = ? AND organization_id = ?`, budgetId.Hex(), tenant(ctx))
+}
+
+func (r *budgetRepository) UpdateMaterialDimensionPrice(ctx context.Context, dimensionId *primitive.ObjectID, price float64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateMaterialDimensionPrice")
+	defer cancel()
+
+	return r.cascade(ctx, []any{tenant(ctx), dimensionId.Hex()}, bumpBudgetsWithLines+`dimension_id = ?)`,
+		`UPDATE budget_lines SET dimension_price = ? WHERE dimension_id = ? AND budget_id IN (`+tenantBudgets+`)`, price, dimensionId.Hex(), tenant(ctx))
+}
+
+func (r *budgetRepository) UpdateMaterialsPrice(ctx context.Context, dimensionId *primitive.ObjectID, updated budget.BudgetDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.UpdateMaterialsPrice")
+	defer cancel()
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := execVersioned(ctx, tx, `UPDATE budgets SET price = ?, version = version + 1 WHERE id = ? AND organization_id = ? AND version = ?
+			AND id IN (SELECT budget_id FROM budget_lines WHERE dimension_id = ?)`,
+			updated.Price, updated.ID.Hex(), tenant(ctx), updated.Version, dimensionId.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM budget_lines WHERE budget_id = ?`, updated.ID.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		for _, line := range updated.Materials {
+			_, err = tx.ExecContext(ctx, insertBudgetLine, line.ID.Hex(), updated.ID.Hex(), materialIdColumn(line.MaterialID), line.Name, line.Price, line.Quantity,
+				line.Dimension.ID.Hex(), line.Dimension.Metric, line.Dimension.Quantity, line.Dimension.Price)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, core.ErrVersionConflict) {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func (r *budgetRepository) ReassignMaterial(ctx context.Context, from *primitive.ObjectID, to *primitive.ObjectID, name string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.ReassignMaterial")
+	defer cancel()
+
+	return r.cascade(ctx, []any{tenant(ctx), from.Hex()}, bumpBudgetsWithLines+`material_id = ?)`,
+		`UPDATE budget_lines SET material_id = ?, name = ? WHERE material_id = ? AND budget_id IN (`+tenantBudgets+`)`, to.Hex(), name, from.Hex(), tenant(ctx))
+}
+
+// cascade increments the version of the budgets the bump statement selects,
+// then changes their lines, so clients holding an older ETag get a 412.
+func (r *budgetRepository) cascade(ctx context.Context, bumpArgs []any, bump string, query string, args ...any) error {
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, bump, bumpArgs...)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+
+		return err
+	})
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func (r *budgetRepository) findBudgets(ctx context.Context, query string, args ...any) ([]budget.BudgetDTO, error) {
+	budgets := []budget.BudgetDTO{}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return budgets, err
+	}
+
+	for rows.Next() {
+		var id string
+		var found budget.BudgetDTO
+
+		err = rows.Scan(&id, &found.Name, &found.Price, &found.Version)
+
+		if err != nil {
+			rows.Close()
+			return []budget.BudgetDTO{}, err
+		}
+
+		found.ID = parseObjectId(id)
+		budgets = append(budgets, found)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return []budget.BudgetDTO{}, err
+	}
+
+	for i := range budgets {
+		budgets[i].Materials, err = r.findLines(ctx, budgets[i].ID)
+
+		if err != nil {
+			return []budget.BudgetDTO{}, err
+		}
+	}
+
+	return budgets, nil
+}
+
+func (r *budgetRepository) findLines(ctx context.Context, budgetId primitive.ObjectID) ([]budget.MaterialsDTO, error) {
+	lines := []budget.MaterialsDTO{}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectBudgetLines, budgetId.Hex())
+
+	if err != nil {
+		return lines, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, materialId, dimensionId string
+		var line budget.MaterialsDTO
+
+		err = rows.Scan(&id, &materialId, &line.Name, &line.Price, &line.Quantity,
+			&dimensionId, &line.Dimension.Metric, &line.Dimension.Quantity, &line.Dimension.Price)
+
+		if err != nil {
+			return lines, err
+		}
+
+		line.ID = parseObjectId(id)
+		line.MaterialID = parseOptionalObjectId(materialId)
+		line.Dimension.ID = parseObjectId(dimensionId)
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// budgetFilterClause mirrors the budget.FilterBudgets query.
+func budgetFilterClause(ctx context.Context, filter budget.BudgetFilter) (string, []any) {
+	conditions := []string{`organization_id = ?`}
+	args := []any{tenant(ctx)}
+
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, likePrefix(filter.NamePrefix))
+	}
+
+	if filter.MinPrice != nil {
+		conditions = append(conditions, `price >= ?`)
+		args = append(args, *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, `price <= ?`)
+		args = append(args, *filter.MaxPrice)
+	}
+
+	if filter.MaterialId != nil {
+		conditions = append(conditions, `id IN (SELECT budget_id FROM budget_lines WHERE material_id = ?)`)
+		args = append(args, filter.MaterialId.Hex())
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func materialIdColumn(materialId primitive.ObjectID) string {
+	if materialId.IsZero() {
+		return ""
+	}
+
+	return materialId.Hex()
+}
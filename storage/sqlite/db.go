@@ -0,0 +1,330 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// versionColumn starts every row at the version Mongo documents get when
+// created or backfilled.
+const versionColumn = `INTEGER NOT NULL DEFAULT 1`
+
+// organizationColumn defaults to core.DefaultOrganizationID, which owns the
+// rows written before organizations existed.
+const organizationColumn = `TEXT NOT NULL DEFAULT '000000000000000000000001'`
+
+const schema = `
+CREATE TABLE IF NOT EXISTS organizations (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS budgets (
+	id              TEXT PRIMARY KEY,
+	name            TEXT NOT NULL,
+	price           REAL NOT NULL DEFAULT 0,
+	version         ` + versionColumn + `,
+	organization_id ` + organizationColumn + `
+);
+
+CREATE TABLE IF NOT EXISTS budget_lines (
+	id                 TEXT PRIMARY KEY,
+	budget_id          TEXT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+	material_id        TEXT NOT NULL DEFAULT '',
+	name               TEXT NOT NULL,
+	price              REAL NOT NULL DEFAULT 0,
+	quantity           REAL NOT NULL DEFAULT 0,
+	dimension_id       TEXT NOT NULL,
+	dimension_metric   TEXT NOT NULL DEFAULT '',
+	dimension_quantity REAL NOT NULL DEFAULT 0,
+	dimension_price    REAL NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS budget_lines_budget_id ON budget_lines(budget_id);
+CREATE INDEX IF NOT EXISTS budget_lines_dimension_id ON budget_lines(dimension_id);
+
+CREATE TABLE IF NOT EXISTS materials (
+	id              TEXT PRIMARY KEY,
+	name            TEXT NOT NULL,
+	normalized_name TEXT NOT NULL DEFAULT '',
+	version         ` + versionColumn + `,
+	organization_id ` + organizationColumn + `
+);
+
+CREATE TABLE IF NOT EXISTS material_dimensions (
+	material_id  TEXT NOT NULL REFERENCES materials(id) ON DELETE CASCADE,
+	dimension_id TEXT NOT NULL,
+	metric       TEXT NOT NULL DEFAULT '',
+	quantity     REAL NOT NULL DEFAULT 0,
+	price        REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (material_id, dimension_id)
+);
+
+CREATE INDEX IF NOT EXISTS material_dimensions_dimension_id ON material_dimensions(dimension_id);
+
+CREATE TABLE IF NOT EXISTS dimensions (
+	id              TEXT PRIMARY KEY,
+	metric          TEXT NOT NULL,
+	quantity        REAL NOT NULL,
+	version         ` + versionColumn + `,
+	organization_id ` + organizationColumn + `
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id            TEXT PRIMARY KEY,
+	username      TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	roles         TEXT NOT NULL DEFAULT '',
+	created_at    TEXT NOT NULL,
+	organization_id ` + organizationColumn + `
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id              TEXT PRIMARY KEY,
+	organization_id TEXT NOT NULL,
+	name            TEXT NOT NULL,
+	scopes          TEXT NOT NULL DEFAULT '',
+	secret_hash     TEXT NOT NULL,
+	created_by      TEXT NOT NULL DEFAULT '',
+	created_at      TEXT NOT NULL,
+	last_used_at    TEXT NOT NULL DEFAULT '',
+	revoked_at      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id              TEXT PRIMARY KEY,
+	organization_id TEXT NOT NULL,
+	actor           TEXT NOT NULL,
+	actor_id        TEXT NOT NULL DEFAULT '',
+	created_at      TEXT NOT NULL,
+	entity_type     TEXT NOT NULL,
+	entity_id       TEXT NOT NULL,
+	operation       TEXT NOT NULL,
+	cause           TEXT NOT NULL DEFAULT '',
+	request_id      TEXT NOT NULL DEFAULT '',
+	before_snapshot TEXT NOT NULL DEFAULT '',
+	after_snapshot  TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entity ON audit_log(organization_id, entity_id);
+`
+
+// Open opens the database file, creating it and its schema when missing.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL", path))
+
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir la base de datos sqlite: %w", err)
+	}
+
+	// a single writer avoids SQLITE_BUSY errors between concurrent requests
+	db.SetMaxOpenConns(1)
+
+	_, err = db.ExecContext(ctx, schema)
+
+	if err == nil {
+		err = addMissingColumns(ctx, db)
+	}
+
+	if err == nil {
+		_, err = db.ExecContext(ctx, searchSchema)
+	}
+
+	if err == nil {
+		err = rebuildSearchIndexes(ctx, db)
+	}
+
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("no se pudo crear el esquema sqlite: %w", err)
+	}
+
+	return db, nil
+}
+
+// addedColumns lists the columns introduced after a table was first released,
+// which CREATE TABLE IF NOT EXISTS does not add to existing database files.
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{table: "budget_lines", column: "material_id", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "users", column: "roles", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "budgets", column: "organization_id", definition: organizationColumn},
+	{table: "materials", column: "organization_id", definition: organizationColumn},
+	{table: "dimensions", column: "organization_id", definition: organizationColumn},
+	{table: "users", column: "organization_id", definition: organizationColumn},
+	{table: "budgets", column: "version", definition: versionColumn},
+	{table: "materials", column: "version", definition: versionColumn},
+	{table: "dimensions", column: "version", definition: versionColumn},
+	{table: "materials", column: "normalized_name", definition: "TEXT NOT NULL DEFAULT ''"},
+}
+
+func addMissingColumns(ctx context.Context, db *sql.DB) error {
+	for _, added := range addedColumns {
+		var exists int
+
+		err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, added.table, added.column).Scan(&exists)
+
+		if err != nil {
+			return err
+		}
+
+		if exists > 0 {
+			continue
+		}
+
+		_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func HealthCheck(db *sql.DB) func(ctx context.Context) error {
+	return db.PingContext
+}
+
+// querier runs the statements of a repository, either on the database or on
+// the transaction of the unit of work running the request.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction of the unit of work in the context, if any.
+// The database has a single connection, which the transaction holds, so a
+// statement run on db while it is open would wait for it forever.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// withTx runs fn inside a transaction, rolling it back when fn fails. Within
+// a unit of work it joins the unit's transaction instead.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func exec(ctx context.Context, db *sql.DB, query string, args ...any) error {
+	_, err := conn(ctx, db).ExecContext(ctx, query, args...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+// execVersioned runs a conditional write, returning core.ErrVersionConflict
+// when it changes no row, like the versioned updates of the Mongo
+// repositories.
+func execVersioned(ctx context.Context, q querier, query string, args ...any) error {
+	result, err := q.ExecContext(ctx, query, args...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	if affected == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// pageClause translates the page into ORDER BY, LIMIT and OFFSET over the
+// given columns, with the same id tiebreaker Mongo gets from _id. Ids are
+// ObjectID hex strings, so they sort by creation time too.
+func pageClause(page core.PageRequest, columns map[string]string) (string, []any) {
+	direction := "ASC"
+
+	if page.SortDescending {
+		direction = "DESC"
+	}
+
+	clause := " ORDER BY "
+
+	if column, ok := columns[page.SortField]; ok && column != "id" {
+		clause += column + " " + direction + ", "
+	}
+
+	clause += "id " + direction + " LIMIT ? OFFSET ?"
+
+	return clause, []any{page.Limit, page.Offset}
+}
+
+// likePrefix escapes the LIKE wildcards of a prefix match, used with ESCAPE '\'.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+}
+
+// tenant returns the organization of the request, which every query over
+// budgets, materials and dimensions filters by, like core.TenantFilter does
+// for Mongo.
+func tenant(ctx context.Context) string {
+	return core.TenantFromContext(ctx).Hex()
+}
+
+func parseObjectId(hex string) primitive.ObjectID {
+	oid, err := primitive.ObjectIDFromHex(hex)
+
+	if err != nil {
+		log.Println("Object ID invalido en sqlite: " + hex)
+	}
+
+	return oid
+}
+
+// parseOptionalObjectId reads columns that are empty for rows written before
+// the column existed.
+func parseOptionalObjectId(hex string) primitive.ObjectID {
+	if hex == "" {
+		return primitive.NilObjectID
+	}
+
+	return parseObjectId(hex)
+}
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lucasbravi2019/arquitectura/api/dimension"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var dimensionSortColumns = map[string]string{
+	"metric":           "metric",
+	"quantity":         "quantity",
+	core.SortCreatedAt: "id",
+}
+
+type dimensionRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewDimensionRepository(db *sql.DB, timeouts core.QueryTimeouts) dimension.DimensionRepository {
+	return &dimensionRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *dimensionRepository) FindDimensions(ctx context.Context, filter dimension.DimensionFilter, page core.PageRequest) ([]dimension.Dimension, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.FindDimensions")
+	defer cancel()
+
+	where := ` WHERE organization_id = ?`
+	args := []any{tenant(ctx)}
+
+	if filter.Metric != "" {
+		where += ` AND metric = ?`
+		args = append(args, filter.Metric)
+	}
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM dimensions`+where, args...).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, pageArgs := pageClause(page, dimensionSortColumns)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id, metric, quantity, version FROM dimensions`+where+order, append(args, pageArgs...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	dimensions := []dimension.Dimension{}
+
+	for rows.Next() {
+		found, err := scanDimension(rows)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, 0, err
+		}
+
+		dimensions = append(dimensions, *found)
+	}
+
+	if err = rows.Err(); err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return dimensions, total, nil
+}
+
+func (r *dimensionRepository) GetDimensionById(ctx context.Context, oid *primitive.ObjectID) *dimension.Dimension {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.GetDimensionById")
+	defer cancel()
+
+	found, err := scanDimension(conn(ctx, r.db).QueryRowContext(ctx, `SELECT id, metric, quantity, version FROM dimensions WHERE id = ? AND organization_id = ?`, oid.Hex(), tenant(ctx)))
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError(ctx, err)
+		}
+		return nil
+	}
+
+	return found
+}
+
+func (r *dimensionRepository) CreateDimension(ctx context.Context, body *dimension.Dimension) *primitive.ObjectID {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.CreateDimension")
+	defer cancel()
+
+	id := body.ID
+
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+
+	err := exec(ctx, r.db, `INSERT INTO dimensions (id, metric, quantity, organization_id) VALUES (?, ?, ?, ?)`, id.Hex(), body.Metric, body.Quantity, tenant(ctx))
+
+	if err != nil {
+		return nil
+	}
+
+	return &id
+}
+
+func (r *dimensionRepository) UpdateDimension(ctx context.Context, oid *primitive.ObjectID, version int64, body *dimension.Dimension) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.UpdateDimension")
+	defer cancel()
+
+	return execVersioned(ctx, conn(ctx, r.db), `UPDATE dimensions SET metric = ?, quantity = ?, version = version + 1 WHERE id = ? AND organization_id = ? AND version = ?`,
+		body.Metric, body.Quantity, oid.Hex(), tenant(ctx), version)
+}
+
+func (r *dimensionRepository) DeleteDimension(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "dimensions.DeleteDimension")
+	defer cancel()
+
+	return execVersioned(ctx, conn(ctx, r.db), `DELETE FROM dimensions WHERE id = ? AND organization_id = ? AND version = ?`, oid.Hex(), tenant(ctx), version)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDimension(row scanner) (*dimension.Dimension, error) {
+	var id string
+	var found dimension.Dimension
+
+	err := row.Scan(&id, &found.Metric, &found.Quantity, &found.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found.ID = parseObjectId(id)
+
+	return &found, nil
+}
@@ -0,0 +1,390 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scopes material dimensions, which have no organization of their own
+const tenantMaterials = `SELECT id FROM materials WHERE organization_id = ?`
+
+const selectMaterialDimensions = `SELECT dimension_id, metric, quantity, price
+	FROM material_dimensions WHERE material_id = ? ORDER BY rowid`
+
+var materialSortColumns = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "id",
+}
+
+type materialRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewMaterialRepository(db *sql.DB, timeouts core.QueryTimeouts) material.MaterialRepository {
+	return &materialRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *materialRepository) FindMaterials(ctx context.Context, filter material.MaterialFilter, page core.PageRequest) ([]material.MaterialDTO, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterials")
+	defer cancel()
+
+	where, args := materialFilterClause(ctx, filter)
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM materials`+where, args...).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, pageArgs := pageClause(page, materialSortColumns)
+
+	materials, err := r.findMaterials(ctx, `SELECT id, name, version FROM materials`+where+order, append(args, pageArgs...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	return materials, total, nil
+}
+
+func (r *materialRepository) FindMaterialByOID(ctx context.Context, oid *primitive.ObjectID) *material.MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialByOID")
+	defer cancel()
+
+	return r.findMaterial(ctx, `SELECT id, name, version FROM materials WHERE id = ? AND organization_id = ?`, oid.Hex(), tenant(ctx))
+}
+
+func (r *materialRepository) FindMaterialByPackageId(ctx context.Context, dimensionId *primitive.ObjectID) *material.MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialByPackageId")
+	defer cancel()
+
+	return r.findMaterial(ctx, `SELECT m.id, m.name, m.version FROM materials m
+		JOIN material_dimensions md ON md.material_id = m.id
+		WHERE md.dimension_id = ? AND m.organization_id = ? ORDER BY md.rowid LIMIT 1`, dimensionId.Hex(), tenant(ctx))
+}
+
+func (r *materialRepository) FindMaterialsByDimensionId(ctx context.Context, dimensionId *primitive.ObjectID) []material.MaterialDTO {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.FindMaterialsByDimensionId")
+	defer cancel()
+
+	materials, err := r.findMaterials(ctx, `SELECT id, name, version FROM materials WHERE id IN (
+		SELECT material_id FROM material_dimensions WHERE dimension_id = ?) AND organization_id = ? ORDER BY rowid`, dimensionId.Hex(), tenant(ctx))
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return materials
+}
+
+func (r *materialRepository) ValidateExistingMaterial(ctx context.Context, materialName *material.MaterialNameDTO, excludedId *primitive.ObjectID) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.ValidateExistingMaterial")
+	defer cancel()
+
+	excluded := ""
+
+	if excludedId != nil {
+		excluded = excludedId.Hex()
+	}
+
+	var taken int
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM materials WHERE organization_id = ? AND normalized_name = ? AND id <> ?`,
+		tenant(ctx), core.NormalizeText(materialName.Name), excluded).Scan(&taken)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	if taken > 0 {
+		return material.ErrDuplicateMaterialName
+	}
+
+	return nil
+}
+
+func (r *materialRepository) CreateMaterial(ctx context.Context, created *material.Material) (*primitive.ObjectID, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.CreateMaterial")
+	defer cancel()
+
+	id := created.ID
+
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO materials (id, name, normalized_name, organization_id) VALUES (?, ?, ?, ?)`,
+			id.Hex(), created.Name, core.NormalizeText(created.Name), tenant(ctx))
+
+		if err != nil {
+			return err
+		}
+
+		for _, d := range created.Dimensions {
+			_, err = tx.ExecContext(ctx, `INSERT INTO material_dimensions (material_id, dimension_id, metric, quantity, price)
+				VALUES (?, ?, ?, ?, ?)`, id.Hex(), d.ID.Hex(), d.Metric, d.Quantity, d.Price)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	var sqliteErr sqlite3.Error
+
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return nil, material.ErrDuplicateMaterialName
+	}
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func (r *materialRepository) UpdateMaterial(ctx context.Context, oid *primitive.ObjectID, version int64, dto *material.MaterialNameDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.UpdateMaterial")
+	defer cancel()
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE materials SET name = ?, normalized_name = ?, version = version + 1
+		WHERE id = ? AND organization_id = ? AND version = ?`, dto.Name, core.NormalizeText(dto.Name), oid.Hex(), tenant(ctx), version)
+
+	var sqliteErr sqlite3.Error
+
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return material.ErrDuplicateMaterialName
+	}
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	if updated == 0 {
+		return core.ErrVersionConflict
+	}
+
+	return nil
+}
+
+func (r *materialRepository) DeleteMaterial(ctx context.Context, oid *primitive.ObjectID, version int64) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.DeleteMaterial")
+	defer cancel()
+
+	return execVersioned(ctx, conn(ctx, r.db), `DELETE FROM materials WHERE id = ? AND organization_id = ? AND version = ?`, oid.Hex(), tenant(ctx), version)
+}
+
+func (r *materialRepository) AddDimensionToMaterial(ctx context.Context, materialOid *primitive.ObjectID, version int64, dimensionOid *primitive.ObjectID, dimension *material.MaterialDimension) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.AddDimensionToMaterial")
+	defer cancel()
+
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// like the Mongo filter, the material must not have the dimension yet
+		err := execVersioned(ctx, tx, `UPDATE materials SET version = version + 1 WHERE id = ? AND organization_id = ? AND version = ?
+			AND id NOT IN (SELECT material_id FROM material_dimensions WHERE dimension_id = ?)`,
+			materialOid.Hex(), tenant(ctx), version, dimensionOid.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO material_dimensions (material_id, dimension_id, metric, quantity, price) VALUES (?, ?, ?, ?, ?)`,
+			materialOid.Hex(), dimensionOid.Hex(), dimension.Metric, dimension.Quantity, dimension.Price)
+
+		if err != nil {
+			core.LogError(ctx, err)
+		}
+
+		return err
+	})
+}
+
+func (r *materialRepository) RemoveDimensionFromMaterials(ctx context.Context, dto material.MaterialDimensionDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.RemoveDimensionFromMaterials")
+	defer cancel()
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE materials SET version = version + 1 WHERE organization_id = ? AND id IN (
+			SELECT material_id FROM material_dimensions WHERE dimension_id = ?)`, tenant(ctx), dto.DimensionOid.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM material_dimensions WHERE dimension_id = ? AND material_id IN (`+tenantMaterials+`)`,
+			dto.DimensionOid.Hex(), tenant(ctx))
+
+		return err
+	})
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
+
+func (r *materialRepository) ChangeMaterialPrice(ctx context.Context, dimensionId *primitive.ObjectID, version int64, priceDTO *material.MaterialDimensionPriceDTO) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.ChangeMaterialPrice")
+	defer cancel()
+
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// UpdateOne semantics: only the first material holding the dimension
+		// with the version changes
+		var materialId string
+
+		err := tx.QueryRowContext(ctx, `SELECT m.id FROM materials m
+			JOIN material_dimensions md ON md.material_id = m.id
+			WHERE md.dimension_id = ? AND m.organization_id = ? AND m.version = ? ORDER BY md.rowid LIMIT 1`,
+			dimensionId.Hex(), tenant(ctx), version).Scan(&materialId)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrVersionConflict
+		}
+
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE materials SET version = version + 1 WHERE id = ?`, materialId)
+		}
+
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE material_dimensions SET price = ? WHERE material_id = ? AND dimension_id = ?`,
+				priceDTO.Price, materialId, dimensionId.Hex())
+		}
+
+		if err != nil {
+			core.LogError(ctx, err)
+		}
+
+		return err
+	})
+}
+
+func (r *materialRepository) findMaterial(ctx context.Context, query string, args ...any) *material.MaterialDTO {
+	materials, err := r.findMaterials(ctx, query, args...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil
+	}
+
+	if len(materials) == 0 {
+		return nil
+	}
+
+	return &materials[0]
+}
+
+func (r *materialRepository) findMaterials(ctx context.Context, query string, args ...any) ([]material.MaterialDTO, error) {
+	materials := []material.MaterialDTO{}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return materials, err
+	}
+
+	for rows.Next() {
+		var id string
+		var found material.MaterialDTO
+
+		err = rows.Scan(&id, &found.Name, &found.Version)
+
+		if err != nil {
+			rows.Close()
+			return []material.MaterialDTO{}, err
+		}
+
+		found.ID = parseObjectId(id)
+		materials = append(materials, found)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return []material.MaterialDTO{}, err
+	}
+
+	for i := range materials {
+		materials[i].Dimensions, err = r.findDimensions(ctx, materials[i].ID)
+
+		if err != nil {
+			return []material.MaterialDTO{}, err
+		}
+	}
+
+	return materials, nil
+}
+
+func (r *materialRepository) findDimensions(ctx context.Context, materialId primitive.ObjectID) ([]material.DimensionDTO, error) {
+	dimensions := []material.DimensionDTO{}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectMaterialDimensions, materialId.Hex())
+
+	if err != nil {
+		return dimensions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var dimension material.DimensionDTO
+
+		err = rows.Scan(&id, &dimension.Metric, &dimension.Quantity, &dimension.Price)
+
+		if err != nil {
+			return dimensions, err
+		}
+
+		dimension.ID = parseObjectId(id)
+		dimensions = append(dimensions, dimension)
+	}
+
+	return dimensions, rows.Err()
+}
+
+// materialFilterClause mirrors the material.FilterMaterials query.
+func materialFilterClause(ctx context.Context, filter material.MaterialFilter) (string, []any) {
+	conditions := []string{`organization_id = ?`}
+	args := []any{tenant(ctx)}
+
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, likePrefix(filter.NamePrefix))
+	}
+
+	if filter.Metric != "" {
+		conditions = append(conditions, `id IN (SELECT material_id FROM material_dimensions WHERE metric = ?)`)
+		args = append(args, filter.Metric)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/material"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+const migrationsSchema = `
+CREATE TABLE IF NOT EXISTS ` + core.MigrationsCollection + ` (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+);
+`
+
+// migrations make the changes that the schema and addedColumns cannot make
+// to existing database files, like indexes over data that may break them.
+var migrations = []core.Migration[*sql.DB]{
+	{Version: 1, Name: "rename_duplicate_material_names", Up: renameDuplicateMaterialNames},
+	{Version: 2, Name: "materials_name_unique_index", Up: createIndex(`CREATE UNIQUE INDEX IF NOT EXISTS materials_name
+		ON materials(organization_id, name COLLATE NOCASE)`)},
+	{Version: 3, Name: "budget_lines_material_index", Up: createIndex(`CREATE INDEX IF NOT EXISTS budget_lines_material_id
+		ON budget_lines(material_id)`)},
+	{Version: 4, Name: "backfill_budget_line_material_ids", Up: backfillBudgetLineMaterialIds},
+	{Version: 5, Name: "materials_normalized_name_unique_index", Up: indexNormalizedMaterialNames},
+}
+
+// Migrate applies the pending migrations and returns how many it applied.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	_, err := db.ExecContext(ctx, migrationsSchema)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return core.RunMigrations(ctx, db, &migrationHistory{db: db}, migrations)
+}
+
+// PendingMigrations returns how many migrations Migrate would apply.
+func PendingMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	_, err := db.ExecContext(ctx, migrationsSchema)
+
+	if err != nil {
+		return 0, err
+	}
+
+	pending, err := core.PendingMigrations(ctx, &migrationHistory{db: db}, migrations)
+
+	return len(pending), err
+}
+
+type migrationHistory struct {
+	db *sql.DB
+}
+
+func (h *migrationHistory) AppliedMigrations(ctx context.Context) ([]core.AppliedMigration, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT version, name, applied_at FROM `+core.MigrationsCollection+` ORDER BY version`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := []core.AppliedMigration{}
+
+	for rows.Next() {
+		var migration core.AppliedMigration
+		var appliedAt string
+
+		err = rows.Scan(&migration.Version, &migration.Name, &appliedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		migration.AppliedAt, _ = time.Parse(time.RFC3339Nano, appliedAt)
+
+		applied = append(applied, migration)
+	}
+
+	return applied, rows.Err()
+}
+
+func (h *migrationHistory) RecordMigration(ctx context.Context, migration core.AppliedMigration) error {
+	_, err := h.db.ExecContext(ctx, `INSERT OR IGNORE INTO `+core.MigrationsCollection+` (version, name, applied_at) VALUES (?, ?, ?)`,
+		migration.Version, migration.Name, migration.AppliedAt.Format(time.RFC3339Nano))
+
+	return err
+}
+
+func createIndex(statement string) func(ctx context.Context, db *sql.DB) error {
+	return func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, statement)
+		return err
+	}
+}
+
+// renameDuplicateMaterialNames does what material.RenameDuplicateNames does
+// for Mongo.
+func renameDuplicateMaterialNames(ctx context.Context, db *sql.DB) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return renameDuplicates(ctx, tx)
+	})
+}
+
+// indexNormalizedMaterialNames replaces the index of materials_name, which
+// folds the case of ASCII letters only, with one over the names compared like
+// the Mongo collation compares them. Materials created meanwhile with names
+// that differ in accents or case are renamed first.
+func indexNormalizedMaterialNames(ctx context.Context, db *sql.DB) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		err := renameDuplicates(ctx, tx)
+
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT id, name FROM materials`)
+
+		if err != nil {
+			return err
+		}
+
+		normalized := map[string]string{}
+
+		for rows.Next() {
+			var id, name string
+
+			err = rows.Scan(&id, &name)
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			normalized[id] = core.NormalizeText(name)
+		}
+
+		rows.Close()
+
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+
+		for id, name := range normalized {
+			_, err = tx.ExecContext(ctx, `UPDATE materials SET normalized_name = ? WHERE id = ?`, name, id)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `DROP INDEX IF EXISTS materials_name`)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS materials_normalized_name
+			ON materials(organization_id, normalized_name)`)
+
+		return err
+	})
+}
+
+func renameDuplicates(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, organization_id FROM materials ORDER BY id`)
+
+	if err != nil {
+		return err
+	}
+
+	type renamed struct {
+		id   string
+		from string
+		to   string
+	}
+
+	taken := map[string]map[string]bool{}
+	renames := []renamed{}
+
+	for rows.Next() {
+		var id, name, organizationId string
+
+		err = rows.Scan(&id, &name, &organizationId)
+
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		if taken[organizationId] == nil {
+			taken[organizationId] = map[string]bool{}
+		}
+
+		unique := material.UniqueName(name, taken[organizationId])
+
+		if unique != name {
+			renames = append(renames, renamed{id: id, from: name, to: unique})
+		}
+	}
+
+	rows.Close()
+
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for _, rename := range renames {
+		_, err = tx.ExecContext(ctx, `UPDATE materials SET name = ? WHERE id = ?`, rename.to, rename.id)
+
+		if err != nil {
+			return err
+		}
+
+		core.Log(ctx, core.LogLevelWarn, "Material renombrado por tener un nombre repetido", core.LogFields{
+			"materialId": rename.id,
+			"from":       rename.from,
+			"to":         rename.to,
+		})
+	}
+
+	return nil
+}
+
+// backfillBudgetLineMaterialIds does what budget.BackfillMaterialIds does for
+// Mongo.
+func backfillBudgetLineMaterialIds(ctx context.Context, db *sql.DB) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT id, name, organization_id FROM materials`)
+
+		if err != nil {
+			return err
+		}
+
+		materials := map[string]map[string][]string{}
+
+		for rows.Next() {
+			var id, name, organizationId string
+
+			err = rows.Scan(&id, &name, &organizationId)
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			if materials[organizationId] == nil {
+				materials[organizationId] = map[string][]string{}
+			}
+
+			name = core.NormalizeText(name)
+			materials[organizationId][name] = append(materials[organizationId][name], id)
+		}
+
+		rows.Close()
+
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+
+		rows, err = tx.QueryContext(ctx, `SELECT l.id, l.name, b.organization_id FROM budget_lines l
+			JOIN budgets b ON b.id = l.budget_id WHERE l.material_id = ''`)
+
+		if err != nil {
+			return err
+		}
+
+		links := map[string]string{}
+
+		for rows.Next() {
+			var id, name, organizationId string
+
+			err = rows.Scan(&id, &name, &organizationId)
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			if matches := materials[organizationId][core.NormalizeText(name)]; len(matches) == 1 {
+				links[id] = matches[0]
+			}
+		}
+
+		rows.Close()
+
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+
+		for lineId, materialId := range links {
+			_, err = tx.ExecContext(ctx, `UPDATE budget_lines SET material_id = ? WHERE id = ?`, materialId, lineId)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
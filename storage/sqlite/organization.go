@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/organization"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const selectOrganizations = `SELECT id, name, created_at FROM organizations`
+
+var organizationSortColumns = map[string]string{
+	"name":             "name",
+	core.SortCreatedAt: "id",
+}
+
+type organizationRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewOrganizationRepository(db *sql.DB, timeouts core.QueryTimeouts) organization.OrganizationRepository {
+	return &organizationRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *organizationRepository) FindOrganizations(ctx context.Context, page core.PageRequest) ([]organization.Organization, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.FindOrganizations")
+	defer cancel()
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM organizations`).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, args := pageClause(page, organizationSortColumns)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectOrganizations+order, args...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	organizations := []organization.Organization{}
+
+	for rows.Next() {
+		found, err := scanOrganization(rows)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, 0, err
+		}
+
+		organizations = append(organizations, *found)
+	}
+
+	return organizations, total, rows.Err()
+}
+
+func (r *organizationRepository) FindOrganizationByOID(ctx context.Context, oid *primitive.ObjectID) *organization.Organization {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.FindOrganizationByOID")
+	defer cancel()
+
+	found, err := scanOrganization(conn(ctx, r.db).QueryRowContext(ctx, selectOrganizations+` WHERE id = ?`, oid.Hex()))
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError(ctx, err)
+		}
+		return nil
+	}
+
+	return found
+}
+
+func (r *organizationRepository) CreateOrganization(ctx context.Context, created *organization.Organization) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "organizations.CreateOrganization")
+	defer cancel()
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	return exec(ctx, r.db, `INSERT INTO organizations (id, name, created_at) VALUES (?, ?, ?)`,
+		created.ID.Hex(), created.Name, created.CreatedAt.Format(time.RFC3339Nano))
+}
+
+func scanOrganization(row scanner) (*organization.Organization, error) {
+	var id, createdAt string
+	var found organization.Organization
+
+	err := row.Scan(&id, &found.Name, &createdAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found.ID = parseObjectId(id)
+	found.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lucasbravi2019/arquitectura/api/search"
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+// searchSchema indexes the names in FTS4 tables that read their content from
+// the original tables. The unicode61 tokenizer folds case and, with
+// remove_diacritics, accents. Triggers keep the indexes in sync.
+var searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS materials_search USING fts4(content="materials", name, tokenize=unicode61 "remove_diacritics=1");
+CREATE VIRTUAL TABLE IF NOT EXISTS budgets_search USING fts4(content="budgets", name, tokenize=unicode61 "remove_diacritics=1");
+CREATE VIRTUAL TABLE IF NOT EXISTS budget_lines_search USING fts4(content="budget_lines", name, tokenize=unicode61 "remove_diacritics=1");
+` + searchTriggers("materials") + searchTriggers("budgets") + searchTriggers("budget_lines")
+
+var searchTables = []string{"materials_search", "budgets_search", "budget_lines_search"}
+
+func searchTriggers(table string) string {
+	return strings.NewReplacer("{table}", table).Replace(`
+CREATE TRIGGER IF NOT EXISTS {table}_search_bu BEFORE UPDATE ON {table} BEGIN
+	DELETE FROM {table}_search WHERE docid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS {table}_search_bd BEFORE DELETE ON {table} BEGIN
+	DELETE FROM {table}_search WHERE docid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS {table}_search_au AFTER UPDATE ON {table} BEGIN
+	INSERT INTO {table}_search (docid, name) VALUES (new.rowid, new.name);
+END;
+CREATE TRIGGER IF NOT EXISTS {table}_search_ai AFTER INSERT ON {table} BEGIN
+	INSERT INTO {table}_search (docid, name) VALUES (new.rowid, new.name);
+END;
+`)
+}
+
+// rebuildSearchIndexes indexes the rows written before the search tables
+// existed.
+func rebuildSearchIndexes(ctx context.Context, db *sql.DB) error {
+	for _, table := range searchTables {
+		_, err := db.ExecContext(ctx, "INSERT INTO "+table+" ("+table+") VALUES ('rebuild')")
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type searchRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewSearchRepository(db *sql.DB, timeouts core.QueryTimeouts) search.SearchRepository {
+	return &searchRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *searchRepository) SearchMaterials(ctx context.Context, terms []string, limit int) ([]search.SearchHitDTO, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "materials.SearchMaterials")
+	defer cancel()
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT m.id, m.name FROM materials_search s
+		JOIN materials m ON m.rowid = s.docid
+		WHERE materials_search MATCH ? AND m.organization_id = ? LIMIT ?`, matchExpression(terms), tenant(ctx), limit)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hits := []search.SearchHitDTO{}
+
+	for rows.Next() {
+		var id string
+		hit := search.SearchHitDTO{Type: search.HitTypeMaterial}
+
+		err = rows.Scan(&id, &hit.Name)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, err
+		}
+
+		hit.ID = parseObjectId(id)
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+func (r *searchRepository) SearchBudgets(ctx context.Context, terms []string, limit int) ([]search.SearchHitDTO, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "budgets.SearchBudgets")
+	defer cancel()
+
+	match := matchExpression(terms)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT b.id, b.name FROM budgets_search s
+		JOIN budgets b ON b.rowid = s.docid
+		WHERE budgets_search MATCH ? AND b.organization_id = ? LIMIT ?`, match, tenant(ctx), limit)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	hits := []search.SearchHitDTO{}
+
+	for rows.Next() {
+		var id string
+		hit := search.SearchHitDTO{Type: search.HitTypeBudget}
+
+		err = rows.Scan(&id, &hit.Name)
+
+		if err != nil {
+			rows.Close()
+			core.LogError(ctx, err)
+			return nil, err
+		}
+
+		hit.ID = parseObjectId(id)
+		hits = append(hits, hit)
+	}
+
+	rows.Close()
+
+	rows, err = conn(ctx, r.db).QueryContext(ctx, `SELECT l.id, l.material_id, l.name, b.id, b.name FROM budget_lines_search s
+		JOIN budget_lines l ON l.rowid = s.docid
+		JOIN budgets b ON b.id = l.budget_id
+		WHERE budget_lines_search MATCH ? AND b.organization_id = ? LIMIT ?`, match, tenant(ctx), limit)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, materialId, budgetId string
+		hit := search.SearchHitDTO{Type: search.HitTypeBudgetLine}
+
+		err = rows.Scan(&id, &materialId, &hit.Name, &budgetId, &hit.BudgetName)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, err
+		}
+
+		hit.ID = parseObjectId(id)
+		budgetOid := parseObjectId(budgetId)
+		hit.BudgetID = &budgetOid
+
+		if materialId != "" {
+			materialOid := parseObjectId(materialId)
+			hit.MaterialID = &materialOid
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// matchExpression finds the rows with any word starting with the stem of one
+// of the terms, which are already split into plain letters and numbers, like
+// the candidates of the other storages; see core.SearchScore.
+func matchExpression(terms []string) string {
+	prefixes := make([]string, len(terms))
+
+	for i, term := range terms {
+		prefixes[i] = core.SearchStem(term) + "*"
+	}
+
+	return strings.Join(prefixes, " OR ")
+}
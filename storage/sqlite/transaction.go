@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lucasbravi2019/arquitectura/core"
+)
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+// NewUnitOfWork runs the units in a sqlite transaction, which the
+// repositories find in the context.
+func NewUnitOfWork(db *sql.DB) core.UnitOfWork {
+	return &unitOfWork{
+		db: db,
+	}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := u.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		core.LogError(ctx, err)
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lucasbravi2019/arquitectura/api/user"
+	"github.com/lucasbravi2019/arquitectura/core"
+	"github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const selectUsers = `SELECT id, username, password_hash, roles, created_at, organization_id FROM users`
+
+var userSortColumns = map[string]string{
+	"username":         "username",
+	core.SortCreatedAt: "id",
+}
+
+type userRepository struct {
+	db       *sql.DB
+	timeouts core.QueryTimeouts
+}
+
+func NewUserRepository(db *sql.DB, timeouts core.QueryTimeouts) user.UserRepository {
+	return &userRepository{
+		db:       db,
+		timeouts: timeouts,
+	}
+}
+
+func (r *userRepository) FindUsers(ctx context.Context, page core.PageRequest) ([]user.User, int64, error) {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUsers")
+	defer cancel()
+
+	var total int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE organization_id = ?`, tenant(ctx)).Scan(&total)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	order, args := pageClause(page, userSortColumns)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, selectUsers+` WHERE organization_id = ?`+order, append([]any{tenant(ctx)}, args...)...)
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	users := []user.User{}
+
+	for rows.Next() {
+		found, err := scanUser(rows)
+
+		if err != nil {
+			core.LogError(ctx, err)
+			return nil, 0, err
+		}
+
+		users = append(users, *found)
+	}
+
+	return users, total, rows.Err()
+}
+
+func (r *userRepository) FindUserByOID(ctx context.Context, oid *primitive.ObjectID) *user.User {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUserByOID")
+	defer cancel()
+
+	return r.findUser(ctx, selectUsers+` WHERE id = ?`, oid.Hex())
+}
+
+func (r *userRepository) FindUserByUsername(ctx context.Context, username string) *user.User {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.FindUserByUsername")
+	defer cancel()
+
+	return r.findUser(ctx, selectUsers+` WHERE username = ?`, user.NormalizeUsername(username))
+}
+
+func (r *userRepository) CreateUser(ctx context.Context, created *user.User) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.CreateUser")
+	defer cancel()
+
+	created.Username = user.NormalizeUsername(created.Username)
+
+	if created.ID.IsZero() {
+		created.ID = primitive.NewObjectID()
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO users (id, username, password_hash, roles, created_at, organization_id) VALUES (?, ?, ?, ?, ?, ?)`,
+		created.ID.Hex(), created.Username, created.PasswordHash, strings.Join(created.Roles, ","), created.CreatedAt.Format(time.RFC3339Nano), created.OrganizationID.Hex())
+
+	var sqliteErr sqlite3.Error
+
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return user.ErrDuplicateUsername
+	}
+
+	if err != nil {
+		core.LogError(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateUserRoles(ctx context.Context, oid *primitive.ObjectID, roles []string) error {
+	ctx, cancel := core.WithQueryTimeout(ctx, r.timeouts, "users.UpdateUserRoles")
+	defer cancel()
+
+	return exec(ctx, r.db, `UPDATE users SET roles = ? WHERE id = ? AND organization_id = ?`, strings.Join(roles, ","), oid.Hex(), tenant(ctx))
+}
+
+func (r *userRepository) findUser(ctx context.Context, query string, args ...any) *user.User {
+	found, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError(ctx, err)
+		}
+		return nil
+	}
+
+	return found
+}
+
+// scanUser reads the roles, stored comma separated since role names never
+// contain commas.
+func scanUser(row scanner) (*user.User, error) {
+	var id, roles, createdAt, organizationId string
+	var found user.User
+
+	err := row.Scan(&id, &found.Username, &found.PasswordHash, &roles, &createdAt, &organizationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found.ID = parseObjectId(id)
+	found.OrganizationID = parseObjectId(organizationId)
+	found.Roles = []string{}
+
+	if roles != "" {
+		found.Roles = strings.Split(roles, ",")
+	}
+
+	found.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}